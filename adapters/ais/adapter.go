@@ -0,0 +1,395 @@
+package ais
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/moosethebrown/ship-net-bridge/adapters/nmea"
+	"github.com/moosethebrown/ship-net-bridge/core"
+	"github.com/moosethebrown/ship-net-bridge/metrics"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// DefaultBaudRate is the AIS receiver standard rate
+	DefaultBaudRate = 38400
+	// DefaultInterval is how often the target list is published
+	DefaultInterval = 5 * time.Second
+	// DefaultMaxAge is how long a target is kept after its last position,
+	// class B vessels at anchor report every 3 minutes
+	DefaultMaxAge = 6 * time.Minute
+)
+
+const (
+	// assessInterval is how often collision risk is assessed
+	assessInterval = time.Second
+	// ownMaxAge is how long ship-nav telemetry is used for assessment
+	ownMaxAge = 10 * time.Second
+)
+
+// State is the vessel state and control the adapter needs,
+// implemented by core.Core
+type State interface {
+	LastTelemetry() *core.Telemetry
+	AutoNav() bool
+	NavStop(reason string)
+	HandleResponse(origin *core.Origin, resp []byte)
+}
+
+// Adapter reads AIVDM sentences of an AIS receiver and tracks vessels
+// around. Targets within rangeLimit meters, or all of them when it's 0, are
+// published every interval. Once a second CPA and TCPA of targets are computed
+// against the position from ship-nav telemetry: a collision warning is
+// published when a target comes under the warning thresholds and, if stop
+// thresholds are set, autonav is stopped when it comes under those.
+// Each happens once per encounter, until the target leaves the thresholds.
+type Adapter struct {
+	address    string
+	baudRate   int
+	interval   time.Duration
+	maxAge     time.Duration
+	rangeLimit float64
+	risk       Risk
+	state      State
+	logger     *zerolog.Logger
+	mutex      sync.Mutex
+	decoder    *Decoder
+	targets    map[uint32]*core.AisTarget
+	// names arrive in their own messages, possibly before positions
+	names     map[uint32]*vesselName
+	warned    map[uint32]bool
+	stopped   map[uint32]bool
+	published bool
+	connected bool
+	messages  int
+	errors    int
+}
+
+// vesselName is kept until maxAge after it was received
+// if the vessel never reports its position
+type vesselName struct {
+	name     string
+	received time.Time
+}
+
+type Status struct {
+	Address   string `json:"address"`
+	Connected bool   `json:"connected"`
+	Messages  int    `json:"messages"`
+	Errors    int    `json:"errors"`
+	Targets   int    `json:"targets"`
+	Warnings  int    `json:"warnings"`
+}
+
+func NewAdapter(address string, baudRate int, interval time.Duration,
+	maxAge time.Duration, rangeLimit float64, risk Risk,
+	state State, logger *zerolog.Logger) *Adapter {
+	if baudRate == 0 {
+		baudRate = DefaultBaudRate
+	}
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	if maxAge <= 0 {
+		maxAge = DefaultMaxAge
+	}
+
+	return &Adapter{
+		address:    address,
+		baudRate:   baudRate,
+		interval:   interval,
+		maxAge:     maxAge,
+		rangeLimit: rangeLimit,
+		risk:       risk,
+		state:      state,
+		logger:     logger,
+		decoder:    NewDecoder(),
+		targets:    make(map[uint32]*core.AisTarget),
+		names:      make(map[uint32]*vesselName),
+		warned:     make(map[uint32]bool),
+		stopped:    make(map[uint32]bool),
+	}
+}
+
+// Run returns an error when the source can't be opened or fails,
+// it's up to the caller to run it again; targets are kept across runs
+func (a *Adapter) Run(ctx context.Context) error {
+	source, err := nmea.Open(ctx, a.address, a.baudRate)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		a.logger.Error().Err(err).Msgf("Failed to open %s", a.address)
+		metrics.Errors.WithLabelValues("ais", "connect").Inc()
+		return err
+	}
+	// closing the source ends read
+	defer source.Close()
+	a.setConnected(true)
+	defer a.setConnected(false)
+
+	readErr := make(chan error, 1)
+	go func() {
+		readErr <- nmea.ReadLines(source, a.handleLine)
+	}()
+
+	assessTicker := time.NewTicker(assessInterval)
+	defer assessTicker.Stop()
+	publishTicker := time.NewTicker(a.interval)
+	defer publishTicker.Stop()
+
+	for {
+		select {
+		case now := <-assessTicker.C:
+			a.assess(now)
+		case <-publishTicker.C:
+			a.publish()
+		case err := <-readErr:
+			if ctx.Err() != nil {
+				return nil
+			}
+			a.logger.Error().Err(err).Msgf("Failed to read %s", a.address)
+			metrics.Errors.WithLabelValues("ais", "read").Inc()
+			return err
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Status can be called from any goroutine
+func (a *Adapter) Status() any {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return &Status{
+		Address:   a.address,
+		Connected: a.connected,
+		Messages:  a.messages,
+		Errors:    a.errors,
+		Targets:   len(a.targets),
+		Warnings:  len(a.warned),
+	}
+}
+
+func (a *Adapter) Healthy() bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return a.connected
+}
+
+// Targets returns assessed targets nearest first, those of unknown
+// distance last; it can be called from any goroutine
+func (a *Adapter) Targets() []*core.AisTarget {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return a.targetList()
+}
+
+func (a *Adapter) handleLine(line string, now time.Time) {
+	if line == "" {
+		return
+	}
+
+	s, err := nmea.Parse(line)
+	var report *Report
+	if err == nil {
+		report, err = a.decoder.Decode(s)
+	}
+
+	a.mutex.Lock()
+	if err != nil {
+		a.errors++
+	} else if report != nil {
+		a.messages++
+		a.update(report, now)
+	}
+	a.mutex.Unlock()
+
+	if err != nil {
+		a.logger.Debug().Err(err).Msg("invalid AIS sentence")
+		if errors.Is(err, nmea.ErrChecksum) {
+			metrics.Errors.WithLabelValues("ais", "checksum").Inc()
+		} else {
+			metrics.Errors.WithLabelValues("ais", "malformed").Inc()
+		}
+	}
+}
+
+// update is called with mutex locked
+func (a *Adapter) update(r *Report, now time.Time) {
+	if r.Name != "" {
+		a.names[r.Mmsi] = &vesselName{
+			name:     r.Name,
+			received: now,
+		}
+		if t := a.targets[r.Mmsi]; t != nil {
+			t.Name = r.Name
+		}
+	}
+	if !r.HasPosition {
+		return
+	}
+
+	t := a.targets[r.Mmsi]
+	if t == nil {
+		t = &core.AisTarget{
+			Mmsi: r.Mmsi,
+		}
+		if n := a.names[r.Mmsi]; n != nil {
+			t.Name = n.name
+		}
+		a.targets[r.Mmsi] = t
+	}
+	t.Latitude = r.Latitude
+	t.Longitude = r.Longitude
+	t.Course = r.Course
+	t.Speed = r.Speed
+	t.Heading = r.Heading
+	t.Timestamp = now
+}
+
+// assess drops targets not heard of for maxAge, updates their distance,
+// CPA and TCPA and acts on the ones coming under risk thresholds
+func (a *Adapter) assess(now time.Time) {
+	own := a.state.LastTelemetry()
+	if (own != nil) && (now.Sub(own.Timestamp) > ownMaxAge) {
+		own = nil
+	}
+	// a target is only marked stopped once autonav has actually been
+	// stopped for it, so that autonav started during an encounter is stopped
+	autoNav := a.state.AutoNav()
+
+	warnings := make([]core.AisTarget, 0)
+	stops := make([]core.AisTarget, 0)
+	a.mutex.Lock()
+	for mmsi, t := range a.targets {
+		if now.Sub(t.Timestamp) > a.maxAge {
+			delete(a.targets, mmsi)
+			delete(a.names, mmsi)
+			delete(a.warned, mmsi)
+			delete(a.stopped, mmsi)
+			continue
+		}
+
+		// without own position targets keep their risk state
+		t.Distance, t.Cpa, t.Tcpa = nil, nil, nil
+		if own != nil {
+			assess(t, own)
+			if !a.risk.warn(t) {
+				delete(a.warned, mmsi)
+			} else if !a.warned[mmsi] {
+				a.warned[mmsi] = true
+				warnings = append(warnings, *t)
+			}
+			if !a.risk.stop(t) {
+				delete(a.stopped, mmsi)
+			} else if !a.stopped[mmsi] && autoNav {
+				a.stopped[mmsi] = true
+				stops = append(stops, *t)
+			}
+		}
+		t.Warning = a.warned[mmsi]
+	}
+	// names of vessels that never report a position
+	for mmsi, n := range a.names {
+		if (a.targets[mmsi] == nil) && (now.Sub(n.received) > a.maxAge) {
+			delete(a.names, mmsi)
+		}
+	}
+	a.mutex.Unlock()
+
+	for i := range warnings {
+		t := &warnings[i]
+		a.logger.Warn().Msgf("collision risk with %s: CPA %.0f m in %.0f s",
+			targetName(t), *t.Cpa, *t.Tcpa)
+		metrics.CollisionWarnings.Inc()
+		a.send(&core.Response{
+			Type:   core.ResponseTypeCollisionWarning,
+			Target: t,
+		})
+	}
+	for i := range stops {
+		t := &stops[i]
+		a.state.NavStop(fmt.Sprintf("collision risk with %s: CPA %.0f m in %.0f s",
+			targetName(t), *t.Cpa, *t.Tcpa))
+		a.send(&core.Response{
+			Type:   core.ResponseTypeCollisionWarning,
+			Cmd:    core.CmdNavStop,
+			Target: t,
+		})
+	}
+}
+
+// publish sends the target list, an empty one only once
+// after the last target is gone
+func (a *Adapter) publish() {
+	a.mutex.Lock()
+	targets := a.targetList()
+	published := a.published
+	a.published = len(targets) > 0
+	a.mutex.Unlock()
+
+	if (len(targets) > 0) || published {
+		a.send(&core.Response{
+			Type:    core.ResponseTypeAisTargets,
+			Targets: targets,
+		})
+	}
+}
+
+// targetList is called with mutex locked, it returns copies
+// of targets within range
+func (a *Adapter) targetList() []*core.AisTarget {
+	targets := make([]*core.AisTarget, 0, len(a.targets))
+	for _, t := range a.targets {
+		if (a.rangeLimit > 0) && (t.Distance != nil) && (*t.Distance > a.rangeLimit) {
+			continue
+		}
+		target := *t
+		targets = append(targets, &target)
+	}
+
+	slices.SortFunc(targets, func(x *core.AisTarget, y *core.AisTarget) int {
+		switch {
+		case (x.Distance != nil) && (y.Distance != nil):
+			return cmp.Compare(*x.Distance, *y.Distance)
+		case x.Distance != nil:
+			return -1
+		case y.Distance != nil:
+			return 1
+		}
+		return cmp.Compare(x.Mmsi, y.Mmsi)
+	})
+	return targets
+}
+
+func (a *Adapter) send(resp *core.Response) {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		a.logger.Error().Err(err).Msg("failed to marshal response")
+		return
+	}
+	a.state.HandleResponse(nil, data)
+}
+
+func (a *Adapter) setConnected(connected bool) {
+	a.mutex.Lock()
+	a.connected = connected
+	a.mutex.Unlock()
+}
+
+func targetName(t *core.AisTarget) string {
+	if t.Name != "" {
+		return fmt.Sprintf("%s (%d)", t.Name, t.Mmsi)
+	}
+	return fmt.Sprint(t.Mmsi)
+}
@@ -0,0 +1,159 @@
+package ais
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/moosethebrown/ship-net-bridge/core"
+	"github.com/rs/zerolog"
+)
+
+type mockState struct {
+	telemetry *core.Telemetry
+	autoNav   bool
+	navStops  []string
+	responses []*core.Response
+}
+
+func (m *mockState) LastTelemetry() *core.Telemetry {
+	return m.telemetry
+}
+
+func (m *mockState) AutoNav() bool {
+	return m.autoNav
+}
+
+func (m *mockState) NavStop(reason string) {
+	m.navStops = append(m.navStops, reason)
+	m.autoNav = false
+}
+
+func (m *mockState) HandleResponse(origin *core.Origin, data []byte) {
+	resp := &core.Response{}
+	json.Unmarshal(data, resp)
+	m.responses = append(m.responses, resp)
+}
+
+// meters returns degrees of latitude, or longitude at the equator
+func meters(m float64) float64 {
+	return m / earthRadius * 180 / math.Pi
+}
+
+func TestAssess(t *testing.T) {
+	own := &core.Telemetry{Heading: 90, Speed: 10}
+
+	// passing a buoy 100 m to port
+	buoy := &core.AisTarget{Latitude: meters(100), Longitude: meters(1000)}
+	assess(buoy, own)
+	if !near(*buoy.Distance, math.Hypot(100, 1000)) || !near(*buoy.Cpa, 100) || !near(*buoy.Tcpa, 100) {
+		t.Errorf("Unexpected buoy assessment %v %v %v", *buoy.Distance, *buoy.Cpa, *buoy.Tcpa)
+	}
+
+	// head-on, closing at 20 m/s
+	course, speed := 270.0, 10/mpsPerKnot
+	vessel := &core.AisTarget{Longitude: meters(1000), Course: &course, Speed: &speed}
+	assess(vessel, own)
+	if !near(*vessel.Cpa, 0) || !near(*vessel.Tcpa, 50) {
+		t.Errorf("Unexpected head-on assessment %v %v", *vessel.Cpa, *vessel.Tcpa)
+	}
+
+	// overtaken, moving apart
+	course, speed = 90, 5/mpsPerKnot
+	vessel = &core.AisTarget{Longitude: meters(-1000), Course: &course, Speed: &speed}
+	assess(vessel, own)
+	if !near(*vessel.Cpa, 1000) || (*vessel.Tcpa >= 0) {
+		t.Errorf("Unexpected assessment of a vessel moving apart %v %v", *vessel.Cpa, *vessel.Tcpa)
+	}
+}
+
+func TestAdapter(t *testing.T) {
+	logger := zerolog.New(os.Stdout).Level(zerolog.DebugLevel)
+	now := time.Now()
+	state := &mockState{
+		telemetry: &core.Telemetry{Heading: 0, Speed: 5, Timestamp: now},
+		autoNav:   true,
+	}
+	risk := Risk{WarnCpa: 500, WarnTcpa: 600, StopCpa: 200, StopTcpa: 120}
+	adapter := NewAdapter("udp://:10110", 0, 0, time.Minute, 20000, risk, state, &logger)
+
+	// 1 km ahead on opposite course, and one out of range
+	for _, line := range []string{
+		staticData(244123456, "SEA BIRD"),
+		position(244123456, meters(1000), 0, 180, 10),
+		position(244654321, 0, meters(30000), 0, 0),
+		"!AIVDM,1,1,,A,1*00",
+	} {
+		adapter.handleLine(line, now)
+	}
+
+	adapter.assess(now)
+	adapter.assess(now.Add(time.Second))
+	if (len(state.navStops) != 1) || (len(state.responses) != 2) {
+		t.Fatalf("Expected one warning and one nav stop, got %v %+v", state.navStops, state.responses)
+	}
+	warning, stop := state.responses[0], state.responses[1]
+	if (warning.Type != core.ResponseTypeCollisionWarning) || (warning.Cmd != "") ||
+		(warning.Target.Name != "SEA BIRD") || (*warning.Target.Tcpa > 100) {
+		t.Errorf("Unexpected collision warning %+v %+v", warning, warning.Target)
+	}
+	if (stop.Type != core.ResponseTypeCollisionWarning) || (stop.Cmd != core.CmdNavStop) {
+		t.Errorf("Unexpected nav stop warning %+v", stop)
+	}
+
+	adapter.publish()
+	list := state.responses[len(state.responses)-1]
+	if (list.Type != core.ResponseTypeAisTargets) || (len(list.Targets) != 1) ||
+		(list.Targets[0].Mmsi != 244123456) || !list.Targets[0].Warning {
+		t.Errorf("Expected the target within range, got %+v", list)
+	}
+	status := adapter.Status().(*Status)
+	if (status.Targets != 2) || (status.Warnings != 1) || (status.Messages != 3) || (status.Errors != 1) {
+		t.Errorf("Unexpected status %+v", status)
+	}
+
+	// targets expire, the empty list is published once
+	adapter.assess(now.Add(2 * time.Minute))
+	adapter.publish()
+	adapter.publish()
+	list = state.responses[len(state.responses)-1]
+	if (len(state.responses) != 4) || (len(list.Targets) != 0) || (len(adapter.Targets()) != 0) {
+		t.Errorf("Expected one empty target list, got %+v", state.responses)
+	}
+}
+
+func TestAutoNavDuringEncounter(t *testing.T) {
+	logger := zerolog.New(os.Stdout).Level(zerolog.DebugLevel)
+	now := time.Now()
+	state := &mockState{
+		telemetry: &core.Telemetry{Heading: 0, Speed: 5, Timestamp: now},
+	}
+	risk := Risk{WarnCpa: 500, WarnTcpa: 600, StopCpa: 200, StopTcpa: 120}
+	adapter := NewAdapter("udp://:10110", 0, 0, time.Minute, 0, risk, state, &logger)
+
+	adapter.handleLine(position(244123456, meters(1000), 0, 180, 10), now)
+	adapter.handleLine(staticData(244654321, "NO POSITION"), now)
+	adapter.assess(now)
+	if len(state.navStops) != 0 {
+		t.Fatalf("Expected no nav stop without autonav, got %v", state.navStops)
+	}
+
+	// autonav started mid-encounter is stopped
+	state.autoNav = true
+	adapter.assess(now.Add(time.Second))
+	adapter.assess(now.Add(2 * time.Second))
+	if len(state.navStops) != 1 {
+		t.Errorf("Expected one nav stop, got %v", state.navStops)
+	}
+
+	// names of vessels without a position expire too
+	adapter.assess(now.Add(2 * time.Minute))
+	adapter.mutex.Lock()
+	names := len(adapter.names)
+	adapter.mutex.Unlock()
+	if names != 0 {
+		t.Errorf("Expected names to expire, got %d", names)
+	}
+}
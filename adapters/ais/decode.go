@@ -0,0 +1,225 @@
+package ais
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/moosethebrown/ship-net-bridge/adapters/nmea"
+)
+
+// Report is what an AIS message tells about a vessel: its position
+// (message types 1, 2, 3, 18 and 19) and/or its name (5, 19 and 24)
+type Report struct {
+	Type        int
+	Mmsi        uint32
+	Name        string
+	HasPosition bool
+	Latitude    float64
+	Longitude   float64
+	// Course over ground and Heading are true, degrees; Speed over ground
+	// is in knots; nil when not available
+	Course  *float64
+	Speed   *float64
+	Heading *float64
+}
+
+// Decoder decodes AIVDM sentences, reassembling messages sent as several
+// sentences; it isn't safe for concurrent use
+type Decoder struct {
+	partial map[string]*partialMessage
+}
+
+type partialMessage struct {
+	count   int
+	next    int
+	payload strings.Builder
+}
+
+func NewDecoder() *Decoder {
+	return &Decoder{
+		partial: make(map[string]*partialMessage),
+	}
+}
+
+// Decode returns the report of an AIVDM sentence: !AIVDM,<count>,<number>,
+// <sequence id>,<channel>,<payload>,<fill bits>; it returns nil for fragments
+// of unfinished messages, other sentences and unsupported message types
+func (d *Decoder) Decode(s *nmea.Sentence) (*Report, error) {
+	if s.Type != "VDM" {
+		// VDO reports our own vessel
+		return nil, nil
+	}
+
+	count, err := strconv.Atoi(s.Field(0))
+	if (err != nil) || (count < 1) {
+		return nil, fmt.Errorf("malformed AIVDM fragment count %q", s.Field(0))
+	}
+	number, err := strconv.Atoi(s.Field(1))
+	if (err != nil) || (number < 1) || (number > count) {
+		return nil, fmt.Errorf("malformed AIVDM fragment number %q", s.Field(1))
+	}
+	fill, err := strconv.Atoi(s.Field(5))
+	if (err != nil) || (fill < 0) || (fill > 5) {
+		return nil, fmt.Errorf("malformed AIVDM fill bits %q", s.Field(5))
+	}
+
+	if count == 1 {
+		return decodePayload(s.Field(4), fill)
+	}
+
+	key := s.Field(2) + "/" + s.Field(3)
+	msg := d.partial[key]
+	if number == 1 {
+		msg = &partialMessage{count: count, next: 1}
+		d.partial[key] = msg
+	}
+	if (msg == nil) || (msg.count != count) || (msg.next != number) {
+		delete(d.partial, key)
+		return nil, errors.New("AIVDM fragment out of sequence")
+	}
+	msg.payload.WriteString(s.Field(4))
+	msg.next++
+	if number < count {
+		return nil, nil
+	}
+
+	delete(d.partial, key)
+	return decodePayload(msg.payload.String(), fill)
+}
+
+func decodePayload(payload string, fill int) (*Report, error) {
+	b, err := newBits(payload, fill)
+	if err != nil {
+		return nil, err
+	}
+	if b.length < 38 {
+		return nil, errors.New("AIS message is too short")
+	}
+
+	r := &Report{
+		Type: int(b.unsigned(0, 6)),
+		Mmsi: b.unsigned(8, 30),
+	}
+	switch r.Type {
+	case 1, 2, 3:
+		// class A position report
+		if b.length < 137 {
+			return nil, fmt.Errorf("AIS message %d is too short", r.Type)
+		}
+		r.setPosition(b, 50, 61, 89, 116, 128)
+	case 18:
+		// class B position report
+		if b.length < 133 {
+			return nil, fmt.Errorf("AIS message %d is too short", r.Type)
+		}
+		r.setPosition(b, 46, 57, 85, 112, 124)
+	case 19:
+		// extended class B position report
+		if b.length < 263 {
+			return nil, fmt.Errorf("AIS message %d is too short", r.Type)
+		}
+		r.setPosition(b, 46, 57, 85, 112, 124)
+		r.Name = b.text(143, 120)
+	case 5:
+		// static and voyage data; only the name is used
+		if b.length < 232 {
+			return nil, fmt.Errorf("AIS message %d is too short", r.Type)
+		}
+		r.Name = b.text(112, 120)
+	case 24:
+		// class B static data, part A carries the name
+		if (b.length < 160) || (b.unsigned(38, 2) != 0) {
+			return nil, nil
+		}
+		r.Name = b.text(40, 120)
+	default:
+		return nil, nil
+	}
+	return r, nil
+}
+
+// setPosition reads speed, longitude, latitude, course and heading
+// starting at the given bits
+func (r *Report) setPosition(b *bits, speed int, lon int, lat int, course int, heading int) {
+	longitude := float64(b.signed(lon, 28)) / 600000
+	latitude := float64(b.signed(lat, 27)) / 600000
+	// 181 and 91 mean not available
+	if (longitude >= -180) && (longitude <= 180) && (latitude >= -90) && (latitude <= 90) {
+		r.HasPosition = true
+		r.Longitude = longitude
+		r.Latitude = latitude
+	}
+
+	if v := b.unsigned(speed, 10); v != 1023 {
+		knots := float64(v) / 10
+		r.Speed = &knots
+	}
+	if v := b.unsigned(course, 12); v < 3600 {
+		degrees := float64(v) / 10
+		r.Course = &degrees
+	}
+	if v := b.unsigned(heading, 9); v < 360 {
+		degrees := float64(v)
+		r.Heading = &degrees
+	}
+}
+
+// bits is an AIS payload unarmored into 6-bit values
+type bits struct {
+	values []byte
+	length int
+}
+
+func newBits(payload string, fill int) (*bits, error) {
+	b := &bits{
+		values: make([]byte, len(payload)),
+		length: len(payload)*6 - fill,
+	}
+	for i := 0; i < len(payload); i++ {
+		c := payload[i]
+		if (c < '0') || (c > 'w') || ((c > 'W') && (c < '`')) {
+			return nil, fmt.Errorf("invalid AIS payload character %q", c)
+		}
+		v := c - '0'
+		if v > 40 {
+			v -= 8
+		}
+		b.values[i] = v
+	}
+	return b, nil
+}
+
+// unsigned reads length bits starting at start, bits past the end read as zero
+func (b *bits) unsigned(start int, length int) uint32 {
+	var v uint32
+	for i := start; i < start+length; i++ {
+		v <<= 1
+		if i < b.length {
+			v |= uint32(b.values[i/6]>>(5-i%6)) & 1
+		}
+	}
+	return v
+}
+
+func (b *bits) signed(start int, length int) int32 {
+	v := b.unsigned(start, length)
+	if v&(1<<(length-1)) != 0 {
+		return int32(v) - int32(1<<length)
+	}
+	return int32(v)
+}
+
+// text reads 6-bit ASCII, trailing padding (@) and spaces are dropped
+func (b *bits) text(start int, length int) string {
+	var sb strings.Builder
+	for i := start; i+6 <= start+length; i += 6 {
+		c := byte(b.unsigned(i, 6))
+		if c < 32 {
+			c += 64
+		}
+		sb.WriteByte(c)
+	}
+	return strings.TrimRight(sb.String(), "@ ")
+}
@@ -0,0 +1,127 @@
+package ais
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/moosethebrown/ship-net-bridge/adapters/nmea"
+)
+
+// encode armors fields, pairs of bit length and value, into an AIVDM sentence
+func encode(fields ...int64) string {
+	bitValues := make([]byte, 0)
+	for i := 0; i+1 < len(fields); i += 2 {
+		length, value := int(fields[i]), fields[i+1]
+		for b := length - 1; b >= 0; b-- {
+			bitValues = append(bitValues, byte(value>>b)&1)
+		}
+	}
+	fill := (6 - len(bitValues)%6) % 6
+	bitValues = append(bitValues, make([]byte, fill)...)
+
+	payload := make([]byte, 0, len(bitValues)/6)
+	for i := 0; i < len(bitValues); i += 6 {
+		var v byte
+		for _, b := range bitValues[i : i+6] {
+			v = v<<1 | b
+		}
+		if v < 40 {
+			payload = append(payload, v+48)
+		} else {
+			payload = append(payload, v+56)
+		}
+	}
+
+	data := fmt.Sprintf("AIVDM,1,1,,A,%s,%d", payload, fill)
+	var sum byte
+	for i := 0; i < len(data); i++ {
+		sum ^= data[i]
+	}
+	return fmt.Sprintf("!%s*%02X", data, sum)
+}
+
+// position encodes a class A position report, heading not available
+func position(mmsi uint32, lat float64, lon float64, course float64, speed float64) string {
+	return encode(6, 1, 2, 0, 30, int64(mmsi), 4, 0, 8, 0, 10, int64(math.Round(speed*10)), 1, 0,
+		28, int64(math.Round(lon*600000)), 27, int64(math.Round(lat*600000)),
+		12, int64(math.Round(course*10)), 9, 511, 31, 0)
+}
+
+// staticData encodes part A of a class B static data report
+func staticData(mmsi uint32, name string) string {
+	fields := []int64{6, 24, 2, 0, 30, int64(mmsi), 2, 0}
+	for i := 0; i < 20; i++ {
+		c := int64('@')
+		if i < len(name) {
+			c = int64(name[i])
+		}
+		fields = append(fields, 6, c&0x3f)
+	}
+	return encode(fields...)
+}
+
+func decode(t *testing.T, d *Decoder, line string) *Report {
+	s, err := nmea.Parse(line)
+	if err != nil {
+		t.Fatalf("Failed to parse %s: %s", line, err)
+	}
+	r, err := d.Decode(s)
+	if err != nil {
+		t.Fatalf("Failed to decode %s: %s", line, err)
+	}
+	return r
+}
+
+func near(a float64, b float64) bool {
+	return math.Abs(a-b) < 1e-5
+}
+
+func TestDecode(t *testing.T) {
+	d := NewDecoder()
+
+	r := decode(t, d, "!AIVDM,1,1,,B,177KQJ5000G?tO`K>RA1wUbN0TKH,0*5C")
+	if (r == nil) || (r.Type != 1) || (r.Mmsi != 477553000) || !r.HasPosition ||
+		!near(r.Latitude, 47.582833) || !near(r.Longitude, -122.345833) {
+		t.Fatalf("Unexpected class A position report %+v", r)
+	}
+	if (r.Course == nil) || (*r.Course != 51) || (r.Speed == nil) || (*r.Speed != 0) ||
+		(r.Heading == nil) || (*r.Heading != 181) {
+		t.Errorf("Unexpected course, speed and heading %v %v %v", r.Course, r.Speed, r.Heading)
+	}
+
+	r = decode(t, d, "!AIVDM,1,1,,A,B6CdCm0t3`tba35f@V9faHi7kP06,0*58")
+	if (r == nil) || (r.Type != 18) || (r.Mmsi != 423302100) || !near(r.Latitude, 40.005283) ||
+		(r.Speed == nil) || (*r.Speed != 1.4) || (r.Heading == nil) || (*r.Heading != 177) {
+		t.Errorf("Unexpected class B position report %+v", r)
+	}
+
+	// the second fragment completes the message
+	if r := decode(t, d, "!AIVDM,2,1,1,A,55?MbV02;H;s<HtKR20EHE:0@T4@Dn2222222216L961O5Gf0NSQEp6ClRp8,0*1C"); r != nil {
+		t.Fatalf("Expected no report for the first fragment, got %+v", r)
+	}
+	r = decode(t, d, "!AIVDM,2,2,1,A,88888888880,2*25")
+	if (r == nil) || (r.Mmsi != 351759000) || (r.Name != "EVER DIADEM") || r.HasPosition {
+		t.Errorf("Unexpected static and voyage data %+v", r)
+	}
+
+	r = decode(t, d, staticData(244123456, "SEA BIRD"))
+	if (r == nil) || (r.Type != 24) || (r.Mmsi != 244123456) || (r.Name != "SEA BIRD") {
+		t.Errorf("Unexpected class B static data %+v", r)
+	}
+
+	r = decode(t, d, position(244123456, -33.9, -151.2, 359.9, 12.3))
+	if (r == nil) || !near(r.Latitude, -33.9) || !near(r.Longitude, -151.2) || (*r.Course != 359.9) {
+		t.Errorf("Unexpected southern and western position %+v", r)
+	}
+
+	// a fragment without the first one is dropped
+	s, _ := nmea.Parse("!AIVDM,2,2,3,A,88888888880,2*27")
+	if _, err := d.Decode(s); err == nil {
+		t.Errorf("Expected fragment out of sequence to be rejected")
+	}
+	s, _ = nmea.Parse("!AIVDO,1,1,,B,177KQJ5000G?tO`K>RA1wUbN0TKH,0*5E")
+	if r, err := d.Decode(s); (r != nil) || (err != nil) {
+		t.Errorf("Expected own vessel report to be ignored, got %+v %v", r, err)
+	}
+}
@@ -0,0 +1,71 @@
+package ais
+
+import (
+	"math"
+
+	"github.com/moosethebrown/ship-net-bridge/core"
+)
+
+const (
+	earthRadius = 6371000.0
+	// mpsPerKnot converts knots to meters per second
+	mpsPerKnot = 1852.0 / 3600
+)
+
+// Risk holds the thresholds of CPA (meters) and TCPA (seconds) under which
+// a target is a collision risk; a zero StopCpa disables stopping autonav
+type Risk struct {
+	WarnCpa  float64
+	WarnTcpa float64
+	StopCpa  float64
+	StopTcpa float64
+}
+
+// warn tells whether an assessed target calls for a collision warning
+func (r *Risk) warn(t *core.AisTarget) bool {
+	return closing(t, r.WarnCpa, r.WarnTcpa)
+}
+
+// stop tells whether an assessed target calls for stopping autonav
+func (r *Risk) stop(t *core.AisTarget) bool {
+	return (r.StopCpa > 0) && closing(t, r.StopCpa, r.StopTcpa)
+}
+
+func closing(t *core.AisTarget, cpa float64, tcpa float64) bool {
+	return (t.Cpa != nil) && (*t.Cpa <= cpa) && (*t.Tcpa >= 0) && (*t.Tcpa <= tcpa)
+}
+
+// assess sets distance, CPA and TCPA of the target relative to the vessel,
+// whose speed is in meters per second; targets without course or speed are
+// taken as not moving. Distances of AIS range are short enough for a local
+// flat projection around the vessel.
+func assess(t *core.AisTarget, own *core.Telemetry) {
+	scale := math.Cos(radians(own.Latitude))
+	x := radians(t.Longitude-own.Longitude) * scale * earthRadius
+	y := radians(t.Latitude-own.Latitude) * earthRadius
+
+	// relative velocity of the target, east and north
+	vx := -own.Speed * math.Sin(radians(own.Heading))
+	vy := -own.Speed * math.Cos(radians(own.Heading))
+	if (t.Course != nil) && (t.Speed != nil) {
+		vx += *t.Speed * mpsPerKnot * math.Sin(radians(*t.Course))
+		vy += *t.Speed * mpsPerKnot * math.Cos(radians(*t.Course))
+	}
+
+	distance := math.Hypot(x, y)
+	cpa := distance
+	tcpa := 0.0
+	if v2 := vx*vx + vy*vy; v2 > 1e-9 {
+		tcpa = -(x*vx + y*vy) / v2
+		if tcpa > 0 {
+			cpa = math.Hypot(x+vx*tcpa, y+vy*tcpa)
+		}
+	}
+	t.Distance = &distance
+	t.Cpa = &cpa
+	t.Tcpa = &tcpa
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
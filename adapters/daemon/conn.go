@@ -0,0 +1,89 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+// DefaultRequestTimeout bounds sending a request and receiving its response
+const DefaultRequestTimeout = 5 * time.Second
+
+// ErrNoResponse is returned by ReadResponse when a request timed out but
+// the connection can still be used, i.e. on serial ports where frames keep
+// message boundaries
+var ErrNoResponse = errors.New("no response")
+
+// Conn exchanges requests and responses with a daemon. Responses are read
+// as JSON values from the stream: tcp and tls don't keep message boundaries,
+// so a response may take several reads or arrive together with the next one.
+type Conn struct {
+	net.Conn
+	decoder *json.Decoder
+	timeout time.Duration
+	limit   time.Time
+}
+
+// Connect dials the endpoint, see Dial
+func (e *Endpoint) Connect(ctx context.Context) (*Conn, error) {
+	conn, err := e.Dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NewConn(conn, e.RequestTimeout), nil
+}
+
+// NewConn wraps conn, each request is given timeout to complete,
+// zero means DefaultRequestTimeout
+func NewConn(conn net.Conn, timeout time.Duration) *Conn {
+	if timeout <= 0 {
+		timeout = DefaultRequestTimeout
+	}
+	return &Conn{
+		Conn:    conn,
+		decoder: json.NewDecoder(conn),
+		timeout: timeout,
+	}
+}
+
+// SetLimit makes requests fail at t at the latest, e.g. on shutdown;
+// zero time removes the limit
+func (c *Conn) SetLimit(t time.Time) {
+	c.limit = t
+}
+
+// Write sends a request, the deadline for the request and its response
+// starts here
+func (c *Conn) Write(msg []byte) (int, error) {
+	deadline := time.Now().Add(c.timeout)
+	if !c.limit.IsZero() && c.limit.Before(deadline) {
+		deadline = c.limit
+	}
+	err := c.Conn.SetDeadline(deadline)
+	if err != nil {
+		return 0, err
+	}
+	return c.Conn.Write(msg)
+}
+
+// ReadResponse reads the response to the last request; after an error
+// other than ErrNoResponse, e.g. a timeout on a socket, responses can't be
+// matched to requests anymore and the connection has to be closed
+func (c *Conn) ReadResponse() (json.RawMessage, error) {
+	var resp json.RawMessage
+	err := c.decoder.Decode(&resp)
+	if err != nil {
+		if serial, ok := c.Conn.(*serialConn); ok && errors.Is(err, os.ErrDeadlineExceeded) {
+			// the next request starts with the next frame
+			serial.reset()
+			c.decoder = json.NewDecoder(c.Conn)
+			return nil, fmt.Errorf("%w: %w", ErrNoResponse, err)
+		}
+		return nil, err
+	}
+	return resp, nil
+}
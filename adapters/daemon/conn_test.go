@@ -0,0 +1,85 @@
+package daemon
+
+import (
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestConnFraming(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	// the daemon splits the first response and sends the next two at once
+	go func() {
+		buf := make([]byte, 1024)
+		server.Read(buf)
+		server.Write([]byte(`{"speed":`))
+		server.Write([]byte(`"5"}`))
+		server.Read(buf)
+		server.Write([]byte(`{"speed":"6"}{"speed":"7"}`))
+	}()
+
+	conn := NewConn(client, time.Second)
+	for _, expected := range []string{`{"speed":"5"}`, `{"speed":"6"}`} {
+		_, err := conn.Write([]byte(`{"type":"query"}`))
+		if err != nil {
+			t.Fatalf("Failed to write request: %s", err)
+		}
+		resp, err := conn.ReadResponse()
+		if err != nil {
+			t.Fatalf("Failed to read response: %s", err)
+		}
+		if string(resp) != expected {
+			t.Errorf("Expected %s, got %s", expected, resp)
+		}
+	}
+	resp, err := conn.ReadResponse()
+	if (err != nil) || (string(resp) != `{"speed":"7"}`) {
+		t.Errorf("Expected the buffered response, got %s, %v", resp, err)
+	}
+}
+
+func TestConnTimeout(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	// the daemon reads requests but never answers
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			if _, err := server.Read(buf); err != nil {
+				return
+			}
+		}
+	}()
+
+	conn := NewConn(client, 50*time.Millisecond)
+	start := time.Now()
+	_, err := conn.Write([]byte(`{"type":"query"}`))
+	if err != nil {
+		t.Fatalf("Failed to write request: %s", err)
+	}
+	_, err = conn.ReadResponse()
+	if !os.IsTimeout(err) {
+		t.Errorf("Expected timeout, got %v", err)
+	}
+
+	// a limit earlier than the request timeout wins
+	conn = NewConn(client, time.Hour)
+	conn.SetLimit(time.Now().Add(50 * time.Millisecond))
+	_, err = conn.Write([]byte(`{"type":"query"}`))
+	if err != nil {
+		t.Fatalf("Failed to write request: %s", err)
+	}
+	_, err = conn.ReadResponse()
+	if !os.IsTimeout(err) {
+		t.Errorf("Expected timeout, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("Requests took %s", time.Since(start))
+	}
+}
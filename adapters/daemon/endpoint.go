@@ -0,0 +1,114 @@
+package daemon
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/moosethebrown/ship-net-bridge/address"
+)
+
+const (
+	DefaultKeepAlive   = 15 * time.Second
+	DefaultDialTimeout = 10 * time.Second
+)
+
+// TLS configures tls:// endpoints; setting CertFile and KeyFile enables
+// mutual TLS, CaFile replaces system roots for verifying the daemon
+type TLS struct {
+	CaFile     string
+	CertFile   string
+	KeyFile    string
+	ServerName string
+}
+
+// Endpoint is the address of a ship daemon: a unix socket path or a
+// unix:///path, tcp://host:port or tls://host:port URL, or of a controller
+// on a serial port: serial:///dev/ttyS0. Requests and responses are exchanged
+// the same way over all of them, see EncodeFrame for serial framing.
+type Endpoint struct {
+	Address string
+	// BaudRate applies to serial, zero means DefaultBaudRate
+	BaudRate int
+	// KeepAlive applies to tcp and tls, zero means DefaultKeepAlive,
+	// negative disables keepalives
+	KeepAlive time.Duration
+	// RequestTimeout bounds each request and its response on connections
+	// made by Connect, zero means DefaultRequestTimeout
+	RequestTimeout time.Duration
+	TLS            *TLS
+}
+
+func (e *Endpoint) Dial(ctx context.Context) (net.Conn, error) {
+	network, addr, err := address.Daemon(e.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   DefaultDialTimeout,
+		KeepAlive: e.KeepAlive,
+	}
+	if dialer.KeepAlive == 0 {
+		dialer.KeepAlive = DefaultKeepAlive
+	}
+
+	switch network {
+	case "serial":
+		return openSerial(addr, e.BaudRate)
+	case "tls":
+		tlsConfig, err := e.tlsConfig(addr)
+		if err != nil {
+			return nil, err
+		}
+		tlsDialer := &tls.Dialer{
+			NetDialer: dialer,
+			Config:    tlsConfig,
+		}
+		return tlsDialer.DialContext(ctx, "tcp", addr)
+	default:
+		return dialer.DialContext(ctx, network, addr)
+	}
+}
+
+func (e *Endpoint) tlsConfig(addr string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.ServerName = host
+	if e.TLS == nil {
+		return tlsConfig, nil
+	}
+
+	if e.TLS.ServerName != "" {
+		tlsConfig.ServerName = e.TLS.ServerName
+	}
+	if e.TLS.CaFile != "" {
+		ca, err := os.ReadFile(e.TLS.CaFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in %s", e.TLS.CaFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if e.TLS.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(e.TLS.CertFile, e.TLS.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,189 @@
+package daemon
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// echo answers every message with the message itself, like a daemon
+// answers a request with a response
+func echo(t *testing.T, l net.Listener) {
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				buf := make([]byte, 1024)
+				for {
+					n, err := conn.Read(buf)
+					if err != nil {
+						return
+					}
+					conn.Write(buf[:n])
+				}
+			}()
+		}
+	}()
+	t.Cleanup(func() { l.Close() })
+}
+
+func roundTrip(t *testing.T, e *Endpoint) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	conn, err := e.Dial(ctx)
+	if err != nil {
+		t.Fatalf("Failed to dial %s: %s", e.Address, err)
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(time.Second))
+	msg := `{"type":"query"}`
+	_, err = conn.Write([]byte(msg))
+	if err != nil {
+		t.Fatalf("Failed to write to %s: %s", e.Address, err)
+	}
+	buf := make([]byte, 1024)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("Failed to read from %s: %s", e.Address, err)
+	}
+	if string(buf[:n]) != msg {
+		t.Errorf("Expected %s, got %s", msg, buf[:n])
+	}
+}
+
+func TestUnixAndTcp(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "daemon.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("Failed to listen on %s: %s", socket, err)
+	}
+	echo(t, l)
+	roundTrip(t, &Endpoint{Address: socket})
+	roundTrip(t, &Endpoint{Address: "unix://" + socket})
+
+	l, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen on tcp: %s", err)
+	}
+	echo(t, l)
+	roundTrip(t, &Endpoint{Address: "tcp://" + l.Addr().String(), KeepAlive: -1})
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := newCert(t, dir, "ca", nil, nil)
+	serverCert, _ := newCert(t, dir, "server", ca, caKey)
+	newCert(t, dir, "client", ca, caKey)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	serverPair, err := tls.LoadX509KeyPair(filepath.Join(dir, "server.pem"), filepath.Join(dir, "server.key"))
+	if err != nil {
+		t.Fatalf("Failed to load server certificate: %s", err)
+	}
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{serverPair},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})
+	if err != nil {
+		t.Fatalf("Failed to listen on tls: %s", err)
+	}
+	echo(t, l)
+
+	e := &Endpoint{
+		Address: "tls://" + l.Addr().String(),
+		TLS: &TLS{
+			CaFile:     filepath.Join(dir, "ca.pem"),
+			CertFile:   filepath.Join(dir, "client.pem"),
+			KeyFile:    filepath.Join(dir, "client.key"),
+			ServerName: serverCert.Subject.CommonName,
+		},
+	}
+	roundTrip(t, e)
+
+	// the daemon requires a client certificate
+	e.TLS.CertFile = ""
+	e.TLS.KeyFile = ""
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	conn, err := e.Dial(ctx)
+	if err == nil {
+		conn.SetDeadline(time.Now().Add(time.Second))
+		// TLS 1.3 reports client certificate errors on first read
+		conn.Write([]byte("{}"))
+		_, err = conn.Read(make([]byte, 16))
+		conn.Close()
+	}
+	if err == nil {
+		t.Errorf("Expected connection without client certificate to fail")
+	}
+}
+
+// newCert writes <name>.pem and <name>.key to dir, the certificate is self-signed
+// when parent is nil
+func newCert(t *testing.T, dir string, name string, parent *x509.Certificate,
+	parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %s", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name + ".ship.local"},
+		DNSNames:     []string{name + ".ship.local"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		parent = template
+		parentKey = key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %s", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %s", err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %s", err)
+	}
+
+	err = os.WriteFile(filepath.Join(dir, name+".pem"),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	if err == nil {
+		err = os.WriteFile(filepath.Join(dir, name+".key"),
+			pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	}
+	if err != nil {
+		t.Fatalf("Failed to write certificate: %s", err)
+	}
+
+	return cert, key
+}
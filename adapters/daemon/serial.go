@@ -0,0 +1,157 @@
+package daemon
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/moosethebrown/ship-net-bridge/metrics"
+)
+
+// DefaultBaudRate is used for serial endpoints unless configured otherwise
+const DefaultBaudRate = 115200
+
+// ErrChecksum is returned by DecodeFrame for frames with a wrong checksum
+var ErrChecksum = errors.New("serial frame checksum mismatch")
+
+// Frames on serial lines look like NMEA 0183 sentences so that they are easy
+// to produce and check on a microcontroller: $<message>*<checksum>\r\n, where
+// checksum is two hex digits of XOR of message bytes. Messages are compact
+// JSON and never contain line breaks.
+const (
+	frameStart = '$'
+	frameEnd   = '*'
+)
+
+func checksum(msg []byte) byte {
+	var sum byte
+	for _, b := range msg {
+		sum ^= b
+	}
+	return sum
+}
+
+// EncodeFrame wraps msg into a serial frame
+func EncodeFrame(msg []byte) []byte {
+	frame := make([]byte, 0, len(msg)+6)
+	frame = append(frame, frameStart)
+	frame = append(frame, msg...)
+	return append(frame, fmt.Sprintf("%c%02X\r\n", frameEnd, checksum(msg))...)
+}
+
+// DecodeFrame checks a line received on a serial line and returns its message;
+// ok is false for lines that are not frames, e.g. boot messages
+func DecodeFrame(line []byte) (msg []byte, ok bool, err error) {
+	line = bytes.TrimRight(line, "\r\n")
+	if (len(line) == 0) || (line[0] != frameStart) {
+		return nil, false, nil
+	}
+
+	end := bytes.LastIndexByte(line, frameEnd)
+	if (end < 0) || (len(line)-end != 3) {
+		return nil, true, fmt.Errorf("malformed serial frame: %q", line)
+	}
+	sum, err := strconv.ParseUint(string(line[end+1:]), 16, 8)
+	if err != nil {
+		return nil, true, fmt.Errorf("malformed serial frame checksum: %q", line)
+	}
+	msg = line[1:end]
+	if checksum(msg) != byte(sum) {
+		return nil, true, ErrChecksum
+	}
+	return msg, true, nil
+}
+
+// serialConn frames messages written to a serial port and returns
+// messages of received frames from Read as a stream, so that it can be
+// used like a daemon socket. Corrupted frames are skipped, a response lost
+// that way times out like a missing one instead of failing the connection.
+type serialConn struct {
+	port   *os.File
+	reader *bufio.Reader
+	// pending is the rest of a message that didn't fit into a Read buffer
+	pending []byte
+}
+
+type serialAddr string
+
+func (a serialAddr) Network() string {
+	return "serial"
+}
+
+func (a serialAddr) String() string {
+	return string(a)
+}
+
+func newSerialConn(port *os.File) *serialConn {
+	return &serialConn{
+		port:   port,
+		reader: bufio.NewReader(port),
+	}
+}
+
+func (c *serialConn) Read(b []byte) (int, error) {
+	for len(c.pending) == 0 {
+		line, err := c.reader.ReadBytes('\n')
+		if err != nil {
+			return 0, err
+		}
+
+		msg, ok, err := DecodeFrame(line)
+		if err != nil {
+			metrics.Errors.WithLabelValues("serial", "frame").Inc()
+			continue
+		}
+		if ok {
+			c.pending = msg
+		}
+	}
+
+	n := copy(b, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+// reset discards received data not read yet, so that a late response
+// isn't taken for the response to the next request
+func (c *serialConn) reset() {
+	c.pending = nil
+	c.reader.Discard(c.reader.Buffered())
+}
+
+func (c *serialConn) Write(b []byte) (int, error) {
+	_, err := c.port.Write(EncodeFrame(b))
+	if err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (c *serialConn) Close() error {
+	return c.port.Close()
+}
+
+func (c *serialConn) LocalAddr() net.Addr {
+	return serialAddr(c.port.Name())
+}
+
+func (c *serialConn) RemoteAddr() net.Addr {
+	return serialAddr(c.port.Name())
+}
+
+func (c *serialConn) SetDeadline(t time.Time) error {
+	return c.port.SetDeadline(t)
+}
+
+func (c *serialConn) SetReadDeadline(t time.Time) error {
+	return c.port.SetReadDeadline(t)
+}
+
+func (c *serialConn) SetWriteDeadline(t time.Time) error {
+	return c.port.SetWriteDeadline(t)
+}
@@ -0,0 +1,77 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/moosethebrown/ship-net-bridge/address"
+	"golang.org/x/sys/unix"
+)
+
+func openSerial(device string, baudRate int) (*serialConn, error) {
+	if baudRate == 0 {
+		baudRate = DefaultBaudRate
+	}
+	port, err := OpenSerial(device, baudRate)
+	if err != nil {
+		return nil, err
+	}
+	return newSerialConn(port), nil
+}
+
+// OpenSerial opens device in raw 8N1 mode without framing, reads and writes
+// support deadlines
+func OpenSerial(device string, baudRate int) (*os.File, error) {
+	speed, ok := address.BaudSpeed(baudRate)
+	if !ok {
+		return nil, fmt.Errorf("unsupported baud rate %d", baudRate)
+	}
+
+	// non-blocking mode lets reads and writes use deadlines
+	port, err := os.OpenFile(device, os.O_RDWR|unix.O_NOCTTY|unix.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	rawConn, err := port.SyscallConn()
+	if err == nil {
+		controlErr := rawConn.Control(func(fd uintptr) {
+			err = setRaw(int(fd), speed)
+		})
+		if controlErr != nil {
+			err = controlErr
+		}
+	}
+	if err != nil {
+		port.Close()
+		return nil, fmt.Errorf("failed to configure %s: %w", device, err)
+	}
+
+	return port, nil
+}
+
+func setRaw(fd int, speed uint32) error {
+	t, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return err
+	}
+
+	// cfmakeraw
+	t.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP |
+		unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	t.Oflag &^= unix.OPOST
+	t.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	t.Cflag &^= unix.CSIZE | unix.PARENB | unix.CSTOPB | unix.CRTSCTS | unix.CBAUD
+	t.Cflag |= unix.CS8 | unix.CREAD | unix.CLOCAL | speed
+	t.Ispeed = speed
+	t.Ospeed = speed
+	t.Cc[unix.VMIN] = 1
+	t.Cc[unix.VTIME] = 0
+
+	err = unix.IoctlSetTermios(fd, unix.TCSETS, t)
+	if err != nil {
+		return err
+	}
+	// drop whatever arrived before the port was configured
+	return unix.IoctlSetInt(fd, unix.TCFLSH, unix.TCIFLUSH)
+}
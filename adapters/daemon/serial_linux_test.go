@@ -0,0 +1,131 @@
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// openPty returns the controller side of a pseudo-terminal pair
+// and the device path of the other side
+func openPty(t *testing.T) (*os.File, string) {
+	ptmx, err := os.OpenFile("/dev/ptmx", os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		t.Skipf("pseudo-terminals are not available: %s", err)
+	}
+	t.Cleanup(func() { ptmx.Close() })
+
+	fd := int(ptmx.Fd())
+	err = unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0)
+	if err != nil {
+		t.Fatalf("Failed to unlock pseudo-terminal: %s", err)
+	}
+	n, err := unix.IoctlGetInt(fd, unix.TIOCGPTN)
+	if err != nil {
+		t.Fatalf("Failed to get pseudo-terminal number: %s", err)
+	}
+
+	return ptmx, fmt.Sprintf("/dev/pts/%d", n)
+}
+
+func TestSerial(t *testing.T) {
+	controller, device := openPty(t)
+
+	e := &Endpoint{Address: "serial://" + device, BaudRate: 57600}
+	conn, err := e.Dial(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to open %s: %s", device, err)
+	}
+	defer conn.Close()
+
+	// the motor controller answers every command with its state
+	go func() {
+		reader := bufio.NewReader(controller)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if err != nil {
+				return
+			}
+			_, ok, err := DecodeFrame(line)
+			if !ok || (err != nil) {
+				controller.Write(EncodeFrame([]byte(`{"error":"bad frame"}`)))
+				continue
+			}
+			controller.Write([]byte("debug: command received\r\n"))
+			controller.Write(EncodeFrame([]byte(`{"speed":"5"}`)))
+		}
+	}()
+
+	conn.SetDeadline(time.Now().Add(time.Second))
+	_, err = conn.Write([]byte(`{"type":"cmd","cmd":"set_speed","data":"5"}`))
+	if err != nil {
+		t.Fatalf("Failed to write command: %s", err)
+	}
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("Failed to read response: %s", err)
+	}
+	if string(buf[:n]) != `{"speed":"5"}` {
+		t.Errorf("Unexpected response %s", buf[:n])
+	}
+
+	// reads time out like on sockets
+	conn.SetDeadline(time.Now().Add(50 * time.Millisecond))
+	_, err = conn.Read(buf)
+	if !os.IsTimeout(err) {
+		t.Errorf("Expected read timeout, got %v", err)
+	}
+}
+
+func TestSerialCorruptedFrame(t *testing.T) {
+	controller, device := openPty(t)
+
+	e := &Endpoint{Address: "serial://" + device, RequestTimeout: 100 * time.Millisecond}
+	conn, err := e.Connect(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to open %s: %s", device, err)
+	}
+	defer conn.Close()
+
+	// the response to the first command gets corrupted on the line
+	go func() {
+		reader := bufio.NewReader(controller)
+		for i := 0; ; i++ {
+			_, err := reader.ReadBytes('\n')
+			if err != nil {
+				return
+			}
+			frame := EncodeFrame([]byte(fmt.Sprintf(`{"speed":"%d"}`, i)))
+			if i == 0 {
+				frame[3] = 'x'
+			}
+			controller.Write(frame)
+		}
+	}()
+
+	_, err = conn.Write([]byte(`{"type":"cmd","cmd":"set_speed","data":"5"}`))
+	if err != nil {
+		t.Fatalf("Failed to write command: %s", err)
+	}
+	_, err = conn.ReadResponse()
+	if !errors.Is(err, ErrNoResponse) {
+		t.Fatalf("Expected no response, got %v", err)
+	}
+
+	// the connection is still usable
+	_, err = conn.Write([]byte(`{"type":"cmd","cmd":"set_speed","data":"6"}`))
+	if err != nil {
+		t.Fatalf("Failed to write command: %s", err)
+	}
+	resp, err := conn.ReadResponse()
+	if (err != nil) || (string(resp) != `{"speed":"1"}`) {
+		t.Errorf("Expected the second response, got %s %v", resp, err)
+	}
+}
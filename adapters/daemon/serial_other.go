@@ -0,0 +1,16 @@
+//go:build !linux
+
+package daemon
+
+import (
+	"errors"
+	"os"
+)
+
+func openSerial(device string, baudRate int) (*serialConn, error) {
+	return nil, errors.New("serial ports are only supported on Linux")
+}
+
+func OpenSerial(device string, baudRate int) (*os.File, error) {
+	return nil, errors.New("serial ports are only supported on Linux")
+}
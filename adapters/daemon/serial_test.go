@@ -0,0 +1,38 @@
+package daemon
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFrame(t *testing.T) {
+	msg := []byte(`{"type":"cmd","cmd":"set_speed","data":"5"}`)
+	frame := EncodeFrame(msg)
+	if (frame[0] != '$') || (string(frame[len(frame)-2:]) != "\r\n") {
+		t.Fatalf("Unexpected frame %q", frame)
+	}
+
+	decoded, ok, err := DecodeFrame(frame)
+	if !ok || (err != nil) || (string(decoded) != string(msg)) {
+		t.Errorf("Expected %s, got %s %v %v", msg, decoded, ok, err)
+	}
+
+	// messages may contain the checksum separator
+	msg = []byte(`{"data":"a*b"}`)
+	decoded, _, err = DecodeFrame(EncodeFrame(msg))
+	if (err != nil) || (string(decoded) != string(msg)) {
+		t.Errorf("Expected %s, got %s %v", msg, decoded, err)
+	}
+
+	corrupted := EncodeFrame([]byte(`{"data":"5"}`))
+	corrupted[3] = 'x'
+	_, ok, err = DecodeFrame(corrupted)
+	if !ok || !errors.Is(err, ErrChecksum) {
+		t.Errorf("Expected checksum mismatch, got %v %v", ok, err)
+	}
+
+	_, ok, err = DecodeFrame([]byte("motor controller v1.2 ready\r\n"))
+	if ok || (err != nil) {
+		t.Errorf("Expected non-frame line to be skipped, got %v %v", ok, err)
+	}
+}
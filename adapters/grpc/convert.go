@@ -0,0 +1,112 @@
+package grpc
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/moosethebrown/ship-net-bridge/adapters/grpc/pb"
+	"github.com/moosethebrown/ship-net-bridge/core"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// formatWaypoints returns waypoints as command data: lat,lon;lat,lon
+func formatWaypoints(waypoints []*pb.Waypoint) string {
+	points := make([]string, 0, len(waypoints))
+	for _, wp := range waypoints {
+		points = append(points, strconv.FormatFloat(wp.GetLatitude(), 'f', -1, 64)+","+
+			strconv.FormatFloat(wp.GetLongitude(), 'f', -1, 64))
+	}
+	return strings.Join(points, ";")
+}
+
+func toTelemetry(t *core.Telemetry) *pb.Telemetry {
+	if t == nil {
+		return nil
+	}
+	telemetry := &pb.Telemetry{
+		Latitude:  t.Latitude,
+		Longitude: t.Longitude,
+		Heading:   t.Heading,
+		Speed:     t.Speed,
+		Timestamp: timestamppb.New(t.Timestamp),
+	}
+	if t.Battery != nil {
+		telemetry.Battery = &pb.Battery{
+			Voltage: t.Battery.Voltage,
+			Current: t.Battery.Current,
+			Charge:  t.Battery.Charge,
+		}
+	}
+	return telemetry
+}
+
+func toFix(f *core.Fix) *pb.Fix {
+	if f == nil {
+		return nil
+	}
+	return &pb.Fix{
+		Latitude:   f.Latitude,
+		Longitude:  f.Longitude,
+		Quality:    int32(f.Quality),
+		Satellites: int32(f.Satellites),
+		Hdop:       f.Hdop,
+		Altitude:   f.Altitude,
+		Course:     f.Course,
+		Speed:      f.Speed,
+		Heading:    f.Heading,
+		Timestamp:  timestamppb.New(f.Timestamp),
+	}
+}
+
+func toAisTarget(t *core.AisTarget) *pb.AisTarget {
+	if t == nil {
+		return nil
+	}
+	return &pb.AisTarget{
+		Mmsi:      t.Mmsi,
+		Name:      t.Name,
+		Latitude:  t.Latitude,
+		Longitude: t.Longitude,
+		Course:    t.Course,
+		Speed:     t.Speed,
+		Heading:   t.Heading,
+		Distance:  t.Distance,
+		Cpa:       t.Cpa,
+		Tcpa:      t.Tcpa,
+		Warning:   t.Warning,
+		Timestamp: timestamppb.New(t.Timestamp),
+	}
+}
+
+func toWaypoints(waypoints []core.Waypoint) []*pb.Waypoint {
+	result := make([]*pb.Waypoint, 0, len(waypoints))
+	for _, wp := range waypoints {
+		result = append(result, &pb.Waypoint{
+			Latitude:  wp.Latitude,
+			Longitude: wp.Longitude,
+		})
+	}
+	return result
+}
+
+// toEvent decodes resp as a bridge response, messages of the daemons
+// only have the payload
+func toEvent(shipId string, resp *core.Response, payload []byte) *pb.Event {
+	event := &pb.Event{
+		ShipId:  shipId,
+		Payload: string(payload),
+	}
+	if resp == nil {
+		return event
+	}
+	event.Type = resp.Type
+	event.Cmd = resp.Cmd
+	event.Data = resp.Data
+	event.Error = resp.Error
+	event.Fix = toFix(resp.Fix)
+	for _, t := range resp.Targets {
+		event.Targets = append(event.Targets, toAisTarget(t))
+	}
+	event.Target = toAisTarget(resp.Target)
+	return event
+}
@@ -0,0 +1,888 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: bridge.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	ShipNetBridge_SpeedUp_FullMethodName          = "/shipnetbridge.v1.ShipNetBridge/SpeedUp"
+	ShipNetBridge_SpeedDown_FullMethodName        = "/shipnetbridge.v1.ShipNetBridge/SpeedDown"
+	ShipNetBridge_TurnLeft_FullMethodName         = "/shipnetbridge.v1.ShipNetBridge/TurnLeft"
+	ShipNetBridge_TurnRight_FullMethodName        = "/shipnetbridge.v1.ShipNetBridge/TurnRight"
+	ShipNetBridge_SetSpeed_FullMethodName         = "/shipnetbridge.v1.ShipNetBridge/SetSpeed"
+	ShipNetBridge_SetSteering_FullMethodName      = "/shipnetbridge.v1.ShipNetBridge/SetSteering"
+	ShipNetBridge_SetWaypoints_FullMethodName     = "/shipnetbridge.v1.ShipNetBridge/SetWaypoints"
+	ShipNetBridge_AddWaypoint_FullMethodName      = "/shipnetbridge.v1.ShipNetBridge/AddWaypoint"
+	ShipNetBridge_ClearWaypoints_FullMethodName   = "/shipnetbridge.v1.ShipNetBridge/ClearWaypoints"
+	ShipNetBridge_SetHomeWaypoint_FullMethodName  = "/shipnetbridge.v1.ShipNetBridge/SetHomeWaypoint"
+	ShipNetBridge_NavStart_FullMethodName         = "/shipnetbridge.v1.ShipNetBridge/NavStart"
+	ShipNetBridge_NavStop_FullMethodName          = "/shipnetbridge.v1.ShipNetBridge/NavStop"
+	ShipNetBridge_StartCalibration_FullMethodName = "/shipnetbridge.v1.ShipNetBridge/StartCalibration"
+	ShipNetBridge_StopCalibration_FullMethodName  = "/shipnetbridge.v1.ShipNetBridge/StopCalibration"
+	ShipNetBridge_ExportTrack_FullMethodName      = "/shipnetbridge.v1.ShipNetBridge/ExportTrack"
+	ShipNetBridge_EmergencyStop_FullMethodName    = "/shipnetbridge.v1.ShipNetBridge/EmergencyStop"
+	ShipNetBridge_Query_FullMethodName            = "/shipnetbridge.v1.ShipNetBridge/Query"
+	ShipNetBridge_GetState_FullMethodName         = "/shipnetbridge.v1.ShipNetBridge/GetState"
+	ShipNetBridge_StreamTelemetry_FullMethodName  = "/shipnetbridge.v1.ShipNetBridge/StreamTelemetry"
+	ShipNetBridge_StreamEvents_FullMethodName     = "/shipnetbridge.v1.ShipNetBridge/StreamEvents"
+)
+
+// ShipNetBridgeClient is the client API for ShipNetBridge service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// ShipNetBridge is a typed front-end to the bridge. Commands take the same
+// path through the vessel's core as requests of other transports: they are
+// queued by command class, rejected when stale (see the call deadline) and
+// answered with the response of ship-control or ship-nav. Error responses
+// fail the call with FAILED_PRECONDITION.
+//
+// ship_id selects the vessel and may be empty when the bridge serves
+// a single one.
+type ShipNetBridgeClient interface {
+	SpeedUp(ctx context.Context, in *CommandRequest, opts ...grpc.CallOption) (*CommandReply, error)
+	SpeedDown(ctx context.Context, in *CommandRequest, opts ...grpc.CallOption) (*CommandReply, error)
+	TurnLeft(ctx context.Context, in *CommandRequest, opts ...grpc.CallOption) (*CommandReply, error)
+	TurnRight(ctx context.Context, in *CommandRequest, opts ...grpc.CallOption) (*CommandReply, error)
+	SetSpeed(ctx context.Context, in *SetValueRequest, opts ...grpc.CallOption) (*CommandReply, error)
+	SetSteering(ctx context.Context, in *SetValueRequest, opts ...grpc.CallOption) (*CommandReply, error)
+	SetWaypoints(ctx context.Context, in *WaypointsRequest, opts ...grpc.CallOption) (*CommandReply, error)
+	AddWaypoint(ctx context.Context, in *WaypointRequest, opts ...grpc.CallOption) (*CommandReply, error)
+	ClearWaypoints(ctx context.Context, in *CommandRequest, opts ...grpc.CallOption) (*CommandReply, error)
+	SetHomeWaypoint(ctx context.Context, in *WaypointRequest, opts ...grpc.CallOption) (*CommandReply, error)
+	NavStart(ctx context.Context, in *CommandRequest, opts ...grpc.CallOption) (*CommandReply, error)
+	// NavStop replies once the stop is queued, ship-nav's response to it
+	// is an event
+	NavStop(ctx context.Context, in *CommandRequest, opts ...grpc.CallOption) (*CommandReply, error)
+	StartCalibration(ctx context.Context, in *CommandRequest, opts ...grpc.CallOption) (*CommandReply, error)
+	StopCalibration(ctx context.Context, in *CommandRequest, opts ...grpc.CallOption) (*CommandReply, error)
+	ExportTrack(ctx context.Context, in *ExportTrackRequest, opts ...grpc.CallOption) (*ExportTrackReply, error)
+	EmergencyStop(ctx context.Context, in *CommandRequest, opts ...grpc.CallOption) (*CommandReply, error)
+	// Query asks ship-nav for telemetry
+	Query(ctx context.Context, in *CommandRequest, opts ...grpc.CallOption) (*QueryReply, error)
+	// GetState returns the vessel state known to the bridge without asking
+	// the daemons
+	GetState(ctx context.Context, in *CommandRequest, opts ...grpc.CallOption) (*VesselState, error)
+	// StreamTelemetry sends ship-nav telemetry as it is received, checked
+	// every interval
+	StreamTelemetry(ctx context.Context, in *TelemetryStreamRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[TelemetryUpdate], error)
+	// StreamEvents sends messages other transports get uncorrelated: announces,
+	// responses to requests of other transports, NMEA fixes, AIS targets and
+	// collision warnings
+	StreamEvents(ctx context.Context, in *EventStreamRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error)
+}
+
+type shipNetBridgeClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewShipNetBridgeClient(cc grpc.ClientConnInterface) ShipNetBridgeClient {
+	return &shipNetBridgeClient{cc}
+}
+
+func (c *shipNetBridgeClient) SpeedUp(ctx context.Context, in *CommandRequest, opts ...grpc.CallOption) (*CommandReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CommandReply)
+	err := c.cc.Invoke(ctx, ShipNetBridge_SpeedUp_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shipNetBridgeClient) SpeedDown(ctx context.Context, in *CommandRequest, opts ...grpc.CallOption) (*CommandReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CommandReply)
+	err := c.cc.Invoke(ctx, ShipNetBridge_SpeedDown_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shipNetBridgeClient) TurnLeft(ctx context.Context, in *CommandRequest, opts ...grpc.CallOption) (*CommandReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CommandReply)
+	err := c.cc.Invoke(ctx, ShipNetBridge_TurnLeft_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shipNetBridgeClient) TurnRight(ctx context.Context, in *CommandRequest, opts ...grpc.CallOption) (*CommandReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CommandReply)
+	err := c.cc.Invoke(ctx, ShipNetBridge_TurnRight_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shipNetBridgeClient) SetSpeed(ctx context.Context, in *SetValueRequest, opts ...grpc.CallOption) (*CommandReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CommandReply)
+	err := c.cc.Invoke(ctx, ShipNetBridge_SetSpeed_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shipNetBridgeClient) SetSteering(ctx context.Context, in *SetValueRequest, opts ...grpc.CallOption) (*CommandReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CommandReply)
+	err := c.cc.Invoke(ctx, ShipNetBridge_SetSteering_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shipNetBridgeClient) SetWaypoints(ctx context.Context, in *WaypointsRequest, opts ...grpc.CallOption) (*CommandReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CommandReply)
+	err := c.cc.Invoke(ctx, ShipNetBridge_SetWaypoints_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shipNetBridgeClient) AddWaypoint(ctx context.Context, in *WaypointRequest, opts ...grpc.CallOption) (*CommandReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CommandReply)
+	err := c.cc.Invoke(ctx, ShipNetBridge_AddWaypoint_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shipNetBridgeClient) ClearWaypoints(ctx context.Context, in *CommandRequest, opts ...grpc.CallOption) (*CommandReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CommandReply)
+	err := c.cc.Invoke(ctx, ShipNetBridge_ClearWaypoints_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shipNetBridgeClient) SetHomeWaypoint(ctx context.Context, in *WaypointRequest, opts ...grpc.CallOption) (*CommandReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CommandReply)
+	err := c.cc.Invoke(ctx, ShipNetBridge_SetHomeWaypoint_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shipNetBridgeClient) NavStart(ctx context.Context, in *CommandRequest, opts ...grpc.CallOption) (*CommandReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CommandReply)
+	err := c.cc.Invoke(ctx, ShipNetBridge_NavStart_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shipNetBridgeClient) NavStop(ctx context.Context, in *CommandRequest, opts ...grpc.CallOption) (*CommandReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CommandReply)
+	err := c.cc.Invoke(ctx, ShipNetBridge_NavStop_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shipNetBridgeClient) StartCalibration(ctx context.Context, in *CommandRequest, opts ...grpc.CallOption) (*CommandReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CommandReply)
+	err := c.cc.Invoke(ctx, ShipNetBridge_StartCalibration_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shipNetBridgeClient) StopCalibration(ctx context.Context, in *CommandRequest, opts ...grpc.CallOption) (*CommandReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CommandReply)
+	err := c.cc.Invoke(ctx, ShipNetBridge_StopCalibration_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shipNetBridgeClient) ExportTrack(ctx context.Context, in *ExportTrackRequest, opts ...grpc.CallOption) (*ExportTrackReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ExportTrackReply)
+	err := c.cc.Invoke(ctx, ShipNetBridge_ExportTrack_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shipNetBridgeClient) EmergencyStop(ctx context.Context, in *CommandRequest, opts ...grpc.CallOption) (*CommandReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CommandReply)
+	err := c.cc.Invoke(ctx, ShipNetBridge_EmergencyStop_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shipNetBridgeClient) Query(ctx context.Context, in *CommandRequest, opts ...grpc.CallOption) (*QueryReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(QueryReply)
+	err := c.cc.Invoke(ctx, ShipNetBridge_Query_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shipNetBridgeClient) GetState(ctx context.Context, in *CommandRequest, opts ...grpc.CallOption) (*VesselState, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(VesselState)
+	err := c.cc.Invoke(ctx, ShipNetBridge_GetState_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shipNetBridgeClient) StreamTelemetry(ctx context.Context, in *TelemetryStreamRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[TelemetryUpdate], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ShipNetBridge_ServiceDesc.Streams[0], ShipNetBridge_StreamTelemetry_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[TelemetryStreamRequest, TelemetryUpdate]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ShipNetBridge_StreamTelemetryClient = grpc.ServerStreamingClient[TelemetryUpdate]
+
+func (c *shipNetBridgeClient) StreamEvents(ctx context.Context, in *EventStreamRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ShipNetBridge_ServiceDesc.Streams[1], ShipNetBridge_StreamEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[EventStreamRequest, Event]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ShipNetBridge_StreamEventsClient = grpc.ServerStreamingClient[Event]
+
+// ShipNetBridgeServer is the server API for ShipNetBridge service.
+// All implementations must embed UnimplementedShipNetBridgeServer
+// for forward compatibility.
+//
+// ShipNetBridge is a typed front-end to the bridge. Commands take the same
+// path through the vessel's core as requests of other transports: they are
+// queued by command class, rejected when stale (see the call deadline) and
+// answered with the response of ship-control or ship-nav. Error responses
+// fail the call with FAILED_PRECONDITION.
+//
+// ship_id selects the vessel and may be empty when the bridge serves
+// a single one.
+type ShipNetBridgeServer interface {
+	SpeedUp(context.Context, *CommandRequest) (*CommandReply, error)
+	SpeedDown(context.Context, *CommandRequest) (*CommandReply, error)
+	TurnLeft(context.Context, *CommandRequest) (*CommandReply, error)
+	TurnRight(context.Context, *CommandRequest) (*CommandReply, error)
+	SetSpeed(context.Context, *SetValueRequest) (*CommandReply, error)
+	SetSteering(context.Context, *SetValueRequest) (*CommandReply, error)
+	SetWaypoints(context.Context, *WaypointsRequest) (*CommandReply, error)
+	AddWaypoint(context.Context, *WaypointRequest) (*CommandReply, error)
+	ClearWaypoints(context.Context, *CommandRequest) (*CommandReply, error)
+	SetHomeWaypoint(context.Context, *WaypointRequest) (*CommandReply, error)
+	NavStart(context.Context, *CommandRequest) (*CommandReply, error)
+	// NavStop replies once the stop is queued, ship-nav's response to it
+	// is an event
+	NavStop(context.Context, *CommandRequest) (*CommandReply, error)
+	StartCalibration(context.Context, *CommandRequest) (*CommandReply, error)
+	StopCalibration(context.Context, *CommandRequest) (*CommandReply, error)
+	ExportTrack(context.Context, *ExportTrackRequest) (*ExportTrackReply, error)
+	EmergencyStop(context.Context, *CommandRequest) (*CommandReply, error)
+	// Query asks ship-nav for telemetry
+	Query(context.Context, *CommandRequest) (*QueryReply, error)
+	// GetState returns the vessel state known to the bridge without asking
+	// the daemons
+	GetState(context.Context, *CommandRequest) (*VesselState, error)
+	// StreamTelemetry sends ship-nav telemetry as it is received, checked
+	// every interval
+	StreamTelemetry(*TelemetryStreamRequest, grpc.ServerStreamingServer[TelemetryUpdate]) error
+	// StreamEvents sends messages other transports get uncorrelated: announces,
+	// responses to requests of other transports, NMEA fixes, AIS targets and
+	// collision warnings
+	StreamEvents(*EventStreamRequest, grpc.ServerStreamingServer[Event]) error
+	mustEmbedUnimplementedShipNetBridgeServer()
+}
+
+// UnimplementedShipNetBridgeServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedShipNetBridgeServer struct{}
+
+func (UnimplementedShipNetBridgeServer) SpeedUp(context.Context, *CommandRequest) (*CommandReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SpeedUp not implemented")
+}
+func (UnimplementedShipNetBridgeServer) SpeedDown(context.Context, *CommandRequest) (*CommandReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SpeedDown not implemented")
+}
+func (UnimplementedShipNetBridgeServer) TurnLeft(context.Context, *CommandRequest) (*CommandReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TurnLeft not implemented")
+}
+func (UnimplementedShipNetBridgeServer) TurnRight(context.Context, *CommandRequest) (*CommandReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TurnRight not implemented")
+}
+func (UnimplementedShipNetBridgeServer) SetSpeed(context.Context, *SetValueRequest) (*CommandReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetSpeed not implemented")
+}
+func (UnimplementedShipNetBridgeServer) SetSteering(context.Context, *SetValueRequest) (*CommandReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetSteering not implemented")
+}
+func (UnimplementedShipNetBridgeServer) SetWaypoints(context.Context, *WaypointsRequest) (*CommandReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetWaypoints not implemented")
+}
+func (UnimplementedShipNetBridgeServer) AddWaypoint(context.Context, *WaypointRequest) (*CommandReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddWaypoint not implemented")
+}
+func (UnimplementedShipNetBridgeServer) ClearWaypoints(context.Context, *CommandRequest) (*CommandReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ClearWaypoints not implemented")
+}
+func (UnimplementedShipNetBridgeServer) SetHomeWaypoint(context.Context, *WaypointRequest) (*CommandReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetHomeWaypoint not implemented")
+}
+func (UnimplementedShipNetBridgeServer) NavStart(context.Context, *CommandRequest) (*CommandReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method NavStart not implemented")
+}
+func (UnimplementedShipNetBridgeServer) NavStop(context.Context, *CommandRequest) (*CommandReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method NavStop not implemented")
+}
+func (UnimplementedShipNetBridgeServer) StartCalibration(context.Context, *CommandRequest) (*CommandReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StartCalibration not implemented")
+}
+func (UnimplementedShipNetBridgeServer) StopCalibration(context.Context, *CommandRequest) (*CommandReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StopCalibration not implemented")
+}
+func (UnimplementedShipNetBridgeServer) ExportTrack(context.Context, *ExportTrackRequest) (*ExportTrackReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExportTrack not implemented")
+}
+func (UnimplementedShipNetBridgeServer) EmergencyStop(context.Context, *CommandRequest) (*CommandReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EmergencyStop not implemented")
+}
+func (UnimplementedShipNetBridgeServer) Query(context.Context, *CommandRequest) (*QueryReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Query not implemented")
+}
+func (UnimplementedShipNetBridgeServer) GetState(context.Context, *CommandRequest) (*VesselState, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetState not implemented")
+}
+func (UnimplementedShipNetBridgeServer) StreamTelemetry(*TelemetryStreamRequest, grpc.ServerStreamingServer[TelemetryUpdate]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamTelemetry not implemented")
+}
+func (UnimplementedShipNetBridgeServer) StreamEvents(*EventStreamRequest, grpc.ServerStreamingServer[Event]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamEvents not implemented")
+}
+func (UnimplementedShipNetBridgeServer) mustEmbedUnimplementedShipNetBridgeServer() {}
+func (UnimplementedShipNetBridgeServer) testEmbeddedByValue()                       {}
+
+// UnsafeShipNetBridgeServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ShipNetBridgeServer will
+// result in compilation errors.
+type UnsafeShipNetBridgeServer interface {
+	mustEmbedUnimplementedShipNetBridgeServer()
+}
+
+func RegisterShipNetBridgeServer(s grpc.ServiceRegistrar, srv ShipNetBridgeServer) {
+	// If the following call pancis, it indicates UnimplementedShipNetBridgeServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&ShipNetBridge_ServiceDesc, srv)
+}
+
+func _ShipNetBridge_SpeedUp_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommandRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShipNetBridgeServer).SpeedUp(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ShipNetBridge_SpeedUp_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShipNetBridgeServer).SpeedUp(ctx, req.(*CommandRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShipNetBridge_SpeedDown_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommandRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShipNetBridgeServer).SpeedDown(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ShipNetBridge_SpeedDown_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShipNetBridgeServer).SpeedDown(ctx, req.(*CommandRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShipNetBridge_TurnLeft_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommandRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShipNetBridgeServer).TurnLeft(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ShipNetBridge_TurnLeft_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShipNetBridgeServer).TurnLeft(ctx, req.(*CommandRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShipNetBridge_TurnRight_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommandRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShipNetBridgeServer).TurnRight(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ShipNetBridge_TurnRight_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShipNetBridgeServer).TurnRight(ctx, req.(*CommandRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShipNetBridge_SetSpeed_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetValueRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShipNetBridgeServer).SetSpeed(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ShipNetBridge_SetSpeed_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShipNetBridgeServer).SetSpeed(ctx, req.(*SetValueRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShipNetBridge_SetSteering_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetValueRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShipNetBridgeServer).SetSteering(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ShipNetBridge_SetSteering_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShipNetBridgeServer).SetSteering(ctx, req.(*SetValueRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShipNetBridge_SetWaypoints_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WaypointsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShipNetBridgeServer).SetWaypoints(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ShipNetBridge_SetWaypoints_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShipNetBridgeServer).SetWaypoints(ctx, req.(*WaypointsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShipNetBridge_AddWaypoint_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WaypointRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShipNetBridgeServer).AddWaypoint(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ShipNetBridge_AddWaypoint_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShipNetBridgeServer).AddWaypoint(ctx, req.(*WaypointRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShipNetBridge_ClearWaypoints_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommandRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShipNetBridgeServer).ClearWaypoints(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ShipNetBridge_ClearWaypoints_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShipNetBridgeServer).ClearWaypoints(ctx, req.(*CommandRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShipNetBridge_SetHomeWaypoint_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WaypointRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShipNetBridgeServer).SetHomeWaypoint(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ShipNetBridge_SetHomeWaypoint_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShipNetBridgeServer).SetHomeWaypoint(ctx, req.(*WaypointRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShipNetBridge_NavStart_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommandRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShipNetBridgeServer).NavStart(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ShipNetBridge_NavStart_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShipNetBridgeServer).NavStart(ctx, req.(*CommandRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShipNetBridge_NavStop_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommandRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShipNetBridgeServer).NavStop(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ShipNetBridge_NavStop_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShipNetBridgeServer).NavStop(ctx, req.(*CommandRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShipNetBridge_StartCalibration_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommandRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShipNetBridgeServer).StartCalibration(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ShipNetBridge_StartCalibration_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShipNetBridgeServer).StartCalibration(ctx, req.(*CommandRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShipNetBridge_StopCalibration_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommandRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShipNetBridgeServer).StopCalibration(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ShipNetBridge_StopCalibration_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShipNetBridgeServer).StopCalibration(ctx, req.(*CommandRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShipNetBridge_ExportTrack_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExportTrackRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShipNetBridgeServer).ExportTrack(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ShipNetBridge_ExportTrack_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShipNetBridgeServer).ExportTrack(ctx, req.(*ExportTrackRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShipNetBridge_EmergencyStop_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommandRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShipNetBridgeServer).EmergencyStop(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ShipNetBridge_EmergencyStop_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShipNetBridgeServer).EmergencyStop(ctx, req.(*CommandRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShipNetBridge_Query_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommandRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShipNetBridgeServer).Query(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ShipNetBridge_Query_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShipNetBridgeServer).Query(ctx, req.(*CommandRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShipNetBridge_GetState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommandRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShipNetBridgeServer).GetState(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ShipNetBridge_GetState_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShipNetBridgeServer).GetState(ctx, req.(*CommandRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShipNetBridge_StreamTelemetry_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(TelemetryStreamRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ShipNetBridgeServer).StreamTelemetry(m, &grpc.GenericServerStream[TelemetryStreamRequest, TelemetryUpdate]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ShipNetBridge_StreamTelemetryServer = grpc.ServerStreamingServer[TelemetryUpdate]
+
+func _ShipNetBridge_StreamEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(EventStreamRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ShipNetBridgeServer).StreamEvents(m, &grpc.GenericServerStream[EventStreamRequest, Event]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ShipNetBridge_StreamEventsServer = grpc.ServerStreamingServer[Event]
+
+// ShipNetBridge_ServiceDesc is the grpc.ServiceDesc for ShipNetBridge service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ShipNetBridge_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "shipnetbridge.v1.ShipNetBridge",
+	HandlerType: (*ShipNetBridgeServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SpeedUp",
+			Handler:    _ShipNetBridge_SpeedUp_Handler,
+		},
+		{
+			MethodName: "SpeedDown",
+			Handler:    _ShipNetBridge_SpeedDown_Handler,
+		},
+		{
+			MethodName: "TurnLeft",
+			Handler:    _ShipNetBridge_TurnLeft_Handler,
+		},
+		{
+			MethodName: "TurnRight",
+			Handler:    _ShipNetBridge_TurnRight_Handler,
+		},
+		{
+			MethodName: "SetSpeed",
+			Handler:    _ShipNetBridge_SetSpeed_Handler,
+		},
+		{
+			MethodName: "SetSteering",
+			Handler:    _ShipNetBridge_SetSteering_Handler,
+		},
+		{
+			MethodName: "SetWaypoints",
+			Handler:    _ShipNetBridge_SetWaypoints_Handler,
+		},
+		{
+			MethodName: "AddWaypoint",
+			Handler:    _ShipNetBridge_AddWaypoint_Handler,
+		},
+		{
+			MethodName: "ClearWaypoints",
+			Handler:    _ShipNetBridge_ClearWaypoints_Handler,
+		},
+		{
+			MethodName: "SetHomeWaypoint",
+			Handler:    _ShipNetBridge_SetHomeWaypoint_Handler,
+		},
+		{
+			MethodName: "NavStart",
+			Handler:    _ShipNetBridge_NavStart_Handler,
+		},
+		{
+			MethodName: "NavStop",
+			Handler:    _ShipNetBridge_NavStop_Handler,
+		},
+		{
+			MethodName: "StartCalibration",
+			Handler:    _ShipNetBridge_StartCalibration_Handler,
+		},
+		{
+			MethodName: "StopCalibration",
+			Handler:    _ShipNetBridge_StopCalibration_Handler,
+		},
+		{
+			MethodName: "ExportTrack",
+			Handler:    _ShipNetBridge_ExportTrack_Handler,
+		},
+		{
+			MethodName: "EmergencyStop",
+			Handler:    _ShipNetBridge_EmergencyStop_Handler,
+		},
+		{
+			MethodName: "Query",
+			Handler:    _ShipNetBridge_Query_Handler,
+		},
+		{
+			MethodName: "GetState",
+			Handler:    _ShipNetBridge_GetState_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamTelemetry",
+			Handler:       _ShipNetBridge_StreamTelemetry_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamEvents",
+			Handler:       _ShipNetBridge_StreamEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "bridge.proto",
+}
@@ -0,0 +1,629 @@
+package grpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moosethebrown/ship-net-bridge/adapters/grpc/pb"
+	"github.com/moosethebrown/ship-net-bridge/core"
+	"github.com/moosethebrown/ship-net-bridge/metrics"
+	"github.com/moosethebrown/ship-net-bridge/queue"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// DefaultResponseTimeout is how long commands wait for a response
+	// when the call has no deadline
+	DefaultResponseTimeout = 10 * time.Second
+	// DefaultTelemetryInterval is how often telemetry streams check
+	// for new telemetry
+	DefaultTelemetryInterval = time.Second
+	DefaultClientQueueSize   = 100
+)
+
+const (
+	// OperatorKey is the metadata key identifying the operator of calls
+	OperatorKey = "operator"
+
+	minTelemetryInterval = 100 * time.Millisecond
+	shutdownTimeout      = 3 * time.Second
+)
+
+// subscriber is an event stream, of vessel shipId or all vessels
+type subscriber struct {
+	shipId string
+	events chan *pb.Event
+}
+
+// Server serves the gRPC API of all vessels: commands are requests to
+// a vessel's core correlated to the call, which waits for the response
+// until its deadline or responseTimeout; the request is not executed
+// after that. Calls are authorized by a bearer token in the authorization
+// metadata, none are without a token; TLS is used when certFile and keyFile
+// are set.
+type Server struct {
+	pb.UnimplementedShipNetBridgeServer
+	address           string
+	token             string
+	certFile          string
+	keyFile           string
+	responseTimeout   time.Duration
+	telemetryInterval time.Duration
+	clientQueueSize   int
+	vessels           []*Vessel
+	logger            *zerolog.Logger
+	subscribersMutex  sync.Mutex
+	subscribers       map[*subscriber]bool
+	statusMutex       sync.Mutex
+	// stopping is closed to end streams when Run returns
+	stopping chan struct{}
+	streams  int
+	calls    int
+	errors   int
+}
+
+type Status struct {
+	Address string `json:"address"`
+	Streams int    `json:"streams"`
+	Calls   int    `json:"calls"`
+	Errors  int    `json:"errors"`
+}
+
+func NewServer(address string, token string, certFile string, keyFile string,
+	responseTimeout time.Duration, telemetryInterval time.Duration,
+	clientQueueSize int, logger *zerolog.Logger) *Server {
+	if responseTimeout <= 0 {
+		responseTimeout = DefaultResponseTimeout
+	}
+	if telemetryInterval <= 0 {
+		telemetryInterval = DefaultTelemetryInterval
+	}
+	if clientQueueSize <= 0 {
+		clientQueueSize = DefaultClientQueueSize
+	}
+
+	return &Server{
+		address:           address,
+		token:             token,
+		certFile:          certFile,
+		keyFile:           keyFile,
+		responseTimeout:   responseTimeout,
+		telemetryInterval: telemetryInterval,
+		clientQueueSize:   clientQueueSize,
+		vessels:           make([]*Vessel, 0),
+		logger:            logger,
+		subscribers:       make(map[*subscriber]bool),
+	}
+}
+
+func (s *Server) Name() string {
+	return "grpc"
+}
+
+// Run serves until ctx is cancelled, returns an error when the address
+// can't be listened on or the TLS certificate can't be loaded
+func (s *Server) Run(ctx context.Context) error {
+	opts := []grpc.ServerOption{
+		grpc.UnaryInterceptor(s.unaryInterceptor),
+		grpc.StreamInterceptor(s.streamInterceptor),
+	}
+	if s.certFile != "" {
+		creds, err := credentials.NewServerTLSFromFile(s.certFile, s.keyFile)
+		if err != nil {
+			s.logger.Error().Err(err).Msg("Failed to load TLS certificate")
+			metrics.Errors.WithLabelValues("grpc", "tls").Inc()
+			return err
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+	server := grpc.NewServer(opts...)
+	pb.RegisterShipNetBridgeServer(server, s)
+
+	listener, err := net.Listen("tcp", s.address)
+	if err != nil {
+		s.logger.Error().Err(err).Msgf("Failed to listen on %s", s.address)
+		metrics.Errors.WithLabelValues("grpc", "listen").Inc()
+		return err
+	}
+
+	stopping := make(chan struct{})
+	s.statusMutex.Lock()
+	s.stopping = stopping
+	s.statusMutex.Unlock()
+
+	s.logger.Info().Msgf("listening on %s", s.address)
+	defer s.logger.Info().Msg("stopping")
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- server.Serve(listener)
+	}()
+
+	select {
+	case <-ctx.Done():
+		close(stopping)
+		s.shutdown(server)
+		<-errChan
+		return nil
+	case err := <-errChan:
+		close(stopping)
+		s.logger.Error().Err(err).Msg("grpc server failed")
+		return err
+	}
+}
+
+// shutdown waits for calls in progress, streams end as stopping is closed
+func (s *Server) shutdown(server *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(shutdownTimeout):
+		s.logger.Error().Msg("calls still in progress, stopping anyway")
+		server.Stop()
+		<-done
+	}
+}
+
+// Status can be called from any goroutine
+func (s *Server) Status() any {
+	s.statusMutex.Lock()
+	defer s.statusMutex.Unlock()
+
+	return &Status{
+		Address: s.address,
+		Streams: s.streams,
+		Calls:   s.calls,
+		Errors:  s.errors,
+	}
+}
+
+func (s *Server) QueueDepths() map[string]int {
+	s.subscribersMutex.Lock()
+	defer s.subscribersMutex.Unlock()
+
+	depth := 0
+	for sub := range s.subscribers {
+		depth += len(sub.events)
+	}
+
+	return map[string]int{
+		"events": depth,
+	}
+}
+
+func (s *Server) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (any, error) {
+	err := s.authorize(ctx)
+	if err == nil {
+		var resp any
+		resp, err = handler(ctx, req)
+		if err == nil {
+			s.countCall(false)
+			return resp, nil
+		}
+	}
+
+	s.countCall(true)
+	s.logger.Debug().Err(err).Msgf("%s failed", info.FullMethod)
+	return nil, err
+}
+
+func (s *Server) streamInterceptor(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	err := s.authorize(stream.Context())
+	if err != nil {
+		s.countCall(true)
+		return err
+	}
+
+	s.statusMutex.Lock()
+	s.streams++
+	s.statusMutex.Unlock()
+	defer func() {
+		s.statusMutex.Lock()
+		s.streams--
+		s.statusMutex.Unlock()
+	}()
+
+	err = handler(srv, stream)
+	s.countCall(err != nil)
+	return err
+}
+
+func (s *Server) countCall(failed bool) {
+	s.statusMutex.Lock()
+	defer s.statusMutex.Unlock()
+
+	s.calls++
+	if failed {
+		s.errors++
+	}
+}
+
+// authorize fails closed, without a token nobody is authorized
+func (s *Server) authorize(ctx context.Context) error {
+	if s.token == "" {
+		return status.Error(codes.Unauthenticated, "unauthorized")
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, auth := range md.Get("authorization") {
+		token, ok := strings.CutPrefix(auth, "Bearer ")
+		if ok && (subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1) {
+			return nil
+		}
+	}
+
+	s.logger.Error().Msgf("unauthorized call from %s", peerAddress(ctx))
+	metrics.Errors.WithLabelValues("grpc", "unauthorized").Inc()
+	return status.Error(codes.Unauthenticated, "unauthorized")
+}
+
+// operator is given by the operator metadata, the peer address without it
+func operator(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, operator := range md.Get(OperatorKey) {
+		if operator != "" {
+			return operator
+		}
+	}
+	return "grpc " + peerAddress(ctx)
+}
+
+func peerAddress(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok {
+		return p.Addr.String()
+	}
+	return "unknown"
+}
+
+// vessel returns the vessel shipId, an empty shipId selects
+// the only vessel
+func (s *Server) vessel(shipId string) (*Vessel, error) {
+	if shipId == "" {
+		if len(s.vessels) == 1 {
+			return s.vessels[0], nil
+		}
+		return nil, status.Error(codes.InvalidArgument, "ship_id is required, the bridge serves several vessels")
+	}
+
+	for _, v := range s.vessels {
+		if v.shipId == shipId {
+			return v, nil
+		}
+	}
+	return nil, status.Errorf(codes.NotFound, "unknown vessel %s", shipId)
+}
+
+// selectVessels returns vessel shipId, all vessels if shipId is empty
+func (s *Server) selectVessels(shipId string) ([]*Vessel, error) {
+	if shipId == "" {
+		return s.vessels, nil
+	}
+
+	v, err := s.vessel(shipId)
+	if err != nil {
+		return nil, err
+	}
+	return []*Vessel{v}, nil
+}
+
+// call is the transport of a single request, it keeps the first response
+type call struct {
+	responses chan []byte
+}
+
+func (c *call) Name() string {
+	return "grpc"
+}
+
+func (c *call) SendResponse(resp []byte) {
+	select {
+	case c.responses <- resp:
+	default:
+	}
+}
+
+func (c *call) Announce() {
+}
+
+// request passes rq to the core of vessel shipId and waits for the response
+// unless wait is false; error responses fail the call
+func (s *Server) request(ctx context.Context, shipId string, rq *core.Request, wait bool) ([]byte, error) {
+	v, err := s.vessel(shipId)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := json.Marshal(rq)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("failed to marshal request")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(s.responseTimeout)
+	}
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+
+	c := &call{
+		responses: make(chan []byte, 1),
+	}
+	v.core.HandleRequestWithMeta(c, msg, &core.RequestMeta{
+		Operator:   operator(ctx),
+		Correlated: true,
+		Deadline:   deadline,
+	})
+	if !wait {
+		return nil, nil
+	}
+
+	select {
+	case resp := <-c.responses:
+		if r := decodeResponse(resp); (r != nil) && (r.Type == core.ResponseTypeError) {
+			return nil, status.Error(codes.FailedPrecondition, r.Error)
+		}
+		return resp, nil
+	case <-ctx.Done():
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+}
+
+// command sends command cmd, ship-nav doesn't respond to nav_stop
+// on behalf of the request
+func (s *Server) command(ctx context.Context, shipId string, cmd string, data string) (*pb.CommandReply, error) {
+	resp, err := s.request(ctx, shipId, &core.Request{
+		Type: core.RequestTypeCmd,
+		Cmd:  cmd,
+		Data: data,
+	}, cmd != core.CmdNavStop)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.CommandReply{Payload: string(resp)}, nil
+}
+
+func (s *Server) SpeedUp(ctx context.Context, rq *pb.CommandRequest) (*pb.CommandReply, error) {
+	return s.command(ctx, rq.GetShipId(), core.CmdSpeedUp, "")
+}
+
+func (s *Server) SpeedDown(ctx context.Context, rq *pb.CommandRequest) (*pb.CommandReply, error) {
+	return s.command(ctx, rq.GetShipId(), core.CmdSpeedDown, "")
+}
+
+func (s *Server) TurnLeft(ctx context.Context, rq *pb.CommandRequest) (*pb.CommandReply, error) {
+	return s.command(ctx, rq.GetShipId(), core.CmdTurnLeft, "")
+}
+
+func (s *Server) TurnRight(ctx context.Context, rq *pb.CommandRequest) (*pb.CommandReply, error) {
+	return s.command(ctx, rq.GetShipId(), core.CmdTurnRight, "")
+}
+
+func (s *Server) SetSpeed(ctx context.Context, rq *pb.SetValueRequest) (*pb.CommandReply, error) {
+	return s.command(ctx, rq.GetShipId(), core.CmdSetSpeed, strconv.Itoa(int(rq.GetValue())))
+}
+
+func (s *Server) SetSteering(ctx context.Context, rq *pb.SetValueRequest) (*pb.CommandReply, error) {
+	return s.command(ctx, rq.GetShipId(), core.CmdSetSteering, strconv.Itoa(int(rq.GetValue())))
+}
+
+// SetWaypoints rejects an empty list, which the core would drop
+// without a response
+func (s *Server) SetWaypoints(ctx context.Context, rq *pb.WaypointsRequest) (*pb.CommandReply, error) {
+	if len(rq.GetWaypoints()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "no waypoints provided")
+	}
+	return s.command(ctx, rq.GetShipId(), core.CmdSetWaypoints, formatWaypoints(rq.GetWaypoints()))
+}
+
+func (s *Server) AddWaypoint(ctx context.Context, rq *pb.WaypointRequest) (*pb.CommandReply, error) {
+	if rq.GetWaypoint() == nil {
+		return nil, status.Error(codes.InvalidArgument, "no waypoint provided")
+	}
+	return s.command(ctx, rq.GetShipId(), core.CmdAddWaypoint,
+		formatWaypoints([]*pb.Waypoint{rq.GetWaypoint()}))
+}
+
+func (s *Server) ClearWaypoints(ctx context.Context, rq *pb.CommandRequest) (*pb.CommandReply, error) {
+	return s.command(ctx, rq.GetShipId(), core.CmdClearWaypoints, "")
+}
+
+func (s *Server) SetHomeWaypoint(ctx context.Context, rq *pb.WaypointRequest) (*pb.CommandReply, error) {
+	if rq.GetWaypoint() == nil {
+		return nil, status.Error(codes.InvalidArgument, "no waypoint provided")
+	}
+	return s.command(ctx, rq.GetShipId(), core.CmdSetHomeWaypoint,
+		formatWaypoints([]*pb.Waypoint{rq.GetWaypoint()}))
+}
+
+func (s *Server) NavStart(ctx context.Context, rq *pb.CommandRequest) (*pb.CommandReply, error) {
+	return s.command(ctx, rq.GetShipId(), core.CmdNavStart, "")
+}
+
+func (s *Server) NavStop(ctx context.Context, rq *pb.CommandRequest) (*pb.CommandReply, error) {
+	return s.command(ctx, rq.GetShipId(), core.CmdNavStop, "")
+}
+
+func (s *Server) StartCalibration(ctx context.Context, rq *pb.CommandRequest) (*pb.CommandReply, error) {
+	return s.command(ctx, rq.GetShipId(), core.CmdStartCalibration, "")
+}
+
+func (s *Server) StopCalibration(ctx context.Context, rq *pb.CommandRequest) (*pb.CommandReply, error) {
+	return s.command(ctx, rq.GetShipId(), core.CmdStopCalibration, "")
+}
+
+func (s *Server) EmergencyStop(ctx context.Context, rq *pb.CommandRequest) (*pb.CommandReply, error) {
+	return s.command(ctx, rq.GetShipId(), core.CmdEmergencyStop, "")
+}
+
+func (s *Server) ExportTrack(ctx context.Context, rq *pb.ExportTrackRequest) (*pb.ExportTrackReply, error) {
+	data := fmt.Sprintf("%s;%d;%s", rq.GetFormat(), rq.GetTrackId(), rq.GetFileName())
+	resp, err := s.command(ctx, rq.GetShipId(), core.CmdExportTrack, data)
+	if err != nil {
+		return nil, err
+	}
+
+	track := &core.Response{}
+	if err := json.Unmarshal([]byte(resp.Payload), track); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if rq.GetFileName() != "" {
+		return &pb.ExportTrackReply{Path: track.Data}, nil
+	}
+	return &pb.ExportTrackReply{Data: track.Data}, nil
+}
+
+// Query decodes ship-nav's response the way the ship-nav adapter does
+func (s *Server) Query(ctx context.Context, rq *pb.CommandRequest) (*pb.QueryReply, error) {
+	resp, err := s.request(ctx, rq.GetShipId(), &core.Request{
+		Type: core.RequestTypeQuery,
+	}, true)
+	if err != nil {
+		return nil, err
+	}
+
+	reply := &pb.QueryReply{Payload: string(resp)}
+	t := &core.Telemetry{}
+	if (json.Unmarshal(resp, t) == nil) && ((t.Latitude != 0) || (t.Longitude != 0)) {
+		t.Timestamp = time.Now()
+		reply.Telemetry = toTelemetry(t)
+	}
+	return reply, nil
+}
+
+func (s *Server) GetState(ctx context.Context, rq *pb.CommandRequest) (*pb.VesselState, error) {
+	v, err := s.vessel(rq.GetShipId())
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.VesselState{
+		ShipId:    v.shipId,
+		Telemetry: toTelemetry(v.core.LastTelemetry()),
+		Fix:       toFix(v.core.LastFix()),
+		Waypoints: toWaypoints(v.core.Waypoints()),
+		AutoNav:   v.core.AutoNav(),
+	}, nil
+}
+
+func (s *Server) stoppingChan() <-chan struct{} {
+	s.statusMutex.Lock()
+	defer s.statusMutex.Unlock()
+	return s.stopping
+}
+
+func (s *Server) StreamTelemetry(rq *pb.TelemetryStreamRequest,
+	stream pb.ShipNetBridge_StreamTelemetryServer) error {
+	vessels, err := s.selectVessels(rq.GetShipId())
+	if err != nil {
+		return err
+	}
+	interval := s.telemetryInterval
+	if rq.GetIntervalMs() > 0 {
+		interval = max(time.Duration(rq.GetIntervalMs())*time.Millisecond, minTelemetryInterval)
+	}
+	stopping := s.stoppingChan()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	sent := make(map[*Vessel]time.Time)
+	for {
+		for _, v := range vessels {
+			t := v.core.LastTelemetry()
+			if (t == nil) || !t.Timestamp.After(sent[v]) {
+				continue
+			}
+			sent[v] = t.Timestamp
+			err := stream.Send(&pb.TelemetryUpdate{
+				ShipId:    v.shipId,
+				Telemetry: toTelemetry(t),
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stream.Context().Done():
+			return nil
+		case <-stopping:
+			return status.Error(codes.Unavailable, "server is stopping")
+		}
+	}
+}
+
+func (s *Server) StreamEvents(rq *pb.EventStreamRequest, stream pb.ShipNetBridge_StreamEventsServer) error {
+	if rq.GetShipId() != "" {
+		if _, err := s.vessel(rq.GetShipId()); err != nil {
+			return err
+		}
+	}
+	stopping := s.stoppingChan()
+
+	sub := &subscriber{
+		shipId: rq.GetShipId(),
+		events: make(chan *pb.Event, s.clientQueueSize),
+	}
+	s.subscribersMutex.Lock()
+	s.subscribers[sub] = true
+	s.subscribersMutex.Unlock()
+	defer func() {
+		s.subscribersMutex.Lock()
+		delete(s.subscribers, sub)
+		s.subscribersMutex.Unlock()
+	}()
+
+	for {
+		select {
+		case event := <-sub.events:
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		case <-stopping:
+			return status.Error(codes.Unavailable, "server is stopping")
+		}
+	}
+}
+
+// publish never blocks, events are dropped for streams that fall behind
+func (s *Server) publish(event *pb.Event) {
+	s.subscribersMutex.Lock()
+	defer s.subscribersMutex.Unlock()
+
+	for sub := range s.subscribers {
+		if (sub.shipId != "") && (sub.shipId != event.ShipId) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			s.logger.Error().Msg("event queue of a stream is full, dropping event")
+			metrics.Overflows.WithLabelValues("grpc", "events",
+				queue.DropNewest.String()).Inc()
+		}
+	}
+}
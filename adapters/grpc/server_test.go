@@ -0,0 +1,218 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/moosethebrown/ship-net-bridge/adapters/grpc/pb"
+	"github.com/moosethebrown/ship-net-bridge/core"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type request struct {
+	rq   *core.Request
+	meta *core.RequestMeta
+}
+
+// mockCore answers like the daemons would: nav_start fails, queries
+// return telemetry, other commands succeed
+type mockCore struct {
+	mutex     sync.Mutex
+	requests  []*request
+	telemetry *core.Telemetry
+}
+
+func (m *mockCore) HandleRequestWithMeta(transport core.Transport, msg []byte, meta *core.RequestMeta) {
+	rq := &core.Request{}
+	json.Unmarshal(msg, rq)
+	m.mutex.Lock()
+	m.requests = append(m.requests, &request{rq: rq, meta: meta})
+	m.mutex.Unlock()
+
+	go func() {
+		switch {
+		case rq.Type == core.RequestTypeQuery:
+			transport.SendResponse([]byte(`{"latitude":56.348284,"longitude":43.95941,"heading":90,"speed":2}`))
+		case rq.Cmd == core.CmdNavStart:
+			transport.SendResponse([]byte(`{"type":"error","cmd":"nav_start","error":"no waypoints"}`))
+		case rq.Cmd == core.CmdNavStop:
+		default:
+			transport.SendResponse([]byte(`{"result":"ok"}`))
+		}
+	}()
+}
+
+func (m *mockCore) LastTelemetry() *core.Telemetry {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.telemetry
+}
+
+func (m *mockCore) LastFix() *core.Fix {
+	return nil
+}
+
+func (m *mockCore) Waypoints() []core.Waypoint {
+	return []core.Waypoint{{Latitude: 56.36, Longitude: 43.9}}
+}
+
+func (m *mockCore) AutoNav() bool {
+	return true
+}
+
+func (m *mockCore) lastRequest() *request {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.requests[len(m.requests)-1]
+}
+
+func expectCode(t *testing.T, err error, code codes.Code) {
+	t.Helper()
+	if status.Code(err) != code {
+		t.Errorf("Expected %s, got %v", code, err)
+	}
+}
+
+func TestServer(t *testing.T) {
+	logger := zerolog.New(os.Stdout).Level(zerolog.DebugLevel)
+	// find a free port
+	probe, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	address := probe.Addr().String()
+	probe.Close()
+
+	theCore := &mockCore{
+		telemetry: &core.Telemetry{Latitude: 56.348284, Longitude: 43.95941, Heading: 90, Speed: 2,
+			Timestamp: time.Now()},
+	}
+	server := NewServer(address, "secret", "", "", time.Second, 0, 0, &logger)
+	vessel := server.AddVessel("ship", theCore)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		done <- server.Run(ctx)
+	}()
+
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to create client: %s", err)
+	}
+	defer conn.Close()
+	client := pb.NewShipNetBridgeClient(conn)
+
+	// the server may still be starting
+	var state *pb.VesselState
+	for i := 0; i < 50; i++ {
+		_, err = client.GetState(context.Background(), &pb.CommandRequest{})
+		if status.Code(err) != codes.Unavailable {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	expectCode(t, err, codes.Unauthenticated)
+
+	callCtx := metadata.AppendToOutgoingContext(context.Background(),
+		"authorization", "Bearer secret", OperatorKey, "fleet")
+	state, err = client.GetState(callCtx, &pb.CommandRequest{})
+	if err != nil {
+		t.Fatalf("Failed to get state: %s", err)
+	}
+	if (state.ShipId != "ship") || !state.AutoNav || (len(state.Waypoints) != 1) ||
+		(state.Telemetry.GetHeading() != 90) {
+		t.Errorf("Unexpected state %+v", state)
+	}
+
+	reply, err := client.SetWaypoints(callCtx, &pb.WaypointsRequest{Waypoints: []*pb.Waypoint{
+		{Latitude: 56.348284, Longitude: 43.95941}, {Latitude: 56.36, Longitude: 43.9}}})
+	if (err != nil) || (reply.Payload != `{"result":"ok"}`) {
+		t.Fatalf("Unexpected reply %v: %v", reply, err)
+	}
+	last := theCore.lastRequest()
+	if (last.rq.Cmd != core.CmdSetWaypoints) || (last.rq.Data != "56.348284,43.95941;56.36,43.9") ||
+		(last.meta.Operator != "fleet") || !last.meta.Correlated || last.meta.Deadline.IsZero() {
+		t.Errorf("Unexpected request %+v %+v", last.rq, last.meta)
+	}
+
+	_, err = client.SetWaypoints(callCtx, &pb.WaypointsRequest{})
+	expectCode(t, err, codes.InvalidArgument)
+	_, err = client.SetSpeed(callCtx, &pb.SetValueRequest{ShipId: "other", Value: 5})
+	expectCode(t, err, codes.NotFound)
+	_, err = client.NavStart(callCtx, &pb.CommandRequest{})
+	expectCode(t, err, codes.FailedPrecondition)
+	if _, err = client.NavStop(callCtx, &pb.CommandRequest{}); err != nil {
+		t.Errorf("Expected nav_stop not to wait for a response, got %s", err)
+	}
+
+	query, err := client.Query(callCtx, &pb.CommandRequest{ShipId: "ship"})
+	if (err != nil) || (query.Telemetry.GetLatitude() != 56.348284) {
+		t.Errorf("Unexpected query reply %v: %v", query, err)
+	}
+
+	telemetry, err := client.StreamTelemetry(callCtx, &pb.TelemetryStreamRequest{})
+	if err != nil {
+		t.Fatalf("Failed to stream telemetry: %s", err)
+	}
+	if update, err := telemetry.Recv(); (err != nil) || (update.ShipId != "ship") ||
+		(update.Telemetry.GetSpeed() != 2) {
+		t.Errorf("Unexpected telemetry %v: %v", update, err)
+	}
+
+	events, err := client.StreamEvents(callCtx, &pb.EventStreamRequest{ShipId: "ship"})
+	if err != nil {
+		t.Fatalf("Failed to stream events: %s", err)
+	}
+	// the stream subscribes once the call reaches the server
+	for i := 0; i < 100; i++ {
+		server.subscribersMutex.Lock()
+		n := len(server.subscribers)
+		server.subscribersMutex.Unlock()
+		if n > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	vessel.Announce()
+	vessel.SendResponse([]byte(`{"status":"calibrated"}`))
+	if event, err := events.Recv(); (err != nil) || (event.Type != core.ResponseTypeAnnounce) ||
+		(event.Data != "ship") {
+		t.Errorf("Unexpected announce %v: %v", event, err)
+	}
+	if event, err := events.Recv(); (err != nil) || (event.Type != "") ||
+		(event.Payload != `{"status":"calibrated"}`) {
+		t.Errorf("Unexpected daemon message %v: %v", event, err)
+	}
+
+	// open streams don't hold up shutdown
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected server to stop cleanly, got %s", err)
+		}
+	case <-time.After(shutdownTimeout):
+		t.Errorf("Server didn't stop")
+	}
+}
+
+func TestAuthorizeWithoutToken(t *testing.T) {
+	logger := zerolog.New(os.Stdout).Level(zerolog.DebugLevel)
+	server := NewServer("127.0.0.1:0", "", "", "", time.Second, 0, 0, &logger)
+
+	// an empty bearer token doesn't match a missing one
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "))
+	expectCode(t, server.authorize(ctx), codes.Unauthenticated)
+	expectCode(t, server.authorize(context.Background()), codes.Unauthenticated)
+}
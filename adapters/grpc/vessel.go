@@ -0,0 +1,77 @@
+package grpc
+
+import (
+	"encoding/json"
+	"slices"
+
+	"github.com/moosethebrown/ship-net-bridge/core"
+)
+
+// responseTypes are the types of messages generated by the bridge itself,
+// other messages come from the daemons
+var responseTypes = []string{
+	core.ResponseTypeTrack,
+	core.ResponseTypeError,
+	core.ResponseTypeAnnounce,
+	core.ResponseTypeFix,
+	core.ResponseTypeAisTargets,
+	core.ResponseTypeCollisionWarning,
+}
+
+// Core is the part of core.Core the server uses
+type Core interface {
+	HandleRequestWithMeta(transport core.Transport, msg []byte, meta *core.RequestMeta)
+	LastTelemetry() *core.Telemetry
+	LastFix() *core.Fix
+	Waypoints() []core.Waypoint
+	AutoNav() bool
+}
+
+// Vessel is the transport of one ship's core, its uncorrelated responses
+// and announces are streamed as events
+type Vessel struct {
+	server *Server
+	shipId string
+	core   Core
+}
+
+// AddVessel registers a ship served by the server, must be called before Run
+func (s *Server) AddVessel(shipId string, theCore Core) *Vessel {
+	v := &Vessel{
+		server: s,
+		shipId: shipId,
+		core:   theCore,
+	}
+	s.vessels = append(s.vessels, v)
+	return v
+}
+
+func (v *Vessel) Name() string {
+	return "grpc"
+}
+
+func (v *Vessel) SendResponse(resp []byte) {
+	v.server.publish(toEvent(v.shipId, decodeResponse(resp), resp))
+}
+
+func (v *Vessel) Announce() {
+	msg, err := json.Marshal(&core.Response{
+		Type: core.ResponseTypeAnnounce,
+		Data: v.shipId,
+	})
+	if err != nil {
+		v.server.logger.Error().Err(err).Msg("failed to marshal announce message")
+		return
+	}
+
+	v.SendResponse(msg)
+}
+
+// decodeResponse returns nil for messages of the daemons
+func decodeResponse(msg []byte) *core.Response {
+	resp := &core.Response{}
+	if (json.Unmarshal(msg, resp) != nil) || !slices.Contains(responseTypes, resp.Type) {
+		return nil
+	}
+	return resp
+}
@@ -0,0 +1,127 @@
+package mavlink
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	stxV1 = 0xfe
+	stxV2 = 0xfd
+	// incompatFlagSigned marks frames followed by a signature
+	incompatFlagSigned = 0x01
+	signatureLength    = 13
+	headerLengthV1     = 6
+	headerLengthV2     = 10
+	checksumLength     = 2
+)
+
+var (
+	// ErrChecksum is returned for frames with a wrong checksum
+	ErrChecksum = errors.New("MAVLink frame checksum mismatch")
+	// ErrUnknownMessage is returned for messages the gateway doesn't know,
+	// their checksum can't be checked without CRC_EXTRA
+	ErrUnknownMessage = errors.New("unknown MAVLink message")
+)
+
+// Frame is a MAVLink packet, Payload is zero-padded to the full length
+// of the message on decoding and truncated on encoding as v2 requires
+type Frame struct {
+	Seq         uint8
+	SystemId    uint8
+	ComponentId uint8
+	MessageId   uint32
+	Payload     []byte
+}
+
+// crc16 is CRC-16/MCRF4XX, called X.25 by MAVLink
+func crc16(crc uint16, data []byte) uint16 {
+	for _, b := range data {
+		tmp := b ^ byte(crc)
+		tmp ^= tmp << 4
+		crc = (crc >> 8) ^ (uint16(tmp) << 8) ^ (uint16(tmp) << 3) ^ (uint16(tmp) >> 4)
+	}
+	return crc
+}
+
+// Encode builds a MAVLink v2 frame, unsigned
+func (f *Frame) Encode() ([]byte, error) {
+	info, ok := messages[f.MessageId]
+	if !ok {
+		return nil, ErrUnknownMessage
+	}
+
+	payload := f.Payload
+	for (len(payload) > 1) && (payload[len(payload)-1] == 0) {
+		payload = payload[:len(payload)-1]
+	}
+	if len(payload) > 255 {
+		return nil, fmt.Errorf("MAVLink payload of %d bytes is too long", len(payload))
+	}
+
+	data := make([]byte, 0, headerLengthV2+len(payload)+checksumLength)
+	data = append(data, stxV2, byte(len(payload)), 0, 0, f.Seq, f.SystemId, f.ComponentId,
+		byte(f.MessageId), byte(f.MessageId>>8), byte(f.MessageId>>16))
+	data = append(data, payload...)
+	crc := crc16(0xffff, data[1:])
+	crc = crc16(crc, []byte{info.crcExtra})
+	return binary.LittleEndian.AppendUint16(data, crc), nil
+}
+
+// Decode parses the frame at the start of data, MAVLink v1 or v2, and
+// returns the number of bytes it takes; bytes before the start marker
+// are skipped. Signatures of signed frames aren't checked.
+func Decode(data []byte) (*Frame, int, error) {
+	start := 0
+	for (start < len(data)) && (data[start] != stxV1) && (data[start] != stxV2) {
+		start++
+	}
+	if start == len(data) {
+		return nil, len(data), errors.New("no MAVLink frame")
+	}
+	data = data[start:]
+
+	headerLength := headerLengthV1
+	if data[0] == stxV2 {
+		headerLength = headerLengthV2
+	}
+	if len(data) < headerLength {
+		return nil, start + len(data), errors.New("truncated MAVLink frame")
+	}
+
+	f := &Frame{}
+	payloadLength := int(data[1])
+	length := headerLength + payloadLength + checksumLength
+	if data[0] == stxV2 {
+		if data[2]&^incompatFlagSigned != 0 {
+			return nil, start + 1, fmt.Errorf("unsupported MAVLink incompatibility flags %#x", data[2])
+		}
+		if data[2]&incompatFlagSigned != 0 {
+			length += signatureLength
+		}
+		f.Seq, f.SystemId, f.ComponentId = data[4], data[5], data[6]
+		f.MessageId = uint32(data[7]) | uint32(data[8])<<8 | uint32(data[9])<<16
+	} else {
+		f.Seq, f.SystemId, f.ComponentId = data[2], data[3], data[4]
+		f.MessageId = uint32(data[5])
+	}
+	if len(data) < length {
+		return nil, start + len(data), errors.New("truncated MAVLink frame")
+	}
+
+	info, ok := messages[f.MessageId]
+	if !ok {
+		return nil, start + length, fmt.Errorf("%w %d", ErrUnknownMessage, f.MessageId)
+	}
+	end := headerLength + payloadLength
+	crc := crc16(0xffff, data[1:end])
+	crc = crc16(crc, []byte{info.crcExtra})
+	if binary.LittleEndian.Uint16(data[end:]) != crc {
+		return nil, start + length, ErrChecksum
+	}
+
+	f.Payload = make([]byte, max(payloadLength, info.length))
+	copy(f.Payload, data[headerLength:end])
+	return f, start + length, nil
+}
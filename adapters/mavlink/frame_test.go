@@ -0,0 +1,77 @@
+package mavlink
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestCrc(t *testing.T) {
+	// check value of CRC-16/MCRF4XX
+	if crc := crc16(0xffff, []byte("123456789")); crc != 0x6f91 {
+		t.Errorf("Unexpected checksum %#x", crc)
+	}
+}
+
+func TestFrame(t *testing.T) {
+	f := &Frame{
+		Seq:         7,
+		SystemId:    255,
+		ComponentId: 190,
+		MessageId:   MsgMissionCount,
+		Payload:     (&MissionSeq{Seq: 3, TargetSystem: 1, TargetComponent: 1}).marshal(),
+	}
+	data, err := f.Encode()
+	if err != nil {
+		t.Fatalf("Failed to encode frame: %s", err)
+	}
+	// the zero mission type extension is truncated
+	if (data[0] != stxV2) || (data[1] != 4) || (len(data) != headerLengthV2+4+checksumLength) {
+		t.Fatalf("Unexpected frame % x", data)
+	}
+
+	// garbage before the frame is skipped, another frame follows
+	stream := append([]byte{0x00, 0x42}, data...)
+	stream = append(stream, data...)
+	decoded, n, err := Decode(stream)
+	if err != nil {
+		t.Fatalf("Failed to decode frame: %s", err)
+	}
+	if (n != 2+len(data)) || (decoded.Seq != 7) || (decoded.SystemId != 255) ||
+		(decoded.MessageId != MsgMissionCount) || (len(decoded.Payload) != 5) {
+		t.Errorf("Unexpected frame %+v, %d bytes", decoded, n)
+	}
+	if m := parseMissionSeq(decoded.Payload); (m.Seq != 3) || (m.TargetSystem != 1) {
+		t.Errorf("Unexpected MISSION_COUNT %+v", m)
+	}
+
+	corrupted := bytes.Clone(data)
+	corrupted[headerLengthV2]++
+	if _, n, err := Decode(corrupted); (err != ErrChecksum) || (n != len(data)) {
+		t.Errorf("Expected checksum mismatch, got %v, %d bytes", err, n)
+	}
+
+	unknown := bytes.Clone(data)
+	unknown[7] = 0xff
+	if _, _, err := Decode(unknown); !errors.Is(err, ErrUnknownMessage) {
+		t.Errorf("Expected unknown message, got %v", err)
+	}
+	if _, _, err := Decode(data[:len(data)-1]); err == nil {
+		t.Errorf("Expected truncated frame to be rejected")
+	}
+}
+
+func TestFrameV1(t *testing.T) {
+	payload := (&Heartbeat{Type: 6, Autopilot: 8, MavlinkVersion: 3}).marshal()
+	data := append([]byte{stxV1, byte(len(payload)), 1, 255, 0, MsgHeartbeat}, payload...)
+	crc := crc16(crc16(0xffff, data[1:]), []byte{messages[MsgHeartbeat].crcExtra})
+	data = append(data, byte(crc), byte(crc>>8))
+
+	f, n, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Failed to decode MAVLink v1 frame: %s", err)
+	}
+	if m := parseHeartbeat(f.Payload); (n != len(data)) || (f.SystemId != 255) || (m.Type != 6) {
+		t.Errorf("Unexpected heartbeat %+v %+v", f, m)
+	}
+}
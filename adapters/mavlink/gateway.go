@@ -0,0 +1,766 @@
+package mavlink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moosethebrown/ship-net-bridge/core"
+	"github.com/moosethebrown/ship-net-bridge/metrics"
+	"github.com/rs/zerolog"
+)
+
+const (
+	DefaultSystemId = 1
+	// DefaultInterval is how often the position is sent
+	DefaultInterval = 500 * time.Millisecond
+	// DefaultMaxAge is how long telemetry is sent after it was received
+	DefaultMaxAge = 10 * time.Second
+	// DefaultControlScale is the ship-control value full stick deflection
+	// maps to
+	DefaultControlScale = 100
+)
+
+const (
+	// componentId is MAV_COMP_ID_AUTOPILOT1, the vessel itself
+	componentId       = 1
+	heartbeatInterval = time.Second
+	// peerTimeout is how long peers get messages after they were last heard
+	peerTimeout = 10 * time.Second
+	// missionTimeout and missionRetries limit waiting for mission items
+	missionTimeout = time.Second
+	missionRetries = 5
+	maxDatagram    = 65536
+
+	// manualControlTimeout is how long a speed set by MANUAL_CONTROL is kept
+	// without further input, e.g. when the link to the station is lost
+	manualControlTimeout = time.Second
+)
+
+// Core is what the gateway needs of core.Core
+type Core interface {
+	HandleRequestWithMeta(transport core.Transport, msg []byte, meta *core.RequestMeta)
+	LastTelemetry() *core.Telemetry
+	Waypoints() []core.Waypoint
+	AutoNav() bool
+}
+
+// Gateway lets ground control stations, e.g. QGroundControl or Mission
+// Planner, operate the vessel over MAVLink v2 on UDP. It announces the vessel
+// as a surface boat with HEARTBEAT, reports the ship-nav position with
+// GLOBAL_POSITION_INT every interval and turns:
+//   - mission upload (MISSION_COUNT, MISSION_ITEM_INT) into set_waypoints,
+//     download lists the core's waypoints, MISSION_CLEAR_ALL clears them
+//   - MAV_CMD_MISSION_START and MAV_CMD_DO_PAUSE_CONTINUE into nav_start and
+//     nav_stop, MAV_CMD_DO_SET_HOME into set_home_waypoint and
+//     MAV_CMD_DO_FLIGHTTERMINATION into emergency_stop
+//   - MANUAL_CONTROL forward (x) and yaw (r) axes into set_speed and
+//     set_steering, scaled to maxSpeed and maxSteering at full deflection;
+//     speed and steering are set to 0 when input stops for manualControlTimeout
+//
+// Errors and collision warnings are sent as STATUSTEXT. Messages go to
+// ground stations heard from within peerTimeout and to gcsAddress, if set,
+// so that stations listening on the standard port find the vessel.
+type Gateway struct {
+	address     string
+	gcsAddress  string
+	systemId    uint8
+	interval    time.Duration
+	maxAge      time.Duration
+	maxSpeed    int
+	maxSteering int
+	allowed     []*net.IPNet
+	core        Core
+	logger      *zerolog.Logger
+	started     time.Time
+	// connMutex guards conn, peers, seq and counters
+	connMutex sync.Mutex
+	conn      *net.UDPConn
+	gcs       *net.UDPAddr
+	peers     map[string]*peer
+	seq       uint8
+	received  int
+	sent      int
+	errors    int
+	// only used by Run's goroutine
+	upload     *upload
+	download   []core.Waypoint
+	speed      int
+	steering   int
+	manualAt   time.Time
+	manualFrom *net.UDPAddr
+}
+
+type peer struct {
+	addr     *net.UDPAddr
+	lastSeen time.Time
+}
+
+// upload is a mission being received from a ground station
+type upload struct {
+	addr        *net.UDPAddr
+	systemId    uint8
+	componentId uint8
+	count       int
+	waypoints   []string
+	requested   time.Time
+	retries     int
+}
+
+type datagram struct {
+	data []byte
+	addr *net.UDPAddr
+}
+
+type Status struct {
+	Address  string   `json:"address"`
+	Peers    []string `json:"peers"`
+	Received int      `json:"received"`
+	Sent     int      `json:"sent"`
+	Errors   int      `json:"errors"`
+}
+
+// NewGateway creates a gateway listening on address, gcsAddress may be
+// empty; messages from outside allowed networks are dropped, none means
+// all are, MAVLink messages aren't authenticated
+func NewGateway(address string, gcsAddress string, systemId int,
+	interval time.Duration, maxAge time.Duration, maxSpeed int, maxSteering int,
+	allowed []*net.IPNet, theCore Core, logger *zerolog.Logger) *Gateway {
+	if systemId == 0 {
+		systemId = DefaultSystemId
+	}
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	if maxAge <= 0 {
+		maxAge = DefaultMaxAge
+	}
+	if maxSpeed == 0 {
+		maxSpeed = DefaultControlScale
+	}
+	if maxSteering == 0 {
+		maxSteering = DefaultControlScale
+	}
+
+	return &Gateway{
+		address:     address,
+		gcsAddress:  gcsAddress,
+		systemId:    uint8(systemId),
+		interval:    interval,
+		maxAge:      maxAge,
+		maxSpeed:    maxSpeed,
+		maxSteering: maxSteering,
+		allowed:     allowed,
+		core:        theCore,
+		logger:      logger,
+		started:     time.Now(),
+		peers:       make(map[string]*peer),
+	}
+}
+
+// Run returns an error when the address can't be listened on
+// or reading fails
+func (g *Gateway) Run(ctx context.Context) error {
+	var gcs *net.UDPAddr
+	if g.gcsAddress != "" {
+		var err error
+		gcs, err = net.ResolveUDPAddr("udp", g.gcsAddress)
+		if err != nil {
+			g.logger.Error().Err(err).Msgf("Failed to resolve %s", g.gcsAddress)
+			metrics.Errors.WithLabelValues("mavlink", "connect").Inc()
+			return err
+		}
+	}
+	addr, err := net.ResolveUDPAddr("udp", g.address)
+	var conn *net.UDPConn
+	if err == nil {
+		conn, err = net.ListenUDP("udp", addr)
+	}
+	if err != nil {
+		g.logger.Error().Err(err).Msgf("Failed to listen on %s", g.address)
+		metrics.Errors.WithLabelValues("mavlink", "listen").Inc()
+		return err
+	}
+	g.logger.Info().Msgf("listening on %s", g.address)
+
+	g.connMutex.Lock()
+	g.conn = conn
+	g.gcs = gcs
+	g.connMutex.Unlock()
+	defer func() {
+		g.connMutex.Lock()
+		g.conn = nil
+		g.connMutex.Unlock()
+		// closing the connection ends read
+		conn.Close()
+	}()
+
+	readCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	datagrams := make(chan *datagram)
+	readErr := make(chan error, 1)
+	go func() {
+		readErr <- g.read(readCtx, conn, datagrams)
+	}()
+
+	heartbeatTicker := time.NewTicker(heartbeatInterval)
+	defer heartbeatTicker.Stop()
+	positionTicker := time.NewTicker(g.interval)
+	defer positionTicker.Stop()
+	g.sendHeartbeat()
+
+	for {
+		select {
+		case d := <-datagrams:
+			g.handleDatagram(d, time.Now())
+		case now := <-heartbeatTicker.C:
+			g.sendHeartbeat()
+			g.checkUpload(now)
+			g.checkManualControl(now)
+		case now := <-positionTicker.C:
+			g.sendPosition(now)
+		case err := <-readErr:
+			if ctx.Err() != nil {
+				return nil
+			}
+			g.logger.Error().Err(err).Msgf("Failed to read %s", g.address)
+			metrics.Errors.WithLabelValues("mavlink", "read").Inc()
+			return err
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (g *Gateway) Name() string {
+	return "mavlink"
+}
+
+// SendResponse passes errors and collision warnings on as STATUSTEXT,
+// other responses have no MAVLink counterpart
+func (g *Gateway) SendResponse(resp []byte) {
+	var msg core.Response
+	if json.Unmarshal(resp, &msg) != nil {
+		return
+	}
+
+	switch {
+	case msg.Type == core.ResponseTypeError:
+		g.broadcast(MsgStatusText, (&StatusText{
+			Severity: severityError,
+			Text:     strings.TrimPrefix(msg.Cmd+": "+msg.Error, ": "),
+		}).marshal())
+	case (msg.Type == core.ResponseTypeCollisionWarning) && (msg.Target != nil):
+		text := fmt.Sprintf("Collision risk %d", msg.Target.Mmsi)
+		if msg.Target.Name != "" {
+			text = "Collision risk " + msg.Target.Name
+		}
+		if msg.Cmd == core.CmdNavStop {
+			text += ", nav stopped"
+		}
+		g.broadcast(MsgStatusText, (&StatusText{
+			Severity: severityWarning,
+			Text:     text,
+		}).marshal())
+	}
+}
+
+// Announce does nothing, the heartbeat announces the vessel
+func (g *Gateway) Announce() {
+}
+
+// Status can be called from any goroutine
+func (g *Gateway) Status() any {
+	g.connMutex.Lock()
+	defer g.connMutex.Unlock()
+
+	status := &Status{
+		Address:  g.address,
+		Peers:    make([]string, 0, len(g.peers)),
+		Received: g.received,
+		Sent:     g.sent,
+		Errors:   g.errors,
+	}
+	for key := range g.peers {
+		status.Peers = append(status.Peers, key)
+	}
+	return status
+}
+
+func (g *Gateway) Healthy() bool {
+	g.connMutex.Lock()
+	defer g.connMutex.Unlock()
+
+	return g.conn != nil
+}
+
+// QueueDepths reports no queues, messages are sent right away
+func (g *Gateway) QueueDepths() map[string]int {
+	return map[string]int{}
+}
+
+func (g *Gateway) read(ctx context.Context, conn *net.UDPConn, datagrams chan<- *datagram) error {
+	buf := make([]byte, maxDatagram)
+	for {
+		n, addr, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return err
+		}
+		d := &datagram{
+			data: append([]byte(nil), buf[:n]...),
+			addr: addr,
+		}
+		select {
+		case datagrams <- d:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (g *Gateway) allowedAddr(addr *net.UDPAddr) bool {
+	for _, ipNet := range g.allowed {
+		if ipNet.Contains(addr.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Gateway) handleDatagram(d *datagram, now time.Time) {
+	if !g.allowedAddr(d.addr) {
+		g.logger.Debug().Msgf("dropping datagram from %s", d.addr)
+		metrics.Errors.WithLabelValues("mavlink", "unauthorized").Inc()
+		return
+	}
+
+	data := d.data
+	for len(data) > 0 {
+		f, n, err := Decode(data)
+		data = data[n:]
+		if errors.Is(err, ErrUnknownMessage) {
+			// ground stations send plenty of messages the gateway doesn't need
+			continue
+		}
+
+		g.connMutex.Lock()
+		if err != nil {
+			g.errors++
+		} else {
+			g.received++
+			g.peers[d.addr.String()] = &peer{addr: d.addr, lastSeen: now}
+		}
+		g.connMutex.Unlock()
+
+		if err != nil {
+			g.logger.Debug().Err(err).Msgf("invalid MAVLink frame from %s", d.addr)
+			if errors.Is(err, ErrChecksum) {
+				metrics.Errors.WithLabelValues("mavlink", "checksum").Inc()
+			} else {
+				metrics.Errors.WithLabelValues("mavlink", "malformed").Inc()
+			}
+			continue
+		}
+		g.handleFrame(f, d.addr, now)
+	}
+}
+
+func (g *Gateway) handleFrame(f *Frame, from *net.UDPAddr, now time.Time) {
+	switch f.MessageId {
+	case MsgMissionRequestList:
+		m := parseMissionTarget(f.Payload)
+		if g.targeted(m.TargetSystem) {
+			g.handleRequestList(f, from, m)
+		}
+	case MsgMissionCount:
+		m := parseMissionSeq(f.Payload)
+		if g.targeted(m.TargetSystem) {
+			g.handleMissionCount(f, from, m, now)
+		}
+	case MsgMissionItemInt:
+		m := parseMissionItemInt(f.Payload)
+		if g.targeted(m.TargetSystem) {
+			g.handleMissionItem(f, from, m, now)
+		}
+	case MsgMissionRequest, MsgMissionRequestInt:
+		// items are sent as MISSION_ITEM_INT either way
+		m := parseMissionSeq(f.Payload)
+		if g.targeted(m.TargetSystem) {
+			g.handleMissionRequest(f, from, m)
+		}
+	case MsgMissionAck:
+		m := parseMissionAck(f.Payload)
+		if g.targeted(m.TargetSystem) && (m.MissionType == missionTypeMission) {
+			g.download = nil
+		}
+	case MsgMissionClearAll:
+		m := parseMissionTarget(f.Payload)
+		if g.targeted(m.TargetSystem) {
+			if m.MissionType == missionTypeMission {
+				g.request(from, core.CmdClearWaypoints, "")
+			}
+			g.ack(f, from, missionAccepted, m.MissionType)
+		}
+	case MsgManualControl:
+		m := parseManualControl(f.Payload)
+		if g.targeted(m.Target) {
+			g.handleManualControl(from, m, now)
+		}
+	case MsgCommandLong:
+		m := parseCommandLong(f.Payload)
+		if g.targeted(m.TargetSystem) {
+			g.handleCommand(f, from, m)
+		}
+	}
+}
+
+// targeted tells whether a message addressed to system is for the vessel
+func (g *Gateway) targeted(system uint8) bool {
+	return (system == 0) || (system == g.systemId)
+}
+
+func (g *Gateway) handleRequestList(f *Frame, from *net.UDPAddr, m *MissionTarget) {
+	count := 0
+	if m.MissionType == missionTypeMission {
+		g.download = g.core.Waypoints()
+		count = len(g.download)
+	}
+	// fences and rally points are reported empty
+	g.send(from, MsgMissionCount, (&MissionSeq{
+		Seq:             uint16(count),
+		TargetSystem:    f.SystemId,
+		TargetComponent: f.ComponentId,
+		MissionType:     m.MissionType,
+	}).marshal())
+}
+
+func (g *Gateway) handleMissionRequest(f *Frame, from *net.UDPAddr, m *MissionSeq) {
+	if (m.MissionType != missionTypeMission) || (int(m.Seq) >= len(g.download)) {
+		g.ack(f, from, missionInvalidSequence, m.MissionType)
+		return
+	}
+
+	wp := g.download[m.Seq]
+	item := &MissionItemInt{
+		X:               int32(math.Round(wp.Latitude * 1e7)),
+		Y:               int32(math.Round(wp.Longitude * 1e7)),
+		Seq:             m.Seq,
+		Command:         cmdNavWaypoint,
+		TargetSystem:    f.SystemId,
+		TargetComponent: f.ComponentId,
+		Frame:           frameGlobalRelativeAltInt,
+		Autocontinue:    1,
+	}
+	if m.Seq == 0 {
+		item.Current = 1
+	}
+	g.send(from, MsgMissionItemInt, item.marshal())
+}
+
+func (g *Gateway) handleMissionCount(f *Frame, from *net.UDPAddr, m *MissionSeq, now time.Time) {
+	if m.MissionType != missionTypeMission {
+		g.ack(f, from, missionUnsupported, m.MissionType)
+		return
+	}
+	if g.upload != nil {
+		g.logger.Info().Msgf("mission upload from %s replaces the one in progress", from)
+	}
+	g.upload = nil
+	if m.Seq == 0 {
+		g.request(from, core.CmdClearWaypoints, "")
+		g.ack(f, from, missionAccepted, m.MissionType)
+		return
+	}
+
+	g.upload = &upload{
+		addr:        from,
+		systemId:    f.SystemId,
+		componentId: f.ComponentId,
+		count:       int(m.Seq),
+		waypoints:   make([]string, 0, m.Seq),
+	}
+	g.requestItem(now)
+}
+
+func (g *Gateway) handleMissionItem(f *Frame, from *net.UDPAddr, m *MissionItemInt, now time.Time) {
+	up := g.upload
+	if (up == nil) || (up.addr.String() != from.String()) || (m.MissionType != missionTypeMission) {
+		return
+	}
+	if int(m.Seq) != len(up.waypoints) {
+		// a repeated item, the next one is requested on timeout
+		return
+	}
+
+	lat := float64(m.X) / 1e7
+	lon := float64(m.Y) / 1e7
+	result := missionAccepted
+	switch {
+	case m.Command != cmdNavWaypoint:
+		result = missionUnsupported
+	case !globalFrame(m.Frame):
+		result = missionUnsupportedFrame
+	case (math.Abs(lat) > 90) || (math.Abs(lon) > 180):
+		result = missionError
+	}
+	if result != missionAccepted {
+		g.logger.Warn().Msgf("rejecting mission from %s: item %d has command %d in frame %d",
+			from, m.Seq, m.Command, m.Frame)
+		g.upload = nil
+		g.ack(f, from, uint8(result), m.MissionType)
+		return
+	}
+
+	up.waypoints = append(up.waypoints, formatFloat(lat)+","+formatFloat(lon))
+	up.retries = 0
+	if len(up.waypoints) < up.count {
+		g.requestItem(now)
+		return
+	}
+
+	g.upload = nil
+	g.request(from, core.CmdSetWaypoints, strings.Join(up.waypoints, ";"))
+	g.ack(f, from, missionAccepted, m.MissionType)
+}
+
+// requestItem asks for the next item of the upload
+func (g *Gateway) requestItem(now time.Time) {
+	up := g.upload
+	up.requested = now
+	g.send(up.addr, MsgMissionRequestInt, (&MissionSeq{
+		Seq:             uint16(len(up.waypoints)),
+		TargetSystem:    up.systemId,
+		TargetComponent: up.componentId,
+	}).marshal())
+}
+
+// checkUpload repeats requests for items that didn't arrive
+// and gives up after missionRetries
+func (g *Gateway) checkUpload(now time.Time) {
+	up := g.upload
+	if (up == nil) || (now.Sub(up.requested) < missionTimeout) {
+		return
+	}
+	up.retries++
+	if up.retries <= missionRetries {
+		g.requestItem(now)
+		return
+	}
+
+	g.logger.Warn().Msgf("mission upload from %s timed out", up.addr)
+	g.upload = nil
+	g.send(up.addr, MsgMissionAck, (&MissionAck{
+		TargetSystem:    up.systemId,
+		TargetComponent: up.componentId,
+		Type:            missionOperationCancelled,
+	}).marshal())
+}
+
+func (g *Gateway) handleManualControl(from *net.UDPAddr, m *ManualControl, now time.Time) {
+	g.manualAt = now
+	g.manualFrom = from
+	speed := int(math.Round(float64(m.X) * float64(g.maxSpeed) / 1000))
+	steering := int(math.Round(float64(m.R) * float64(g.maxSteering) / 1000))
+	// only changes are passed on, a centered stick doesn't stop autonav
+	if speed != g.speed {
+		g.speed = speed
+		g.request(from, core.CmdSetSpeed, strconv.Itoa(speed))
+	}
+	if steering != g.steering {
+		g.steering = steering
+		g.request(from, core.CmdSetSteering, strconv.Itoa(steering))
+	}
+}
+
+// checkManualControl stops the vessel moved by manual control and centers
+// its rudder when input stops, so that a lost link doesn't leave it running
+// at the last speed and steering
+func (g *Gateway) checkManualControl(now time.Time) {
+	if ((g.speed == 0) && (g.steering == 0)) || (now.Sub(g.manualAt) < manualControlTimeout) {
+		return
+	}
+	g.logger.Warn().Msgf("no manual control from %s for %s, stopping",
+		g.manualFrom, now.Sub(g.manualAt).Round(time.Millisecond))
+	if g.speed != 0 {
+		g.speed = 0
+		g.request(g.manualFrom, core.CmdSetSpeed, "0")
+	}
+	if g.steering != 0 {
+		g.steering = 0
+		g.request(g.manualFrom, core.CmdSetSteering, "0")
+	}
+}
+
+func (g *Gateway) handleCommand(f *Frame, from *net.UDPAddr, m *CommandLong) {
+	result := uint8(resultAccepted)
+	switch m.Command {
+	case cmdMissionStart:
+		g.request(from, core.CmdNavStart, "")
+	case cmdDoPauseContinue:
+		if m.Params[0] == 0 {
+			g.request(from, core.CmdNavStop, "")
+		} else {
+			g.request(from, core.CmdNavStart, "")
+		}
+	case cmdDoSetHome:
+		lat, lon := float64(m.Params[4]), float64(m.Params[5])
+		if m.Params[0] == 1 {
+			// the current position
+			t := g.core.LastTelemetry()
+			if (t == nil) || (time.Since(t.Timestamp) > g.maxAge) {
+				result = resultDenied
+				break
+			}
+			lat, lon = t.Latitude, t.Longitude
+		}
+		g.request(from, core.CmdSetHomeWaypoint, formatFloat(lat)+","+formatFloat(lon))
+	case cmdDoFlightTermination:
+		if m.Params[0] < 0.5 {
+			result = resultDenied
+			break
+		}
+		g.request(from, core.CmdEmergencyStop, "")
+	default:
+		result = resultUnsupported
+	}
+
+	g.send(from, MsgCommandAck, (&CommandAck{
+		Command:         m.Command,
+		Result:          result,
+		TargetSystem:    f.SystemId,
+		TargetComponent: f.ComponentId,
+	}).marshal())
+}
+
+// request passes a command to the core, it's executed asynchronously
+func (g *Gateway) request(from *net.UDPAddr, cmd string, data string) {
+	msg, err := json.Marshal(&core.Request{
+		Type: core.RequestTypeCmd,
+		Cmd:  cmd,
+		Data: data,
+	})
+	if err != nil {
+		g.logger.Error().Err(err).Msg("failed to marshal request")
+		return
+	}
+	g.core.HandleRequestWithMeta(g, msg, &core.RequestMeta{
+		Operator: "mavlink " + from.String(),
+	})
+}
+
+func (g *Gateway) ack(f *Frame, to *net.UDPAddr, result uint8, missionType uint8) {
+	g.send(to, MsgMissionAck, (&MissionAck{
+		TargetSystem:    f.SystemId,
+		TargetComponent: f.ComponentId,
+		Type:            result,
+		MissionType:     missionType,
+	}).marshal())
+}
+
+func (g *Gateway) sendHeartbeat() {
+	baseMode := uint8(modeFlagManualInputEnabled | modeFlagSafetyArmed)
+	if g.core.AutoNav() {
+		baseMode |= modeFlagAutoEnabled
+	}
+	systemStatus := uint8(stateStandby)
+	if t := g.core.LastTelemetry(); (t != nil) && (time.Since(t.Timestamp) <= g.maxAge) {
+		systemStatus = stateActive
+	}
+
+	g.broadcast(MsgHeartbeat, (&Heartbeat{
+		Type:           typeSurfaceBoat,
+		Autopilot:      autopilotGeneric,
+		BaseMode:       baseMode,
+		SystemStatus:   systemStatus,
+		MavlinkVersion: mavlinkVersion,
+	}).marshal())
+}
+
+// sendPosition reports telemetry while it's fresh, ship-nav speed
+// is taken as meters per second
+func (g *Gateway) sendPosition(now time.Time) {
+	t := g.core.LastTelemetry()
+	if (t == nil) || (now.Sub(t.Timestamp) > g.maxAge) {
+		return
+	}
+
+	heading := math.Mod(math.Mod(t.Heading, 360)+360, 360)
+	g.broadcast(MsgGlobalPositionInt, (&GlobalPositionInt{
+		TimeBootMs: uint32(now.Sub(g.started).Milliseconds()),
+		Lat:        int32(math.Round(t.Latitude * 1e7)),
+		Lon:        int32(math.Round(t.Longitude * 1e7)),
+		Vx:         int16(math.Round(t.Speed * 100 * math.Cos(heading*math.Pi/180))),
+		Vy:         int16(math.Round(t.Speed * 100 * math.Sin(heading*math.Pi/180))),
+		Hdg:        uint16(math.Round(heading*100)) % 36000,
+	}).marshal())
+}
+
+func (g *Gateway) send(to *net.UDPAddr, messageId uint32, payload []byte) {
+	g.connMutex.Lock()
+	defer g.connMutex.Unlock()
+
+	g.sendLocked([]*net.UDPAddr{to}, messageId, payload)
+}
+
+// broadcast sends to peers heard from lately and to gcsAddress
+func (g *Gateway) broadcast(messageId uint32, payload []byte) {
+	g.connMutex.Lock()
+	defer g.connMutex.Unlock()
+
+	addrs := make([]*net.UDPAddr, 0, len(g.peers)+1)
+	if g.gcs != nil {
+		addrs = append(addrs, g.gcs)
+	}
+	for key, p := range g.peers {
+		if time.Since(p.lastSeen) > peerTimeout {
+			g.logger.Info().Msgf("ground station %s timed out", key)
+			delete(g.peers, key)
+			continue
+		}
+		if (g.gcs == nil) || (key != g.gcs.String()) {
+			addrs = append(addrs, p.addr)
+		}
+	}
+	g.sendLocked(addrs, messageId, payload)
+}
+
+// sendLocked is called with connMutex locked, messages are dropped
+// while the gateway isn't running
+func (g *Gateway) sendLocked(addrs []*net.UDPAddr, messageId uint32, payload []byte) {
+	if (g.conn == nil) || (len(addrs) == 0) {
+		return
+	}
+
+	f := &Frame{
+		Seq:         g.seq,
+		SystemId:    g.systemId,
+		ComponentId: componentId,
+		MessageId:   messageId,
+		Payload:     payload,
+	}
+	g.seq++
+	data, err := f.Encode()
+	if err != nil {
+		g.logger.Error().Err(err).Msg("failed to encode MAVLink frame")
+		return
+	}
+	for _, addr := range addrs {
+		if _, err := g.conn.WriteToUDP(data, addr); err != nil {
+			g.logger.Debug().Err(err).Msgf("failed to send to %s", addr)
+			metrics.Errors.WithLabelValues("mavlink", "write").Inc()
+			continue
+		}
+		g.sent++
+	}
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', 7, 64)
+}
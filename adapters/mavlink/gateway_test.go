@@ -0,0 +1,321 @@
+package mavlink
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/moosethebrown/ship-net-bridge/adapters/daemon"
+	"github.com/moosethebrown/ship-net-bridge/adapters/shipnav"
+	"github.com/moosethebrown/ship-net-bridge/core"
+	"github.com/moosethebrown/ship-net-bridge/queue"
+	"github.com/rs/zerolog"
+)
+
+type mockCore struct {
+	mutex     sync.Mutex
+	requests  []*core.Request
+	telemetry *core.Telemetry
+	waypoints []core.Waypoint
+}
+
+func (m *mockCore) HandleRequestWithMeta(transport core.Transport, msg []byte, meta *core.RequestMeta) {
+	rq := &core.Request{}
+	json.Unmarshal(msg, rq)
+	m.mutex.Lock()
+	m.requests = append(m.requests, rq)
+	m.mutex.Unlock()
+}
+
+func (m *mockCore) LastTelemetry() *core.Telemetry {
+	return m.telemetry
+}
+
+func (m *mockCore) Waypoints() []core.Waypoint {
+	return m.waypoints
+}
+
+func (m *mockCore) AutoNav() bool {
+	return false
+}
+
+// takeRequests waits for n requests
+func (m *mockCore) takeRequests(t *testing.T, n int) []*core.Request {
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		m.mutex.Lock()
+		if len(m.requests) >= n {
+			requests := m.requests
+			m.requests = nil
+			m.mutex.Unlock()
+			return requests
+		}
+		m.mutex.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Expected %d requests", n)
+	return nil
+}
+
+// station is a ground control station talking to the gateway
+type station struct {
+	t       *testing.T
+	conn    *net.UDPConn
+	gateway *net.UDPAddr
+	seq     uint8
+}
+
+func (s *station) send(messageId uint32, payload []byte) {
+	f := &Frame{Seq: s.seq, SystemId: 255, ComponentId: 190, MessageId: messageId, Payload: payload}
+	s.seq++
+	data, err := f.Encode()
+	if err != nil {
+		s.t.Fatalf("Failed to encode frame: %s", err)
+	}
+	s.conn.WriteToUDP(data, s.gateway)
+}
+
+// expect returns the next message of the given id, skipping others
+func (s *station) expect(messageId uint32) *Frame {
+	buf := make([]byte, maxDatagram)
+	s.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		n, _, err := s.conn.ReadFromUDP(buf)
+		if err != nil {
+			s.t.Fatalf("Expected message %d: %s", messageId, err)
+		}
+		f, _, err := Decode(buf[:n])
+		if err != nil {
+			s.t.Fatalf("Failed to decode frame: %s", err)
+		}
+		if f.MessageId == messageId {
+			return f
+		}
+	}
+}
+
+// startGateway runs a gateway announcing itself to the returned station,
+// stop cancels it and returns Run's result
+func startGateway(t *testing.T, theCore Core) (*Gateway, *station, func() error) {
+	logger := zerolog.New(os.Stdout).Level(zerolog.DebugLevel)
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("Failed to listen on udp: %s", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	// find a free port
+	probe, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen on udp: %s", err)
+	}
+	address := probe.LocalAddr().String()
+	probe.Close()
+
+	_, loopback, _ := net.ParseCIDR("127.0.0.0/8")
+	gateway := NewGateway(address, conn.LocalAddr().String(), 0, 20*time.Millisecond, 0, 10, 0,
+		[]*net.IPNet{loopback}, theCore, &logger)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	done := make(chan error)
+	go func() {
+		done <- gateway.Run(ctx)
+	}()
+
+	gcs := &station{t: t, conn: conn}
+	gcs.gateway, _ = net.ResolveUDPAddr("udp", address)
+	return gateway, gcs, func() error {
+		cancel()
+		return <-done
+	}
+}
+
+func TestGateway(t *testing.T) {
+	theCore := &mockCore{
+		telemetry: &core.Telemetry{Latitude: 56.348284, Longitude: 43.95941, Heading: 90, Speed: 2, Timestamp: time.Now()},
+		waypoints: []core.Waypoint{{Latitude: 56.36, Longitude: 43.9}},
+	}
+
+	gateway, gcs, stop := startGateway(t, theCore)
+
+	// the vessel is announced to gcsAddress
+	hb := parseHeartbeat(gcs.expect(MsgHeartbeat).Payload)
+	if (hb.Type != typeSurfaceBoat) || (hb.SystemStatus != stateActive) {
+		t.Errorf("Unexpected heartbeat %+v", hb)
+	}
+	f := gcs.expect(MsgGlobalPositionInt)
+	pos := parseGlobalPositionInt(f.Payload)
+	if (f.SystemId != DefaultSystemId) || (pos.Lat != 563482840) || (pos.Hdg != 9000) || (pos.Vy != 200) {
+		t.Errorf("Unexpected position %+v", pos)
+	}
+
+	// mission upload
+	gcs.send(MsgMissionCount, (&MissionSeq{Seq: 2, TargetSystem: 1}).marshal())
+	for i, xy := range [][2]int32{{563482840, 439594100}, {563592260, 439076180}} {
+		rq := parseMissionSeq(gcs.expect(MsgMissionRequestInt).Payload)
+		if (int(rq.Seq) != i) || (rq.TargetSystem != 255) {
+			t.Fatalf("Unexpected request for item %+v", rq)
+		}
+		gcs.send(MsgMissionItemInt, (&MissionItemInt{X: xy[0], Y: xy[1], Seq: uint16(i),
+			Command: cmdNavWaypoint, TargetSystem: 1, Frame: frameGlobalRelativeAltInt}).marshal())
+	}
+	if ack := parseMissionAck(gcs.expect(MsgMissionAck).Payload); ack.Type != missionAccepted {
+		t.Errorf("Expected mission to be accepted, got %+v", ack)
+	}
+	rq := theCore.takeRequests(t, 1)[0]
+	if (rq.Cmd != core.CmdSetWaypoints) || (rq.Data != "56.3482840,43.9594100;56.3592260,43.9076180") {
+		t.Errorf("Unexpected request %+v", rq)
+	}
+
+	// unsupported mission items are rejected
+	gcs.send(MsgMissionCount, (&MissionSeq{Seq: 1, TargetSystem: 1}).marshal())
+	gcs.expect(MsgMissionRequestInt)
+	gcs.send(MsgMissionItemInt, (&MissionItemInt{Command: 22, TargetSystem: 1, Frame: frameGlobalInt}).marshal())
+	if ack := parseMissionAck(gcs.expect(MsgMissionAck).Payload); ack.Type != missionUnsupported {
+		t.Errorf("Expected takeoff to be rejected, got %+v", ack)
+	}
+
+	// mission download
+	gcs.send(MsgMissionRequestList, (&MissionTarget{TargetSystem: 1}).marshal())
+	if count := parseMissionSeq(gcs.expect(MsgMissionCount).Payload); count.Seq != 1 {
+		t.Fatalf("Expected one waypoint, got %+v", count)
+	}
+	gcs.send(MsgMissionRequestInt, (&MissionSeq{Seq: 0, TargetSystem: 1}).marshal())
+	item := parseMissionItemInt(gcs.expect(MsgMissionItemInt).Payload)
+	if (item.X != 563600000) || (item.Y != 439000000) || (item.Command != cmdNavWaypoint) || (item.Current != 1) {
+		t.Errorf("Unexpected mission item %+v", item)
+	}
+	gcs.send(MsgMissionRequestInt, (&MissionSeq{Seq: 1, TargetSystem: 1}).marshal())
+	if ack := parseMissionAck(gcs.expect(MsgMissionAck).Payload); ack.Type != missionInvalidSequence {
+		t.Errorf("Expected invalid sequence, got %+v", ack)
+	}
+
+	// commands, the one for another system is ignored
+	gcs.send(MsgCommandLong, (&CommandLong{Command: cmdMissionStart, TargetSystem: 2}).marshal())
+	gcs.send(MsgCommandLong, (&CommandLong{Command: cmdMissionStart, TargetSystem: 1}).marshal())
+	if ack := parseCommandAck(gcs.expect(MsgCommandAck).Payload); (ack.Command != cmdMissionStart) ||
+		(ack.Result != resultAccepted) || (ack.TargetSystem != 255) {
+		t.Errorf("Unexpected command ack %+v", ack)
+	}
+	gcs.send(MsgCommandLong, (&CommandLong{Command: 512, TargetSystem: 1}).marshal())
+	if ack := parseCommandAck(gcs.expect(MsgCommandAck).Payload); ack.Result != resultUnsupported {
+		t.Errorf("Expected unsupported command, got %+v", ack)
+	}
+
+	// manual control, a centered stick isn't passed on
+	gcs.send(MsgManualControl, (&ManualControl{Target: 1}).marshal())
+	gcs.send(MsgManualControl, (&ManualControl{X: 500, R: -1000, Target: 1}).marshal())
+	requests := theCore.takeRequests(t, 3)
+	if (requests[0].Cmd != core.CmdNavStart) || (requests[1].Cmd != core.CmdSetSpeed) ||
+		(requests[1].Data != "5") || (requests[2].Cmd != core.CmdSetSteering) || (requests[2].Data != "-100") {
+		t.Errorf("Unexpected requests %+v %+v %+v", requests[0], requests[1], requests[2])
+	}
+
+	gateway.SendResponse([]byte(`{"type":"error","cmd":"nav_start","error":"no waypoints"}`))
+	text := parseStatusText(gcs.expect(MsgStatusText).Payload)
+	if (text.Severity != severityError) || (text.Text != "nav_start: no waypoints") {
+		t.Errorf("Unexpected status text %+v", text)
+	}
+	if status := gateway.Status().(*Status); (len(status.Peers) != 1) || (status.Errors != 0) {
+		t.Errorf("Unexpected status %+v", status)
+	}
+
+	if err := stop(); err != nil {
+		t.Errorf("Expected gateway to stop cleanly, got %s", err)
+	}
+}
+
+func TestManualControlTimeout(t *testing.T) {
+	logger := zerolog.New(os.Stdout).Level(zerolog.DebugLevel)
+	theCore := &mockCore{}
+	gateway := NewGateway(":14550", "", 0, 0, 0, 10, 0, nil, theCore, &logger)
+	gcs := &net.UDPAddr{IP: net.IPv4(192, 168, 1, 10), Port: 14550}
+
+	// without allowed networks nobody is
+	if gateway.allowedAddr(gcs) {
+		t.Errorf("Expected %s to be denied", gcs)
+	}
+
+	now := time.Now()
+	gateway.handleManualControl(gcs, &ManualControl{X: 500, R: 300, Target: 1}, now)
+	gateway.checkManualControl(now.Add(manualControlTimeout / 2))
+	gateway.checkManualControl(now.Add(2 * manualControlTimeout))
+	gateway.checkManualControl(now.Add(3 * manualControlTimeout))
+	requests := theCore.takeRequests(t, 4)
+	if (len(requests) != 4) || (requests[0].Data != "5") || (requests[1].Data != "30") ||
+		(requests[2].Cmd != core.CmdSetSpeed) || (requests[2].Data != "0") ||
+		(requests[3].Cmd != core.CmdSetSteering) || (requests[3].Data != "0") {
+		t.Errorf("Expected the vessel to be stopped and centered once, got %+v", requests)
+	}
+}
+
+// TestMissionToShipNav follows an uploaded mission through the core
+// to the request received by the ship-nav daemon
+func TestMissionToShipNav(t *testing.T) {
+	logger := zerolog.New(os.Stdout).Level(zerolog.DebugLevel)
+	socket := filepath.Join(t.TempDir(), "sn.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("Failed to listen on %s: %s", socket, err)
+	}
+	defer l.Close()
+
+	// the daemon answers queries and reports waypoint uploads
+	uploads := make(chan *shipnav.Request, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		decoder := json.NewDecoder(conn)
+		for {
+			rq := &shipnav.Request{}
+			if decoder.Decode(rq) != nil {
+				return
+			}
+			if rq.Type == "cmd" {
+				uploads <- rq
+			}
+			conn.Write([]byte(`{}`))
+		}
+	}()
+
+	theCore := core.NewCore(nil, nil, 3000, &logger)
+	shipNav := shipnav.NewAdapter(&daemon.Endpoint{Address: socket}, theCore, 0,
+		queue.Reject, time.Second, &logger)
+	theCore.SetShipNav(shipNav)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go theCore.Run(ctx)
+	go shipNav.Run(ctx)
+
+	_, gcs, stop := startGateway(t, theCore)
+	defer stop()
+	// the gateway is listening once it's announced itself
+	gcs.expect(MsgHeartbeat)
+	gcs.send(MsgMissionCount, (&MissionSeq{Seq: 2, TargetSystem: 1}).marshal())
+	for i, xy := range [][2]int32{{563482840, 439594100}, {563592260, 439076180}} {
+		gcs.expect(MsgMissionRequestInt)
+		gcs.send(MsgMissionItemInt, (&MissionItemInt{X: xy[0], Y: xy[1], Seq: uint16(i),
+			Command: cmdNavWaypoint, TargetSystem: 1, Frame: frameGlobalRelativeAltInt}).marshal())
+	}
+	gcs.expect(MsgMissionAck)
+
+	select {
+	case rq := <-uploads:
+		if (rq.Cmd != core.CmdSetWaypoints) || (len(rq.Waypoints) != 2) ||
+			(rq.Waypoints[1].Latitude != 56.359226) {
+			t.Errorf("Unexpected ship-nav request %+v", rq)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Expected the mission to reach ship-nav")
+	}
+	if waypoints := theCore.Waypoints(); len(waypoints) != 2 {
+		t.Errorf("Expected the core to keep the mission, got %+v", waypoints)
+	}
+}
@@ -0,0 +1,394 @@
+package mavlink
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+// Message ids of the common dialect used by the gateway
+const (
+	MsgHeartbeat          = 0
+	MsgGlobalPositionInt  = 33
+	MsgMissionRequest     = 40
+	MsgMissionRequestList = 43
+	MsgMissionCount       = 44
+	MsgMissionClearAll    = 45
+	MsgMissionAck         = 47
+	MsgMissionRequestInt  = 51
+	MsgManualControl      = 69
+	MsgMissionItemInt     = 73
+	MsgCommandLong        = 76
+	MsgCommandAck         = 77
+	MsgStatusText         = 253
+)
+
+// messageInfo holds CRC_EXTRA and payload length, extensions included,
+// of a message
+type messageInfo struct {
+	crcExtra byte
+	length   int
+}
+
+var messages = map[uint32]messageInfo{
+	MsgHeartbeat:          {50, 9},
+	MsgGlobalPositionInt:  {104, 28},
+	MsgMissionRequest:     {230, 5},
+	MsgMissionRequestList: {132, 3},
+	MsgMissionCount:       {221, 5},
+	MsgMissionClearAll:    {232, 3},
+	MsgMissionAck:         {153, 4},
+	MsgMissionRequestInt:  {196, 5},
+	MsgManualControl:      {243, 11},
+	MsgMissionItemInt:     {38, 38},
+	MsgCommandLong:        {152, 33},
+	MsgCommandAck:         {143, 10},
+	MsgStatusText:         {83, 51},
+}
+
+// Enum values of the common dialect used by the gateway
+const (
+	typeSurfaceBoat  = 11
+	autopilotGeneric = 0
+
+	modeFlagAutoEnabled        = 4
+	modeFlagManualInputEnabled = 64
+	modeFlagSafetyArmed        = 128
+	stateStandby               = 3
+	stateActive                = 4
+	mavlinkVersion             = 3
+
+	missionTypeMission = 0
+
+	missionAccepted           = 0
+	missionError              = 1
+	missionUnsupportedFrame   = 2
+	missionUnsupported        = 3
+	missionInvalidSequence    = 13
+	missionOperationCancelled = 15
+
+	cmdNavWaypoint         = 16
+	cmdDoSetHome           = 179
+	cmdDoFlightTermination = 185
+	cmdDoPauseContinue     = 193
+	cmdMissionStart        = 300
+
+	frameGlobal               = 0
+	frameGlobalRelativeAlt    = 3
+	frameGlobalInt            = 5
+	frameGlobalRelativeAltInt = 6
+	frameGlobalTerrainAlt     = 10
+	frameGlobalTerrainAltInt  = 11
+
+	resultAccepted    = 0
+	resultDenied      = 2
+	resultUnsupported = 3
+
+	severityError   = 3
+	severityWarning = 4
+)
+
+// globalFrame tells whether items in frame have latitude and longitude
+func globalFrame(frame uint8) bool {
+	switch frame {
+	case frameGlobal, frameGlobalRelativeAlt, frameGlobalInt,
+		frameGlobalRelativeAltInt, frameGlobalTerrainAlt, frameGlobalTerrainAltInt:
+		return true
+	}
+	return false
+}
+
+type Heartbeat struct {
+	CustomMode     uint32
+	Type           uint8
+	Autopilot      uint8
+	BaseMode       uint8
+	SystemStatus   uint8
+	MavlinkVersion uint8
+}
+
+func (m *Heartbeat) marshal() []byte {
+	p := binary.LittleEndian.AppendUint32(nil, m.CustomMode)
+	return append(p, m.Type, m.Autopilot, m.BaseMode, m.SystemStatus, m.MavlinkVersion)
+}
+
+func parseHeartbeat(p []byte) *Heartbeat {
+	return &Heartbeat{
+		CustomMode:     binary.LittleEndian.Uint32(p),
+		Type:           p[4],
+		Autopilot:      p[5],
+		BaseMode:       p[6],
+		SystemStatus:   p[7],
+		MavlinkVersion: p[8],
+	}
+}
+
+// GlobalPositionInt has coordinates in degrees * 1e7, altitudes
+// in millimeters, velocities in cm/s and heading in centidegrees
+type GlobalPositionInt struct {
+	TimeBootMs  uint32
+	Lat         int32
+	Lon         int32
+	Alt         int32
+	RelativeAlt int32
+	Vx          int16
+	Vy          int16
+	Vz          int16
+	Hdg         uint16
+}
+
+func (m *GlobalPositionInt) marshal() []byte {
+	p := binary.LittleEndian.AppendUint32(nil, m.TimeBootMs)
+	for _, v := range []int32{m.Lat, m.Lon, m.Alt, m.RelativeAlt} {
+		p = binary.LittleEndian.AppendUint32(p, uint32(v))
+	}
+	for _, v := range []int16{m.Vx, m.Vy, m.Vz} {
+		p = binary.LittleEndian.AppendUint16(p, uint16(v))
+	}
+	return binary.LittleEndian.AppendUint16(p, m.Hdg)
+}
+
+func parseGlobalPositionInt(p []byte) *GlobalPositionInt {
+	return &GlobalPositionInt{
+		TimeBootMs:  binary.LittleEndian.Uint32(p),
+		Lat:         int32(binary.LittleEndian.Uint32(p[4:])),
+		Lon:         int32(binary.LittleEndian.Uint32(p[8:])),
+		Alt:         int32(binary.LittleEndian.Uint32(p[12:])),
+		RelativeAlt: int32(binary.LittleEndian.Uint32(p[16:])),
+		Vx:          int16(binary.LittleEndian.Uint16(p[20:])),
+		Vy:          int16(binary.LittleEndian.Uint16(p[22:])),
+		Vz:          int16(binary.LittleEndian.Uint16(p[24:])),
+		Hdg:         binary.LittleEndian.Uint16(p[26:]),
+	}
+}
+
+// MissionTarget addresses mission protocol messages without other fields:
+// MISSION_REQUEST_LIST and MISSION_CLEAR_ALL
+type MissionTarget struct {
+	TargetSystem    uint8
+	TargetComponent uint8
+	MissionType     uint8
+}
+
+func (m *MissionTarget) marshal() []byte {
+	return []byte{m.TargetSystem, m.TargetComponent, m.MissionType}
+}
+
+func parseMissionTarget(p []byte) *MissionTarget {
+	return &MissionTarget{
+		TargetSystem:    p[0],
+		TargetComponent: p[1],
+		MissionType:     p[2],
+	}
+}
+
+// MissionSeq is MISSION_COUNT, with Seq being the count, or one of
+// MISSION_REQUEST and MISSION_REQUEST_INT
+type MissionSeq struct {
+	Seq             uint16
+	TargetSystem    uint8
+	TargetComponent uint8
+	MissionType     uint8
+}
+
+func (m *MissionSeq) marshal() []byte {
+	p := binary.LittleEndian.AppendUint16(nil, m.Seq)
+	return append(p, m.TargetSystem, m.TargetComponent, m.MissionType)
+}
+
+func parseMissionSeq(p []byte) *MissionSeq {
+	return &MissionSeq{
+		Seq:             binary.LittleEndian.Uint16(p),
+		TargetSystem:    p[2],
+		TargetComponent: p[3],
+		MissionType:     p[4],
+	}
+}
+
+type MissionAck struct {
+	TargetSystem    uint8
+	TargetComponent uint8
+	Type            uint8
+	MissionType     uint8
+}
+
+func (m *MissionAck) marshal() []byte {
+	return []byte{m.TargetSystem, m.TargetComponent, m.Type, m.MissionType}
+}
+
+func parseMissionAck(p []byte) *MissionAck {
+	return &MissionAck{
+		TargetSystem:    p[0],
+		TargetComponent: p[1],
+		Type:            p[2],
+		MissionType:     p[3],
+	}
+}
+
+// MissionItemInt has X and Y, latitude and longitude in global frames,
+// in degrees * 1e7
+type MissionItemInt struct {
+	Param1          float32
+	Param2          float32
+	Param3          float32
+	Param4          float32
+	X               int32
+	Y               int32
+	Z               float32
+	Seq             uint16
+	Command         uint16
+	TargetSystem    uint8
+	TargetComponent uint8
+	Frame           uint8
+	Current         uint8
+	Autocontinue    uint8
+	MissionType     uint8
+}
+
+func (m *MissionItemInt) marshal() []byte {
+	p := make([]byte, 0, 38)
+	for _, v := range []float32{m.Param1, m.Param2, m.Param3, m.Param4} {
+		p = binary.LittleEndian.AppendUint32(p, math.Float32bits(v))
+	}
+	p = binary.LittleEndian.AppendUint32(p, uint32(m.X))
+	p = binary.LittleEndian.AppendUint32(p, uint32(m.Y))
+	p = binary.LittleEndian.AppendUint32(p, math.Float32bits(m.Z))
+	p = binary.LittleEndian.AppendUint16(p, m.Seq)
+	p = binary.LittleEndian.AppendUint16(p, m.Command)
+	return append(p, m.TargetSystem, m.TargetComponent, m.Frame, m.Current,
+		m.Autocontinue, m.MissionType)
+}
+
+func parseMissionItemInt(p []byte) *MissionItemInt {
+	return &MissionItemInt{
+		Param1:          math.Float32frombits(binary.LittleEndian.Uint32(p)),
+		Param2:          math.Float32frombits(binary.LittleEndian.Uint32(p[4:])),
+		Param3:          math.Float32frombits(binary.LittleEndian.Uint32(p[8:])),
+		Param4:          math.Float32frombits(binary.LittleEndian.Uint32(p[12:])),
+		X:               int32(binary.LittleEndian.Uint32(p[16:])),
+		Y:               int32(binary.LittleEndian.Uint32(p[20:])),
+		Z:               math.Float32frombits(binary.LittleEndian.Uint32(p[24:])),
+		Seq:             binary.LittleEndian.Uint16(p[28:]),
+		Command:         binary.LittleEndian.Uint16(p[30:]),
+		TargetSystem:    p[32],
+		TargetComponent: p[33],
+		Frame:           p[34],
+		Current:         p[35],
+		Autocontinue:    p[36],
+		MissionType:     p[37],
+	}
+}
+
+// ManualControl axes range from -1000 to 1000: X is forward,
+// R is yaw, clockwise
+type ManualControl struct {
+	X       int16
+	Y       int16
+	Z       int16
+	R       int16
+	Buttons uint16
+	Target  uint8
+}
+
+func (m *ManualControl) marshal() []byte {
+	p := make([]byte, 0, 11)
+	for _, v := range []int16{m.X, m.Y, m.Z, m.R} {
+		p = binary.LittleEndian.AppendUint16(p, uint16(v))
+	}
+	p = binary.LittleEndian.AppendUint16(p, m.Buttons)
+	return append(p, m.Target)
+}
+
+func parseManualControl(p []byte) *ManualControl {
+	return &ManualControl{
+		X:       int16(binary.LittleEndian.Uint16(p)),
+		Y:       int16(binary.LittleEndian.Uint16(p[2:])),
+		Z:       int16(binary.LittleEndian.Uint16(p[4:])),
+		R:       int16(binary.LittleEndian.Uint16(p[6:])),
+		Buttons: binary.LittleEndian.Uint16(p[8:]),
+		Target:  p[10],
+	}
+}
+
+type CommandLong struct {
+	Params          [7]float32
+	Command         uint16
+	TargetSystem    uint8
+	TargetComponent uint8
+	Confirmation    uint8
+}
+
+func (m *CommandLong) marshal() []byte {
+	p := make([]byte, 0, 33)
+	for _, v := range m.Params {
+		p = binary.LittleEndian.AppendUint32(p, math.Float32bits(v))
+	}
+	p = binary.LittleEndian.AppendUint16(p, m.Command)
+	return append(p, m.TargetSystem, m.TargetComponent, m.Confirmation)
+}
+
+func parseCommandLong(p []byte) *CommandLong {
+	m := &CommandLong{
+		Command:         binary.LittleEndian.Uint16(p[28:]),
+		TargetSystem:    p[30],
+		TargetComponent: p[31],
+		Confirmation:    p[32],
+	}
+	for i := range m.Params {
+		m.Params[i] = math.Float32frombits(binary.LittleEndian.Uint32(p[i*4:]))
+	}
+	return m
+}
+
+// CommandAck addresses the sender of the command by TargetSystem
+// and TargetComponent
+type CommandAck struct {
+	Command         uint16
+	Result          uint8
+	Progress        uint8
+	ResultParam2    int32
+	TargetSystem    uint8
+	TargetComponent uint8
+}
+
+func (m *CommandAck) marshal() []byte {
+	p := binary.LittleEndian.AppendUint16(nil, m.Command)
+	p = append(p, m.Result, m.Progress)
+	p = binary.LittleEndian.AppendUint32(p, uint32(m.ResultParam2))
+	return append(p, m.TargetSystem, m.TargetComponent)
+}
+
+func parseCommandAck(p []byte) *CommandAck {
+	return &CommandAck{
+		Command:         binary.LittleEndian.Uint16(p),
+		Result:          p[2],
+		Progress:        p[3],
+		ResultParam2:    int32(binary.LittleEndian.Uint32(p[4:])),
+		TargetSystem:    p[8],
+		TargetComponent: p[9],
+	}
+}
+
+// StatusText carries up to 50 characters
+type StatusText struct {
+	Severity uint8
+	Text     string
+}
+
+func (m *StatusText) marshal() []byte {
+	p := make([]byte, 51)
+	p[0] = m.Severity
+	copy(p[1:], m.Text)
+	return p
+}
+
+func parseStatusText(p []byte) *StatusText {
+	text := p[1:51]
+	if end := bytes.IndexByte(text, 0); end >= 0 {
+		text = text[:end]
+	}
+	return &StatusText{
+		Severity: p[0],
+		Text:     string(text),
+	}
+}
@@ -1,142 +1,604 @@
 package mqtt
 
 import (
-	"crypto/tls"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
-	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"github.com/moosethebrown/ship-net-bridge/core"
+	"github.com/moosethebrown/ship-net-bridge/metrics"
+	"github.com/moosethebrown/ship-net-bridge/queue"
+	"github.com/moosethebrown/ship-net-bridge/supervisor"
 	"github.com/rs/zerolog"
 )
 
+const statusTypeBroker = "broker"
+
+const (
+	defaultFallbackInterval  = 60 * time.Second
+	defaultReconnectInterval = 5 * time.Second
+)
+
+// Adapter keeps connection to one of the configured brokers for the vessels
+// added with AddVessel, which share it: it fails over
+// to the next broker when the connection is lost or failoverThreshold
+// consecutive announces fail, and returns to a more preferred broker
+// as soon as it becomes reachable again.
+//
+// With MQTT v5 responses to requests carrying a response topic are published
+// to that topic with the request's correlation data, outgoing messages expire
+// after messageExpiry and operatorProperty user property of requests
+// identifies the operator.
 type Adapter struct {
-	broker            string
+	brokers           []*Broker
+	protocolVersion   int
+	messageExpiry     time.Duration
+	operatorProperty  string
 	connTimeout       time.Duration
-	username          string
-	passwd            string
-	shipId            string
+	clientId          string
 	announceTopic     string
 	announceTimeout   time.Duration
 	disconnectTimeout time.Duration
-	rqTopic           string
-	respTopic         string
-	certCheck         bool
-	client            mqtt.Client
-	core              *core.Core
-	stopChan          chan bool
+	failoverThreshold int
+	fallbackInterval  time.Duration
+	reconnectInterval time.Duration
+	vessels           []*Vessel
+	vesselsByTopic    map[string]*Vessel
+	client            client
+	current           int
+	announceFailures  int
 	announceChan      chan bool
-	responseChan      chan []byte
+	announcePending   map[*Vessel]bool
+	announceMutex     sync.Mutex
+	responses         *queue.Priority[*message]
+	connLostChan      chan client
+	brokersChan       chan []*Broker
+	probeChan         chan *probe
 	logger            *zerolog.Logger
+	statusMutex       sync.Mutex
+	lastAnnounce      time.Time
+	lastAnnounceErr   error
+	// probing is set while brokers are being connected to, probeGen
+	// tells results of probes started before the brokers changed
+	probing  bool
+	probeGen int
+	stopping chan struct{}
+	// dial connects to a broker, tests replace it with fake clients
+	dial func(broker *Broker) (client, error)
+}
+
+// probe is the result of connecting to brokers in the background,
+// client is nil when none of them could be connected to
+type probe struct {
+	gen    int
+	client client
+	index  int
+}
+
+type Status struct {
+	Broker            string     `json:"broker"`
+	Connected         bool       `json:"connected"`
+	ResponseQueue     int        `json:"responseQueue"`
+	LastAnnounce      *time.Time `json:"lastAnnounce,omitempty"`
+	LastAnnounceError string     `json:"lastAnnounceError,omitempty"`
+}
+
+// statusMessage is published (retained) on the status topic
+// after connecting to a broker
+type statusMessage struct {
+	Type   string `json:"type"`
+	Broker string `json:"broker"`
 }
 
-func NewAdapter(broker string, connTimeout time.Duration, username string,
-	passwd string, shipId string, announceTopic string,
+func NewAdapter(brokers []*Broker, protocolVersion int,
+	messageExpiry time.Duration, operatorProperty string,
+	connTimeout time.Duration,
+	clientId string, announceTopic string,
 	announceTimeout time.Duration,
 	disconnectTimeout time.Duration,
-	certCheck bool, core *core.Core,
+	failoverThreshold int,
+	fallbackInterval time.Duration,
+	reconnectInterval time.Duration,
+	overflowPolicy queue.Policy,
 	logger *zerolog.Logger) *Adapter {
+	if fallbackInterval <= 0 {
+		fallbackInterval = defaultFallbackInterval
+	}
+	if reconnectInterval <= 0 {
+		reconnectInterval = defaultReconnectInterval
+	}
 
-	return &Adapter{
-		broker:            broker,
+	a := &Adapter{
+		brokers:           brokers,
+		protocolVersion:   protocolVersion,
+		messageExpiry:     messageExpiry,
+		operatorProperty:  operatorProperty,
 		connTimeout:       connTimeout,
-		username:          username,
-		passwd:            passwd,
-		shipId:            shipId,
+		clientId:          clientId,
 		announceTopic:     announceTopic,
 		announceTimeout:   announceTimeout,
 		disconnectTimeout: disconnectTimeout,
-		rqTopic:           fmt.Sprintf("ship/%s/request", shipId),
-		respTopic:         fmt.Sprintf("ship/%s/response", shipId),
-		certCheck:         certCheck,
-		core:              core,
-		stopChan:          make(chan bool, 1),
+		failoverThreshold: failoverThreshold,
+		fallbackInterval:  fallbackInterval,
+		reconnectInterval: reconnectInterval,
+		vessels:           make([]*Vessel, 0),
+		vesselsByTopic:    make(map[string]*Vessel),
+		current:           -1,
 		announceChan:      make(chan bool, 1),
-		responseChan:      make(chan []byte, 1000),
+		announcePending:   make(map[*Vessel]bool),
+		responses:         queue.NewQueue[*message](1000),
+		connLostChan:      make(chan client, 1),
+		brokersChan:       make(chan []*Broker, 1),
+		probeChan:         make(chan *probe),
 		logger:            logger,
 	}
+	a.responses.SetPolicy(overflowPolicy)
+	a.dial = a.connect
+
+	return a
 }
 
-func (a *Adapter) Run() error {
+// Run returns when ctx is cancelled, after publishing queued responses
+func (a *Adapter) Run(ctx context.Context) error {
 	a.logger.Info().Msg("starting")
 	defer a.logger.Info().Msg("stopping")
 
-	err := a.connect()
-	if err != nil {
-		a.logger.Error().Err(err).Msg("failed to connect to MQTT broker")
-		return err
+	if len(a.brokers) == 0 {
+		return supervisor.Fatal(errors.New("no MQTT brokers configured"))
+	}
+	if len(a.vessels) == 0 {
+		return supervisor.Fatal(errors.New("no vessels served over MQTT"))
+	}
+	if (a.protocolVersion != ProtocolV3) && (a.protocolVersion != ProtocolV5) {
+		return supervisor.Fatal(fmt.Errorf("unsupported MQTT protocol version %d", a.protocolVersion))
+	}
+
+	// results of probes of a previous run are outdated
+	a.probeGen++
+	a.probing = false
+	// the first connection is made before serving, later ones in the
+	// background so that responses keep flowing while brokers are probed
+	p := a.probe(a.probeGen, a.brokers, a.preferred(len(a.brokers)))
+	if p.client == nil {
+		return errors.New("failed to connect to any MQTT broker")
 	}
+	a.switchTo(p.client, p.index)
 
-	defer a.client.Disconnect(uint(a.disconnectTimeout.Milliseconds()))
+	a.stopping = make(chan struct{})
+	defer close(a.stopping)
+	defer a.disconnect()
+
+	fallbackTicker := time.NewTicker(a.fallbackInterval)
+	defer fallbackTicker.Stop()
+	reconnectTicker := time.NewTicker(a.reconnectInterval)
+	defer reconnectTicker.Stop()
 
-main_loop:
 	for {
 		select {
-		case <-a.stopChan:
-			break main_loop
+		case <-ctx.Done():
+			a.flush()
+			return nil
 		case <-a.announceChan:
-			a.logger.Debug().Msg("announce")
-
-			token := a.client.Publish(a.announceTopic, 2, false, a.shipId)
-			// TODO: maybe report net loss only after N consecutive failed announce attempts?
-			if token.WaitTimeout(a.announceTimeout) == false {
-				a.logger.Error().Msg("timeout expired while publishing announce message")
-				a.core.NetLoss()
-			} else if err := token.Error(); err != nil {
-				a.logger.Error().Err(err).Msg("error publishing announce message")
-				a.core.NetLoss()
+			a.announcePendingVessels()
+		case <-a.responses.Ready():
+			msg, ok := a.responses.Pop()
+			if !ok {
+				continue
+			}
+			if a.client == nil {
+				a.logger.Error().Msg("not connected to MQTT broker, dropping response")
+				metrics.Errors.WithLabelValues("mqtt", "not_connected").Inc()
+				continue
+			}
+			a.client.Publish(msg, 0)
+			metrics.MqttMessages.WithLabelValues("sent").Inc()
+		case brokers := <-a.brokersChan:
+			a.replaceBrokers(brokers)
+		case p := <-a.probeChan:
+			a.handleProbe(p)
+		case cl := <-a.connLostChan:
+			if cl == a.client {
+				a.failover()
+			}
+		case <-reconnectTicker.C:
+			if a.client == nil {
+				a.startProbe(a.preferred(len(a.brokers)))
 			}
-		case resp := <-a.responseChan:
-			a.client.Publish(a.respTopic, 2, false, resp)
+		case <-fallbackTicker.C:
+			if (a.client != nil) && (a.current > 0) {
+				// only brokers preferred over the current one are probed
+				a.startProbe(a.preferred(a.current))
+			}
+		}
+	}
+}
+
+func (a *Adapter) Name() string {
+	return "mqtt"
+}
+
+// UpdateBrokers replaces broker list, e.g. after credentials change;
+// the adapter reconnects to the most preferred available broker.
+// Can be called any time.
+func (a *Adapter) UpdateBrokers(brokers []*Broker) {
+	// only the latest update matters
+	select {
+	case <-a.brokersChan:
+	default:
+	}
+	a.brokersChan <- brokers
+}
+
+// SetOverflowPolicy can be called any time
+func (a *Adapter) SetOverflowPolicy(policy queue.Policy) {
+	a.responses.SetPolicy(policy)
+}
+
+// Status can be called from any goroutine
+func (a *Adapter) Status() any {
+	status := &Status{
+		ResponseQueue: a.responses.Len(),
+	}
+
+	a.statusMutex.Lock()
+	defer a.statusMutex.Unlock()
+	if a.client != nil {
+		status.Broker = a.brokers[a.current].Url
+		status.Connected = a.client.IsConnected()
+	}
+	if !a.lastAnnounce.IsZero() {
+		lastAnnounce := a.lastAnnounce
+		status.LastAnnounce = &lastAnnounce
+	}
+	if a.lastAnnounceErr != nil {
+		status.LastAnnounceError = a.lastAnnounceErr.Error()
+	}
+
+	return status
+}
+
+func (a *Adapter) QueueDepths() map[string]int {
+	return map[string]int{
+		"responses": a.responses.Len(),
+	}
+}
+
+func (a *Adapter) Healthy() bool {
+	a.statusMutex.Lock()
+	defer a.statusMutex.Unlock()
+
+	return (a.client != nil) && a.client.IsConnected()
+}
+
+// flush publishes queued responses without waiting for delivery
+func (a *Adapter) flush() {
+	for msg, ok := a.responses.Pop(); ok; msg, ok = a.responses.Pop() {
+		if a.client == nil {
+			continue
 		}
+		a.client.Publish(msg, 0)
+		metrics.MqttMessages.WithLabelValues("sent").Inc()
 	}
+}
 
-	return nil
+// push queues outgoing message, it never blocks so that a stalled broker
+// connection can't stall core
+func (a *Adapter) push(msg *message) {
+	result := a.responses.Push(0, msg)
+	if result.Queued && !result.Dropped {
+		return
+	}
+
+	policy := a.responses.Policy()
+	a.logger.Error().Msgf("response queue is full, overflow policy %s", policy)
+	metrics.Overflows.WithLabelValues("mqtt", "responses", policy.String()).Inc()
 }
 
-func (a *Adapter) Stop() {
-	a.stopChan <- true
+func (a *Adapter) announcePendingVessels() {
+	a.announceMutex.Lock()
+	pending := a.announcePending
+	a.announcePending = make(map[*Vessel]bool)
+	a.announceMutex.Unlock()
+
+	// in order of adding so that announces are predictable
+	for _, v := range a.vessels {
+		if pending[v] {
+			a.announce(v)
+		}
+	}
 }
 
-func (a *Adapter) SendResponse(resp []byte) {
-	a.responseChan <- resp
+// announce publishes the vessel's announce; failures of announces
+// of any vessel count towards failoverThreshold
+func (a *Adapter) announce(v *Vessel) {
+	a.logger.Debug().Msgf("announce %s", v.shipId)
+
+	var announceErr error
+	if a.client == nil {
+		announceErr = errors.New("not connected")
+		v.core.NetLoss()
+	} else {
+		// TODO: maybe report net loss only after N consecutive failed announce attempts?
+		err := a.client.Publish(&message{
+			topic:   a.announceTopic,
+			qos:     2,
+			payload: []byte(v.shipId),
+		}, a.announceTimeout)
+		if err != nil {
+			a.logger.Error().Err(err).Msgf("error publishing announce message of %s", v.shipId)
+			announceErr = err
+			v.core.NetLoss()
+		}
+	}
+
+	if announceErr != nil {
+		metrics.Announces.WithLabelValues("failure").Inc()
+	} else {
+		metrics.Announces.WithLabelValues("success").Inc()
+	}
+	a.statusMutex.Lock()
+	a.lastAnnounce = time.Now()
+	a.lastAnnounceErr = announceErr
+	a.statusMutex.Unlock()
+
+	if announceErr == nil {
+		a.announceFailures = 0
+		return
+	}
+
+	a.announceFailures++
+	if (a.client != nil) && (a.failoverThreshold > 0) &&
+		(a.announceFailures >= a.failoverThreshold) {
+		a.logger.Error().Msgf("%d consecutive announce failures on %s",
+			a.announceFailures, a.brokers[a.current].Url)
+		a.failover()
+	}
 }
 
-func (a *Adapter) Announce() {
+func (a *Adapter) replaceBrokers(brokers []*Broker) {
+	if len(brokers) == 0 {
+		a.logger.Error().Msg("no MQTT brokers configured, keeping current brokers")
+		return
+	}
+
+	a.logger.Info().Msg("MQTT brokers changed, reconnecting")
+	a.disconnect()
+	a.statusMutex.Lock()
+	a.brokers = brokers
+	a.current = -1
+	a.statusMutex.Unlock()
+
+	// a probe of the old brokers is outdated
+	a.probeGen++
+	a.probing = false
+	a.startProbe(a.preferred(len(a.brokers)))
+}
+
+// preferred returns indexes of brokers in order of preference
+// up to (not including) index limit
+func (a *Adapter) preferred(limit int) []int {
+	order := make([]int, 0, limit)
+	for i := 0; i < limit; i++ {
+		order = append(order, i)
+	}
+	return order
+}
+
+// failover switches to the next available broker, the current one is tried last
+func (a *Adapter) failover() {
+	failed := a.current
+	a.logger.Warn().Msgf("failing over from MQTT broker %s", a.brokers[failed].Url)
+	a.disconnect()
+
+	order := make([]int, 0, len(a.brokers))
+	for i := 1; i <= len(a.brokers); i++ {
+		order = append(order, (failed+i)%len(a.brokers))
+	}
+	if a.probing {
+		// the probe in progress connects to whatever it can,
+		// otherwise the reconnect ticker starts a new one
+		a.logger.Info().Msg("MQTT brokers are being probed already")
+		return
+	}
+	a.startProbe(order)
+}
+
+// startProbe connects to brokers in the given order in a separate goroutine,
+// the result is handled by handleProbe; only one probe runs at a time
+func (a *Adapter) startProbe(order []int) {
+	if a.probing {
+		return
+	}
+	a.probing = true
+
+	gen := a.probeGen
+	brokers := a.brokers
+	stopping := a.stopping
+	go func() {
+		p := a.probe(gen, brokers, order)
+		select {
+		case a.probeChan <- p:
+		case <-stopping:
+			if p.client != nil {
+				p.client.Disconnect(0)
+			}
+		}
+	}()
+}
+
+// probe connects to the first available broker in the given order,
+// it may be called from any goroutine
+func (a *Adapter) probe(gen int, brokers []*Broker, order []int) *probe {
+	for _, i := range order {
+		client, err := a.dial(brokers[i])
+		if err != nil {
+			a.logger.Error().Err(err).Msgf("failed to connect to MQTT broker %s", brokers[i].Url)
+			metrics.Errors.WithLabelValues("mqtt", "connect").Inc()
+			continue
+		}
+		return &probe{gen: gen, client: client, index: i}
+	}
+	return &probe{gen: gen, index: -1}
+}
+
+// handleProbe switches to the probed broker unless the brokers changed
+// meanwhile or the adapter is connected to a more preferred one
+func (a *Adapter) handleProbe(p *probe) {
+	if p.gen != a.probeGen {
+		if p.client != nil {
+			p.client.Disconnect(0)
+		}
+		return
+	}
+	a.probing = false
+
+	if p.client == nil {
+		a.logger.Error().Msg("no MQTT broker available")
+		return
+	}
+	if (a.client != nil) && (a.current <= p.index) {
+		p.client.Disconnect(0)
+		return
+	}
+	a.switchTo(p.client, p.index)
+}
+
+// switchTo replaces the current connection with client of broker idx
+func (a *Adapter) switchTo(client client, idx int) {
+	broker := a.brokers[idx]
+	reconnect := a.current >= 0
+	a.disconnect()
+
+	a.statusMutex.Lock()
+	a.client = client
+	a.current = idx
+	a.statusMutex.Unlock()
+	a.announceFailures = 0
+
+	a.logger.Info().Msgf("connected to MQTT broker %s", broker.Url)
+	if reconnect {
+		metrics.Reconnects.WithLabelValues("mqtt").Inc()
+	}
+	metrics.MqttBroker.WithLabelValues(broker.Url).Set(1)
+	a.publishStatus(broker)
+}
+
+func (a *Adapter) disconnect() {
+	if a.client == nil {
+		return
+	}
+
+	a.client.Disconnect(a.disconnectTimeout)
+	a.statusMutex.Lock()
+	a.client = nil
+	a.statusMutex.Unlock()
+	metrics.MqttBroker.Reset()
+}
+
+func (a *Adapter) publishStatus(broker *Broker) {
+	msg, err := json.Marshal(&statusMessage{
+		Type:   statusTypeBroker,
+		Broker: broker.Url,
+	})
+	if err != nil {
+		a.logger.Error().Err(err).Msg("failed to marshal status message")
+		return
+	}
+
+	for _, v := range a.vessels {
+		a.client.Publish(&message{
+			topic:    v.statusTopic,
+			qos:      1,
+			retained: true,
+			payload:  msg,
+		}, 0)
+	}
+}
+
+// requestTopics are subscribed to after connecting
+func (a *Adapter) requestTopics() []string {
+	topics := make([]string, 0, len(a.vessels))
+	for _, v := range a.vessels {
+		topics = append(topics, v.rqTopic)
+	}
+	return topics
+}
+
+func (a *Adapter) connect(broker *Broker) (client, error) {
+	switch a.protocolVersion {
+	case ProtocolV3:
+		return a.connectV3(broker)
+	case ProtocolV5:
+		return a.connectV5(broker)
+	default:
+		return nil, fmt.Errorf("unsupported MQTT protocol version %d", a.protocolVersion)
+	}
+}
+
+// connectionLost is called by clients from their own goroutines
+func (a *Adapter) connectionLost(cl client, broker *Broker, err error) {
+	a.logger.Error().Err(err).Msgf("connection to MQTT broker %s lost", broker.Url)
+	metrics.Errors.WithLabelValues("mqtt", "connection_lost").Inc()
 	select {
-	case a.announceChan <- true:
+	case a.connLostChan <- cl:
 	default:
-		a.logger.Error().Msg("announce channel is full, mqtt.Adapter.Announce() would block")
 	}
 }
 
-func (a *Adapter) connect() error {
-	opts := mqtt.NewClientOptions().AddBroker(a.broker).SetCleanSession(true)
-	opts.SetAutoReconnect(true)
-	opts.SetCredentialsProvider(func() (username string, password string) {
-		return a.username, a.passwd
-	})
-	opts.SetClientID(a.shipId)
-	tlsConfig := &tls.Config{
-		InsecureSkipVerify: !a.certCheck,
-	}
-	opts.SetTLSConfig(tlsConfig)
-	opts.SetOnConnectHandler(func(cl mqtt.Client) {
-		// subscribe to request topic
-		cl.Subscribe(a.rqTopic, 2, func(cl mqtt.Client, msg mqtt.Message) {
-			a.logger.Debug().Msgf("received request: %s", string(msg.Payload()))
-			a.core.HandleRequest(msg.Payload())
-		})
-	})
+// handleRequest is called by clients from their own goroutines
+func (a *Adapter) handleRequest(rq *request) {
+	a.logger.Debug().Msgf("received request on %s: %s", rq.topic, string(rq.payload))
+	metrics.MqttMessages.WithLabelValues("received").Inc()
 
-	a.client = mqtt.NewClient(opts)
-	token := a.client.Connect()
+	// vessels are only added before Run, no locking needed
+	v, ok := a.vesselsByTopic[rq.topic]
+	if !ok {
+		a.logger.Error().Msgf("request on unexpected topic %s", rq.topic)
+		metrics.Errors.WithLabelValues("mqtt", "unknown_topic").Inc()
+		return
+	}
 
-	if token.WaitTimeout(a.connTimeout) == false {
-		return errors.New("failed to connect to broker")
+	meta := &core.RequestMeta{
+		Operator: rq.operator,
+		Deadline: rq.deadline,
 	}
+	if rq.responseTopic == "" {
+		v.core.HandleRequestWithMeta(v, rq.payload, meta)
+		return
+	}
+
+	meta.Correlated = true
+	v.core.HandleRequestWithMeta(&responder{
+		adapter:         a,
+		topic:           rq.responseTopic,
+		correlationData: rq.correlationData,
+	}, rq.payload, meta)
+}
+
+// responder sends responses of an MQTT v5 request to the response topic
+// given in the request
+type responder struct {
+	adapter         *Adapter
+	topic           string
+	correlationData []byte
+}
+
+func (r *responder) Name() string {
+	return "mqtt " + r.topic
+}
+
+func (r *responder) SendResponse(resp []byte) {
+	r.adapter.push(&message{
+		topic:           r.topic,
+		qos:             2,
+		payload:         resp,
+		correlationData: r.correlationData,
+	})
+}
 
-	err := token.Error()
-	return err
+// Announce is published by the adapter itself
+func (r *responder) Announce() {
 }
@@ -0,0 +1,220 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/moosethebrown/ship-net-bridge/core"
+	"github.com/moosethebrown/ship-net-bridge/queue"
+	"github.com/rs/zerolog"
+)
+
+// fakeClient records published messages instead of talking to a broker
+type fakeClient struct {
+	broker       *Broker
+	mutex        sync.Mutex
+	published    []*message
+	publishErr   error
+	disconnected bool
+}
+
+func (c *fakeClient) Publish(msg *message, timeout time.Duration) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.publishErr != nil {
+		return c.publishErr
+	}
+	c.published = append(c.published, msg)
+	return nil
+}
+
+func (c *fakeClient) IsConnected() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return !c.disconnected
+}
+
+func (c *fakeClient) Disconnect(time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.disconnected = true
+}
+
+func (c *fakeClient) messages(topic string) []*message {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	msgs := make([]*message, 0)
+	for _, msg := range c.published {
+		if msg.topic == topic {
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs
+}
+
+// fakeBrokers hands out fake clients of brokers that are up
+type fakeBrokers struct {
+	mutex   sync.Mutex
+	down    map[string]bool
+	clients map[string][]*fakeClient
+}
+
+func (b *fakeBrokers) dial(broker *Broker) (client, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.down[broker.Url] {
+		return nil, errors.New("connection refused")
+	}
+	cl := &fakeClient{broker: broker}
+	b.clients[broker.Url] = append(b.clients[broker.Url], cl)
+	return cl, nil
+}
+
+func (b *fakeBrokers) setDown(url string, down bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.down[url] = down
+}
+
+// latest returns the last client connected to url
+func (b *fakeBrokers) latest(url string) *fakeClient {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	clients := b.clients[url]
+	if len(clients) == 0 {
+		return nil
+	}
+	return clients[len(clients)-1]
+}
+
+const (
+	primary   = "tcp://primary:1883"
+	secondary = "tcp://secondary:1883"
+)
+
+func setup(t *testing.T, failoverThreshold int) (*Adapter, *Vessel, *fakeBrokers) {
+	logger := zerolog.New(os.Stdout).With().Timestamp().Logger().Level(zerolog.DebugLevel)
+
+	a := NewAdapter([]*Broker{{Url: primary}, {Url: secondary}}, ProtocolV3,
+		0, "", time.Second, "TestBridge", "announce", time.Second, time.Second,
+		failoverThreshold, 20*time.Millisecond, 20*time.Millisecond, queue.Reject, &logger)
+	brokers := &fakeBrokers{
+		down:    make(map[string]bool),
+		clients: make(map[string][]*fakeClient),
+	}
+	a.dial = brokers.dial
+	theCore := core.NewCore(nil, nil, 3000, &logger)
+	v := a.AddVessel("TestShip", Topics{}, theCore)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- a.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Run failed: %s", err)
+		}
+	})
+
+	return a, v, brokers
+}
+
+// waitForBroker waits until the adapter is connected to url
+func waitForBroker(a *Adapter, url string) bool {
+	for i := 0; i < 100; i++ {
+		status := a.Status().(*Status)
+		if status.Connected && (status.Broker == url) {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func TestFailover(t *testing.T) {
+	a, _, brokers := setup(t, 0)
+
+	if !waitForBroker(a, primary) {
+		t.Fatalf("Expected to connect to the primary broker, got %+v", a.Status())
+	}
+
+	// the primary goes away and the secondary is promoted
+	brokers.setDown(primary, true)
+	lost := brokers.latest(primary)
+	lost.Disconnect(0)
+	a.connectionLost(lost, lost.broker, errors.New("connection reset"))
+	if !waitForBroker(a, secondary) {
+		t.Fatalf("Expected to fail over to the secondary broker, got %+v", a.Status())
+	}
+
+	// status of the vessel names the broker it's served over
+	statusMsgs := brokers.latest(secondary).messages("ship/TestShip/status")
+	if len(statusMsgs) != 1 {
+		t.Fatalf("Expected status message on the secondary broker, got %d", len(statusMsgs))
+	}
+	var status statusMessage
+	err := json.Unmarshal(statusMsgs[0].payload, &status)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal status message: %s", err)
+	}
+	if !statusMsgs[0].retained || (status.Broker != secondary) {
+		t.Errorf("Expected retained status message naming %s, got %+v", secondary, status)
+	}
+
+	// probes return to the primary as soon as it is back
+	brokers.setDown(primary, false)
+	if !waitForBroker(a, primary) {
+		t.Fatalf("Expected to return to the primary broker, got %+v", a.Status())
+	}
+	if brokers.latest(secondary).IsConnected() {
+		t.Errorf("Expected the secondary broker to be disconnected after returning to the primary")
+	}
+}
+
+func TestAnnounceFailover(t *testing.T) {
+	a, v, brokers := setup(t, 2)
+
+	if !waitForBroker(a, primary) {
+		t.Fatalf("Expected to connect to the primary broker, got %+v", a.Status())
+	}
+
+	// the primary accepts connections but publishing fails
+	cl := brokers.latest(primary)
+	cl.mutex.Lock()
+	cl.publishErr = errors.New("publish timeout")
+	cl.mutex.Unlock()
+	brokers.setDown(primary, true)
+
+	v.Announce()
+	time.Sleep(50 * time.Millisecond)
+	if status := a.Status().(*Status); status.Broker != primary {
+		t.Fatalf("Expected a single announce failure not to fail over, got %+v", status)
+	}
+	v.Announce()
+	if !waitForBroker(a, secondary) {
+		t.Fatalf("Expected to fail over after 2 announce failures, got %+v", a.Status())
+	}
+
+	v.Announce()
+	for i := 0; (i < 100) && (len(brokers.latest(secondary).messages("announce")) == 0); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	announces := brokers.latest(secondary).messages("announce")
+	if (len(announces) != 1) || (string(announces[0].payload) != "TestShip") {
+		t.Errorf("Expected TestShip to be announced on the secondary broker, got %d announces", len(announces))
+	}
+}
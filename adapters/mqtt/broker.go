@@ -0,0 +1,48 @@
+package mqtt
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// Broker is one of the brokers the adapter can connect to,
+// brokers are listed in order of preference
+type Broker struct {
+	Url       string
+	Username  string
+	Password  string
+	CertCheck bool
+	CaFile    string
+	CertFile  string
+	KeyFile   string
+}
+
+func (b *Broker) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: !b.CertCheck,
+	}
+
+	if b.CaFile != "" {
+		ca, err := os.ReadFile(b.CaFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in %s", b.CaFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if b.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(b.CertFile, b.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,37 @@
+package mqtt
+
+import "time"
+
+const (
+	ProtocolV3 = 3
+	ProtocolV5 = 5
+)
+
+type message struct {
+	topic    string
+	qos      byte
+	retained bool
+	payload  []byte
+	// MQTT v5 only
+	correlationData []byte
+}
+
+// client hides differences between MQTT 3.1.1 and MQTT 5 clients
+type client interface {
+	// Publish waits for the message to be delivered at most timeout,
+	// zero timeout means don't wait
+	Publish(msg *message, timeout time.Duration) error
+	IsConnected() bool
+	Disconnect(timeout time.Duration)
+}
+
+// request is a message received on the request topic
+type request struct {
+	topic   string
+	payload []byte
+	// MQTT v5 only
+	responseTopic   string
+	correlationData []byte
+	operator        string
+	deadline        time.Time
+}
@@ -0,0 +1,81 @@
+package mqtt
+
+import (
+	"errors"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+type clientV3 struct {
+	client mqtt.Client
+}
+
+func (a *Adapter) connectV3(broker *Broker) (client, error) {
+	tlsConfig, err := broker.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	cl := &clientV3{}
+
+	opts := mqtt.NewClientOptions().AddBroker(broker.Url).SetCleanSession(true)
+	// reconnection is handled by the adapter to be able to fail over
+	opts.SetAutoReconnect(false)
+	opts.SetConnectTimeout(a.connTimeout)
+	opts.SetCredentialsProvider(func() (username string, password string) {
+		return broker.Username, broker.Password
+	})
+	opts.SetClientID(a.clientId)
+	opts.SetTLSConfig(tlsConfig)
+	opts.SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+		a.connectionLost(cl, broker, err)
+	})
+	opts.SetOnConnectHandler(func(mc mqtt.Client) {
+		// subscribe to request topics of all vessels
+		filters := make(map[string]byte)
+		for _, topic := range a.requestTopics() {
+			filters[topic] = 2
+		}
+		mc.SubscribeMultiple(filters, func(_ mqtt.Client, msg mqtt.Message) {
+			a.handleRequest(&request{
+				topic:   msg.Topic(),
+				payload: msg.Payload(),
+			})
+		})
+	})
+
+	cl.client = mqtt.NewClient(opts)
+	token := cl.client.Connect()
+
+	if token.WaitTimeout(a.connTimeout) == false {
+		return nil, errors.New("failed to connect to broker")
+	}
+
+	err = token.Error()
+	if err != nil {
+		return nil, err
+	}
+
+	return cl, nil
+}
+
+func (c *clientV3) Publish(msg *message, timeout time.Duration) error {
+	token := c.client.Publish(msg.topic, msg.qos, msg.retained, msg.payload)
+	if timeout == 0 {
+		return nil
+	}
+
+	if token.WaitTimeout(timeout) == false {
+		return errors.New("publish timeout")
+	}
+	return token.Error()
+}
+
+func (c *clientV3) IsConnected() bool {
+	return c.client.IsConnected()
+}
+
+func (c *clientV3) Disconnect(timeout time.Duration) {
+	c.client.Disconnect(uint(timeout.Milliseconds()))
+}
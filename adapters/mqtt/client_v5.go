@@ -0,0 +1,233 @@
+package mqtt
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/eclipse/paho.golang/packets"
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/moosethebrown/ship-net-bridge/metrics"
+	"github.com/moosethebrown/ship-net-bridge/queue"
+)
+
+const (
+	keepAlive = 30
+	// publishQueueSize bounds messages published without waiting
+	// that are not acknowledged yet
+	publishQueueSize = 100
+)
+
+// clientV5 publishes messages that aren't waited for in order
+// from a single goroutine, paho.Client.Publish blocks until the message
+// is acknowledged
+type clientV5 struct {
+	client        *paho.Client
+	conn          net.Conn
+	messageExpiry uint32
+	connected     atomic.Bool
+	pending       chan *paho.Publish
+	ctx           context.Context
+	cancel        context.CancelFunc
+}
+
+func dialV5(broker *Broker, timeout time.Duration) (net.Conn, error) {
+	u, err := url.Parse(broker.Url)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	switch u.Scheme {
+	case "tcp", "mqtt":
+		return dialer.Dial("tcp", u.Host)
+	case "ssl", "tls", "mqtts":
+		tlsConfig, err := broker.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName = u.Hostname()
+		}
+		return tls.DialWithDialer(dialer, "tcp", u.Host, tlsConfig)
+	default:
+		return nil, fmt.Errorf("unsupported MQTT v5 broker scheme: %s", u.Scheme)
+	}
+}
+
+func (a *Adapter) connectV5(broker *Broker) (client, error) {
+	conn, err := dialV5(broker, a.connTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	cl := &clientV5{
+		// message expiry is in seconds, rounded up so that
+		// short expiries don't turn into no expiry at all
+		messageExpiry: uint32((a.messageExpiry + time.Second - 1) / time.Second),
+		pending:       make(chan *paho.Publish, publishQueueSize),
+		conn:          conn,
+	}
+	cl.ctx, cl.cancel = context.WithCancel(context.Background())
+	cl.client = paho.NewClient(paho.ClientConfig{
+		ClientID: a.clientId,
+		// tls.Conn is not safe for concurrent writes
+		Conn: packets.NewThreadSafeConn(conn),
+		OnPublishReceived: []func(paho.PublishReceived) (bool, error){
+			func(pr paho.PublishReceived) (bool, error) {
+				a.handleRequest(requestV5(pr.Packet, a.operatorProperty))
+				return true, nil
+			},
+		},
+		OnClientError: func(err error) {
+			if cl.connected.Swap(false) {
+				a.connectionLost(cl, broker, err)
+			}
+		},
+		OnServerDisconnect: func(d *paho.Disconnect) {
+			if cl.connected.Swap(false) {
+				a.connectionLost(cl, broker,
+					fmt.Errorf("disconnected by broker, reason code %d", d.ReasonCode))
+			}
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.connTimeout)
+	defer cancel()
+
+	connect := &paho.Connect{
+		ClientID:   a.clientId,
+		KeepAlive:  keepAlive,
+		CleanStart: true,
+	}
+	if broker.Username != "" {
+		connect.Username = broker.Username
+		connect.UsernameFlag = true
+	}
+	if broker.Password != "" {
+		connect.Password = []byte(broker.Password)
+		connect.PasswordFlag = true
+	}
+
+	_, err = cl.client.Connect(ctx, connect)
+	if err != nil {
+		cl.cancel()
+		conn.Close()
+		return nil, err
+	}
+	cl.connected.Store(true)
+	go cl.publishPending()
+
+	subscriptions := make([]paho.SubscribeOptions, 0)
+	for _, topic := range a.requestTopics() {
+		subscriptions = append(subscriptions, paho.SubscribeOptions{Topic: topic, QoS: 2})
+	}
+	_, err = cl.client.Subscribe(ctx, &paho.Subscribe{
+		Subscriptions: subscriptions,
+	})
+	if err != nil {
+		cl.Disconnect(0)
+		return nil, err
+	}
+
+	return cl, nil
+}
+
+func requestV5(p *paho.Publish, operatorProperty string) *request {
+	rq := &request{
+		topic:   p.Topic,
+		payload: p.Payload,
+	}
+
+	if p.Properties != nil {
+		rq.responseTopic = p.Properties.ResponseTopic
+		rq.correlationData = p.Properties.CorrelationData
+		if operatorProperty != "" {
+			rq.operator = p.Properties.User.Get(operatorProperty)
+		}
+		if p.Properties.MessageExpiry != nil {
+			// the broker sends the remaining lifetime of the message
+			rq.deadline = time.Now().Add(time.Duration(*p.Properties.MessageExpiry) * time.Second)
+		}
+	}
+
+	return rq
+}
+
+func (c *clientV5) Publish(msg *message, timeout time.Duration) error {
+	p := &paho.Publish{
+		Topic:   msg.topic,
+		QoS:     msg.qos,
+		Retain:  msg.retained,
+		Payload: msg.payload,
+		Properties: &paho.PublishProperties{
+			CorrelationData: msg.correlationData,
+		},
+	}
+	if c.messageExpiry > 0 {
+		expiry := c.messageExpiry
+		p.Properties.MessageExpiry = &expiry
+	}
+
+	if timeout == 0 {
+		select {
+		case c.pending <- p:
+			return nil
+		default:
+			metrics.Overflows.WithLabelValues("mqtt", "publish", queue.DropNewest.String()).Inc()
+			return errors.New("too many messages waiting for acknowledgement")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	_, err := c.client.Publish(ctx, p)
+	return err
+}
+
+// publishPending returns when the client is disconnected
+func (c *clientV5) publishPending() {
+	for {
+		select {
+		case p := <-c.pending:
+			_, err := c.client.Publish(c.ctx, p)
+			if err != nil {
+				metrics.Errors.WithLabelValues("mqtt", "publish").Inc()
+			}
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *clientV5) IsConnected() bool {
+	return c.connected.Load()
+}
+
+// Disconnect waits at most timeout for the disconnect packet to be sent,
+// then closes the connection so that a stalled broker can't block failover
+func (c *clientV5) Disconnect(timeout time.Duration) {
+	c.connected.Store(false)
+	c.cancel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		c.client.Disconnect(&paho.Disconnect{ReasonCode: 0})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		// fails the pending write, paho then shuts down on its own
+		c.conn.Close()
+	}
+}
@@ -0,0 +1,232 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/eclipse/paho.golang/packets"
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/moosethebrown/ship-net-bridge/core"
+	"github.com/moosethebrown/ship-net-bridge/queue"
+	"github.com/rs/zerolog"
+)
+
+// fakeBrokerV5 accepts a single MQTT v5 client, acknowledges its packets and
+// passes on subscribed topics and published messages
+type fakeBrokerV5 struct {
+	conn       net.Conn
+	subscribed chan string
+	published  chan *packets.Publish
+}
+
+func (b *fakeBrokerV5) serve() {
+	defer close(b.published)
+	for {
+		cp, err := packets.ReadPacket(b.conn)
+		if err != nil {
+			return
+		}
+		switch p := cp.Content.(type) {
+		case *packets.Connect:
+			(&packets.Connack{ReasonCode: packets.ConnackSuccess}).WriteTo(b.conn)
+		case *packets.Subscribe:
+			reasons := make([]byte, 0)
+			for _, s := range p.Subscriptions {
+				reasons = append(reasons, s.QoS)
+				b.subscribed <- s.Topic
+			}
+			(&packets.Suback{PacketID: p.PacketID, Reasons: reasons}).WriteTo(b.conn)
+		case *packets.Publish:
+			switch p.QoS {
+			case 1:
+				(&packets.Puback{PacketID: p.PacketID}).WriteTo(b.conn)
+			case 2:
+				(&packets.Pubrec{PacketID: p.PacketID}).WriteTo(b.conn)
+			}
+			b.published <- p
+		case *packets.Pubrel:
+			(&packets.Pubcomp{PacketID: p.PacketID}).WriteTo(b.conn)
+		case *packets.Disconnect:
+			return
+		}
+	}
+}
+
+func listenBrokerV5(t *testing.T) (string, chan *fakeBrokerV5) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen on tcp: %s", err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	brokers := make(chan *fakeBrokerV5, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		b := &fakeBrokerV5{
+			conn:       conn,
+			subscribed: make(chan string, 10),
+			published:  make(chan *packets.Publish, 10),
+		}
+		brokers <- b
+		b.serve()
+	}()
+	return "tcp://" + l.Addr().String(), brokers
+}
+
+func TestRequestResponseV5(t *testing.T) {
+	logger := zerolog.New(os.Stdout).With().Timestamp().Logger().Level(zerolog.DebugLevel)
+	url, brokers := listenBrokerV5(t)
+
+	a := NewAdapter([]*Broker{{Url: url}}, ProtocolV5, 30*time.Second, "operator",
+		time.Second, "TestBridge", "announce", time.Second, time.Second,
+		0, time.Minute, time.Minute, queue.Reject, &logger)
+	theCore := core.NewCore(nil, nil, 3000, &logger)
+	a.AddVessel("TestShip", Topics{}, theCore)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go theCore.Run(ctx)
+	done := make(chan error, 1)
+	go func() {
+		done <- a.Run(ctx)
+	}()
+
+	var b *fakeBrokerV5
+	select {
+	case b = <-brokers:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Expected the adapter to connect")
+	}
+	defer b.conn.Close()
+	if topic := <-b.subscribed; topic != "ship/TestShip/request" {
+		t.Fatalf("Expected subscription to the request topic, got %s", topic)
+	}
+
+	expiry := uint32(30)
+	(&packets.Publish{
+		Topic:   "ship/TestShip/request",
+		Payload: []byte(`{"type":"cmd","cmd":"export_track","data":"gpx"}`),
+		Properties: &packets.Properties{
+			ResponseTopic:   "station/1/response",
+			CorrelationData: []byte("rq-42"),
+			MessageExpiry:   &expiry,
+			User:            []packets.User{{Key: "operator", Value: "alice"}},
+		},
+	}).WriteTo(b.conn)
+
+	timeout := time.After(2 * time.Second)
+	for {
+		var p *packets.Publish
+		select {
+		case p = <-b.published:
+		case <-timeout:
+			t.Fatalf("Expected a response on the response topic")
+		}
+		if p.Topic != "station/1/response" {
+			// e.g. the vessel's status message
+			continue
+		}
+
+		if (p.Properties == nil) || (string(p.Properties.CorrelationData) != "rq-42") {
+			t.Errorf("Expected the request's correlation data, got %+v", p.Properties)
+		}
+		if (p.Properties == nil) || (p.Properties.MessageExpiry == nil) ||
+			(*p.Properties.MessageExpiry != 30) {
+			t.Errorf("Expected message expiry of 30s, got %+v", p.Properties)
+		}
+		var resp core.Response
+		err := json.Unmarshal(p.Payload, &resp)
+		if err != nil {
+			t.Fatalf("Failed to unmarshal response: %s", err)
+		}
+		if (resp.Type != core.ResponseTypeError) || (resp.Cmd != core.CmdExportTrack) {
+			t.Errorf("Expected export_track error response, got %+v", resp)
+		}
+		break
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run failed: %s", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("Expected Run to return after disconnecting")
+	}
+}
+
+func TestRequestV5(t *testing.T) {
+	expiry := uint32(10)
+	rq := requestV5(&paho.Publish{
+		Topic:   "ship/TestShip/request",
+		Payload: []byte(`{"type":"query"}`),
+		Properties: &paho.PublishProperties{
+			ResponseTopic:   "station/1/response",
+			CorrelationData: []byte("rq-1"),
+			MessageExpiry:   &expiry,
+			User:            paho.UserProperties{{Key: "operator", Value: "alice"}},
+		},
+	}, "operator")
+
+	if (rq.responseTopic != "station/1/response") || (string(rq.correlationData) != "rq-1") {
+		t.Errorf("Expected response topic and correlation data of the request, got %+v", rq)
+	}
+	if rq.operator != "alice" {
+		t.Errorf("Expected operator alice, got %s", rq.operator)
+	}
+	if remaining := time.Until(rq.deadline); (remaining <= 9*time.Second) || (remaining > 10*time.Second) {
+		t.Errorf("Expected deadline in 10s, got %s", remaining)
+	}
+
+	rq = requestV5(&paho.Publish{Topic: "ship/TestShip/request"}, "operator")
+	if (rq.responseTopic != "") || (rq.operator != "") || !rq.deadline.IsZero() {
+		t.Errorf("Expected request without properties to have no response topic, operator or deadline, got %+v", rq)
+	}
+}
+
+func TestDisconnectTimeoutV5(t *testing.T) {
+	conn, brokerConn := net.Pipe()
+	defer brokerConn.Close()
+
+	cl := &clientV5{
+		conn:    conn,
+		pending: make(chan *paho.Publish, publishQueueSize),
+		client:  paho.NewClient(paho.ClientConfig{Conn: conn}),
+	}
+	cl.ctx, cl.cancel = context.WithCancel(context.Background())
+
+	go func() {
+		// answer CONNECT, then stall like an unresponsive broker
+		packets.ReadPacket(brokerConn)
+		(&packets.Connack{ReasonCode: packets.ConnackSuccess}).WriteTo(brokerConn)
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err := cl.client.Connect(ctx, &paho.Connect{ClientID: "TestBridge", CleanStart: true})
+	if err != nil {
+		t.Fatalf("Failed to connect: %s", err)
+	}
+
+	start := time.Now()
+	disconnected := make(chan struct{})
+	go func() {
+		cl.Disconnect(50 * time.Millisecond)
+		close(disconnected)
+	}()
+	select {
+	case <-disconnected:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Expected Disconnect to return after its timeout")
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Expected Disconnect to wait for the broker up to its timeout, returned after %s", elapsed)
+	}
+}
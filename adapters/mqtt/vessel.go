@@ -0,0 +1,93 @@
+package mqtt
+
+import (
+	"github.com/moosethebrown/ship-net-bridge/address"
+	"github.com/moosethebrown/ship-net-bridge/core"
+)
+
+// Topics a vessel uses, empty topics default to ship/<shipId>/request,
+// ship/<shipId>/response and ship/<shipId>/status
+type Topics struct {
+	Request  string
+	Response string
+	Status   string
+}
+
+// Resolve returns topics with empty ones replaced by the defaults of shipId
+func (t Topics) Resolve(shipId string) Topics {
+	if t.Request == "" {
+		t.Request = address.Topic(shipId, "request")
+	}
+	if t.Response == "" {
+		t.Response = address.Topic(shipId, "response")
+	}
+	if t.Status == "" {
+		t.Status = address.Topic(shipId, "status")
+	}
+	return t
+}
+
+// Vessel is the transport of one ship's core over the adapter's
+// shared broker connection
+type Vessel struct {
+	adapter     *Adapter
+	shipId      string
+	core        *core.Core
+	rqTopic     string
+	respTopic   string
+	statusTopic string
+}
+
+// AddVessel registers a ship served over the connection,
+// must be called before Run
+func (a *Adapter) AddVessel(shipId string, topics Topics, core *core.Core) *Vessel {
+	topics = topics.Resolve(shipId)
+	v := &Vessel{
+		adapter:     a,
+		shipId:      shipId,
+		core:        core,
+		rqTopic:     topics.Request,
+		respTopic:   topics.Response,
+		statusTopic: topics.Status,
+	}
+
+	a.vessels = append(a.vessels, v)
+	a.vesselsByTopic[v.rqTopic] = v
+	return v
+}
+
+func (v *Vessel) Name() string {
+	return "mqtt"
+}
+
+func (v *Vessel) SendResponse(resp []byte) {
+	v.adapter.push(&message{
+		topic:   v.respTopic,
+		qos:     2,
+		payload: resp,
+	})
+}
+
+// Publish queues a message to another topic of the vessel, e.g. a data
+// stream; it's sent at most once and never blocks
+func (v *Vessel) Publish(topic string, payload []byte) {
+	v.adapter.push(&message{
+		topic:   topic,
+		qos:     0,
+		payload: payload,
+	})
+}
+
+// Announce is published by the adapter's goroutine, can be called any time
+func (v *Vessel) Announce() {
+	v.adapter.announceMutex.Lock()
+	v.adapter.announcePending[v] = true
+	v.adapter.announceMutex.Unlock()
+
+	select {
+	case v.adapter.announceChan <- true:
+	default:
+		// the adapter has yet to handle the previous signal,
+		// which covers this announce too
+	}
+}
@@ -0,0 +1,280 @@
+package nmea
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moosethebrown/ship-net-bridge/core"
+)
+
+// staleAfter is how long course, speed and heading are reported
+// after the last sentence carrying them
+const staleAfter = 5 * time.Second
+
+// fixState merges sentences of a receiver into a fix: GGA and RMC give
+// position, RMC and VTG course and speed, HDT and HDG heading
+type fixState struct {
+	fix       core.Fix
+	course    float64
+	speed     float64
+	heading   float64
+	courseAt  time.Time
+	speedAt   time.Time
+	headingAt time.Time
+	// variation is magnetic variation from RMC, east positive,
+	// for HDG sentences that don't carry it
+	variation *float64
+	// updated is set when a position is received and cleared by take
+	updated bool
+}
+
+// sentenceTypes are the sentence types used by fixState
+var sentenceTypes = []string{"GGA", "RMC", "VTG", "HDT", "HDG"}
+
+// sentenceLabel returns the metric label of a sentence type, types from
+// the wire are not bounded so unsupported ones are counted as other
+func sentenceLabel(sentenceType string) string {
+	if slices.Contains(sentenceTypes, sentenceType) {
+		return sentenceType
+	}
+	return "other"
+}
+
+// update applies a sentence received at now; sentences without a valid fix
+// and unsupported sentence types are ignored
+func (f *fixState) update(s *Sentence, now time.Time) error {
+	switch s.Type {
+	case "GGA":
+		return f.updateGGA(s, now)
+	case "RMC":
+		return f.updateRMC(s, now)
+	case "VTG":
+		return f.updateVTG(s, now)
+	case "HDT":
+		heading, err := parseFloat(s.Field(0))
+		if (err != nil) || (heading == nil) {
+			return err
+		}
+		f.setHeading(*heading, now)
+	case "HDG":
+		return f.updateHDG(s, now)
+	}
+	return nil
+}
+
+// take returns the fix if a position has been received since
+// the previous call, nil otherwise
+func (f *fixState) take(now time.Time) *core.Fix {
+	if !f.updated {
+		return nil
+	}
+	f.updated = false
+
+	fix := f.fix
+	if now.Sub(f.courseAt) < staleAfter {
+		course := f.course
+		fix.Course = &course
+	}
+	if now.Sub(f.speedAt) < staleAfter {
+		speed := f.speed
+		fix.Speed = &speed
+	}
+	if now.Sub(f.headingAt) < staleAfter {
+		heading := f.heading
+		fix.Heading = &heading
+	}
+	return &fix
+}
+
+// GGA: time, latitude, N/S, longitude, E/W, quality, satellites, HDOP,
+// altitude, M, ...
+func (f *fixState) updateGGA(s *Sentence, now time.Time) error {
+	quality, err := strconv.Atoi(s.Field(5))
+	if err != nil {
+		return fmt.Errorf("malformed GGA fix quality %q", s.Field(5))
+	}
+	if quality == 0 {
+		// no fix
+		return nil
+	}
+	err = f.setPosition(s, 1, now)
+	if err != nil {
+		return err
+	}
+
+	f.fix.Quality = quality
+	f.fix.Satellites, _ = strconv.Atoi(s.Field(6))
+	f.fix.Hdop, _ = strconv.ParseFloat(s.Field(7), 64)
+	f.fix.Altitude, _ = strconv.ParseFloat(s.Field(8), 64)
+	return nil
+}
+
+// RMC: time, status, latitude, N/S, longitude, E/W, speed (knots), course,
+// date, variation, E/W, mode (NMEA 2.3)
+func (f *fixState) updateRMC(s *Sentence, now time.Time) error {
+	if (s.Field(1) != "A") || (s.Field(11) == "N") {
+		// no fix
+		return nil
+	}
+	err := f.setPosition(s, 2, now)
+	if err != nil {
+		return err
+	}
+	err = f.setCourseAndSpeed(s.Field(7), s.Field(6), now)
+	if err != nil {
+		return err
+	}
+
+	variation, err := parseFloat(s.Field(9))
+	if (err == nil) && (variation != nil) {
+		if s.Field(10) == "W" {
+			*variation = -*variation
+		}
+		f.variation = variation
+	}
+	return nil
+}
+
+// VTG: course (true), T, course (magnetic), M, speed (knots), N,
+// speed (km/h), K, mode (NMEA 2.3)
+func (f *fixState) updateVTG(s *Sentence, now time.Time) error {
+	if s.Field(8) == "N" {
+		// no fix
+		return nil
+	}
+	return f.setCourseAndSpeed(s.Field(0), s.Field(4), now)
+}
+
+// HDG: magnetic sensor heading, deviation, E/W, variation, E/W
+func (f *fixState) updateHDG(s *Sentence, now time.Time) error {
+	heading, err := parseFloat(s.Field(0))
+	if (err != nil) || (heading == nil) {
+		return err
+	}
+	deviation, err := parseFloat(s.Field(1))
+	if err != nil {
+		return err
+	}
+	variation, err := parseFloat(s.Field(3))
+	if err != nil {
+		return err
+	}
+
+	trueHeading := *heading
+	if deviation != nil {
+		trueHeading += signed(*deviation, s.Field(2))
+	}
+	switch {
+	case variation != nil:
+		trueHeading += signed(*variation, s.Field(4))
+	case f.variation != nil:
+		trueHeading += *f.variation
+	default:
+		// true heading can't be told without variation
+		return nil
+	}
+	f.setHeading(trueHeading, now)
+	return nil
+}
+
+// setPosition parses latitude, N/S, longitude, E/W fields starting at field i
+func (f *fixState) setPosition(s *Sentence, i int, now time.Time) error {
+	latitude, err := parseCoordinate(s.Field(i), s.Field(i+1), "N", "S", 90)
+	if err != nil {
+		return err
+	}
+	longitude, err := parseCoordinate(s.Field(i+2), s.Field(i+3), "E", "W", 180)
+	if err != nil {
+		return err
+	}
+
+	f.fix.Latitude = latitude
+	f.fix.Longitude = longitude
+	f.fix.Timestamp = now
+	f.updated = true
+	return nil
+}
+
+func (f *fixState) setCourseAndSpeed(courseField string, speedField string, now time.Time) error {
+	course, err := parseFloat(courseField)
+	if err != nil {
+		return err
+	}
+	speed, err := parseFloat(speedField)
+	if err != nil {
+		return err
+	}
+
+	// course is empty when not moving
+	if course != nil {
+		f.course = *course
+		f.courseAt = now
+	}
+	if speed != nil {
+		f.speed = *speed
+		f.speedAt = now
+	}
+	return nil
+}
+
+func (f *fixState) setHeading(heading float64, now time.Time) {
+	f.heading = math.Mod(heading+360, 360)
+	f.headingAt = now
+}
+
+// parseCoordinate parses [d]ddmm.mmmm with its hemisphere into degrees,
+// negative for the south or west; coordinates over limit degrees are rejected
+func parseCoordinate(value string, hemisphere string, positive string, negative string,
+	limit float64) (float64, error) {
+	dot := strings.IndexByte(value, '.')
+	if dot < 0 {
+		dot = len(value)
+	}
+	if dot < 3 {
+		return 0, fmt.Errorf("malformed coordinate %q", value)
+	}
+	degrees, err := strconv.ParseUint(value[:dot-2], 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("malformed coordinate %q", value)
+	}
+	minutes, err := strconv.ParseFloat(value[dot-2:], 64)
+	if (err != nil) || (minutes >= 60) {
+		return 0, fmt.Errorf("malformed coordinate %q", value)
+	}
+
+	coordinate := float64(degrees) + minutes/60
+	if coordinate > limit {
+		return 0, fmt.Errorf("coordinate %q out of range", value)
+	}
+	switch hemisphere {
+	case positive:
+		return coordinate, nil
+	case negative:
+		return -coordinate, nil
+	}
+	return 0, fmt.Errorf("malformed hemisphere %q", hemisphere)
+}
+
+// parseFloat returns nil for empty fields
+func parseFloat(value string) (*float64, error) {
+	if value == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("malformed number %q", value)
+	}
+	return &f, nil
+}
+
+// signed applies E/W direction, west is negative
+func signed(value float64, direction string) float64 {
+	if direction == "W" {
+		return -value
+	}
+	return value
+}
@@ -0,0 +1,209 @@
+package nmea
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/moosethebrown/ship-net-bridge/core"
+	"github.com/rs/zerolog"
+)
+
+// sentence adds checksum and line break to body, e.g. GPGGA,...
+func sentence(body string) string {
+	return fmt.Sprintf("$%s*%02X\r\n", body, checksum(body))
+}
+
+func near(a float64, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestParse(t *testing.T) {
+	s, err := Parse("$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47\r\n")
+	if err != nil {
+		t.Fatalf("Failed to parse sentence: %s", err)
+	}
+	if (s.Talker != "GP") || (s.Type != "GGA") || (s.Field(1) != "4807.038") || (s.Field(20) != "") {
+		t.Errorf("Unexpected sentence %+v", s)
+	}
+
+	for _, line := range []string{
+		"$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*48",
+		"$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,",
+		"GPGGA,123519*47",
+		"$GPGGA,123519*4",
+	} {
+		if _, err := Parse(line); err == nil {
+			t.Errorf("Expected %q to be rejected", line)
+		}
+	}
+	if _, err := Parse("$GPHDT,87.5,T*00"); err != ErrChecksum {
+		t.Errorf("Expected checksum mismatch, got %v", err)
+	}
+}
+
+func TestSentenceLabel(t *testing.T) {
+	for sentenceType, expected := range map[string]string{
+		"GGA":   "GGA",
+		"HDG":   "HDG",
+		"GSV":   "other",
+		"X1234": "other",
+	} {
+		if label := sentenceLabel(sentenceType); label != expected {
+			t.Errorf("Expected %s label for %s, got %s", expected, sentenceType, label)
+		}
+	}
+}
+
+func TestFix(t *testing.T) {
+	state := &fixState{}
+	now := time.Now()
+	for _, body := range []string{
+		"GNRMC,123519,A,4807.038,N,01131.000,E,5.5,84.4,230394,3.1,W,A",
+		"GNGGA,123519,4807.038,N,01131.000,E,2,08,0.9,545.4,M,46.9,M,,",
+		"HCHDG,90.0,,,,",
+		"GPGGA,123520,,,,,0,00,,,M,,M,,",
+		"GPRMC,123520,V,,,,,,,230394,,,N",
+	} {
+		s, err := Parse(sentence(body))
+		if err == nil {
+			err = state.update(s, now)
+		}
+		if err != nil {
+			t.Fatalf("Failed to handle %s: %s", body, err)
+		}
+	}
+
+	fix := state.take(now)
+	if fix == nil {
+		t.Fatalf("Expected a fix")
+	}
+	if !near(fix.Latitude, 48.1173) || !near(fix.Longitude, 11.516666667) {
+		t.Errorf("Unexpected position %f %f", fix.Latitude, fix.Longitude)
+	}
+	if (fix.Quality != 2) || (fix.Satellites != 8) || (fix.Altitude != 545.4) {
+		t.Errorf("Unexpected GGA data %+v", fix)
+	}
+	if (fix.Speed == nil) || (*fix.Speed != 5.5) || (fix.Course == nil) || (*fix.Course != 84.4) {
+		t.Errorf("Unexpected course and speed %v %v", fix.Course, fix.Speed)
+	}
+	// magnetic heading corrected by variation from RMC
+	if (fix.Heading == nil) || !near(*fix.Heading, 86.9) {
+		t.Errorf("Unexpected heading %v", fix.Heading)
+	}
+	if state.take(now) != nil {
+		t.Errorf("Expected no fix without a new position")
+	}
+
+	// course, speed and heading go stale, position is taken as is
+	s, _ := Parse(sentence("GPGGA,123525,3345.500,S,15112.250,W,1,05,1.2,3.0,M,,M,,"))
+	state.update(s, now.Add(staleAfter))
+	fix = state.take(now.Add(staleAfter))
+	if (fix == nil) || !near(fix.Latitude, -33.758333333) || !near(fix.Longitude, -151.204166667) {
+		t.Fatalf("Unexpected fix %+v", fix)
+	}
+	if (fix.Course != nil) || (fix.Speed != nil) || (fix.Heading != nil) {
+		t.Errorf("Expected stale course, speed and heading to be left out, got %+v", fix)
+	}
+
+	s, _ = Parse(sentence("GPGGA,123530,48x7.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,"))
+	if state.update(s, now) == nil {
+		t.Errorf("Expected malformed latitude to be rejected")
+	}
+}
+
+func TestParseCoordinate(t *testing.T) {
+	for _, tc := range []struct {
+		value      string
+		hemisphere string
+		limit      float64
+		expected   float64
+		valid      bool
+	}{
+		{"4807.038", "N", 90, 48.1173, true},
+		{"3345.500", "S", 90, -33.758333333, true},
+		{"9000.000", "N", 90, 90, true},
+		{"9000.001", "N", 90, 0, false},
+		{"9530.000", "S", 90, 0, false},
+		{"01131.000", "E", 180, 11.516666667, true},
+		{"18000.000", "W", 180, -180, true},
+		{"18000.500", "E", 180, 0, false},
+		{"25500.000", "W", 180, 0, false},
+		{"4860.000", "N", 90, 0, false},
+		{"4807.038", "E", 90, 0, false},
+	} {
+		positive, negative := "N", "S"
+		if tc.limit == 180 {
+			positive, negative = "E", "W"
+		}
+		coordinate, err := parseCoordinate(tc.value, tc.hemisphere, positive, negative, tc.limit)
+		if !tc.valid {
+			if err == nil {
+				t.Errorf("Expected %s %s to be rejected, got %f", tc.value, tc.hemisphere, coordinate)
+			}
+			continue
+		}
+		if (err != nil) || !near(coordinate, tc.expected) {
+			t.Errorf("Expected %s %s to be %f, got %f %v", tc.value, tc.hemisphere, tc.expected, coordinate, err)
+		}
+	}
+}
+
+func TestInput(t *testing.T) {
+	logger := zerolog.New(os.Stdout).Level(zerolog.DebugLevel)
+	theCore := core.NewCore(nil, nil, 3000, &logger)
+
+	// find a free port
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen on udp: %s", err)
+	}
+	address := conn.LocalAddr().String()
+	conn.Close()
+
+	input := NewInput("udp://"+address, 0, 10*time.Millisecond, theCore, &logger)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go theCore.Run(ctx)
+	done := make(chan error)
+	go func() {
+		done <- input.Run(ctx)
+	}()
+
+	sender, err := net.Dial("udp", address)
+	if err != nil {
+		t.Fatalf("Failed to dial %s: %s", address, err)
+	}
+	defer sender.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for (theCore.LastFix() == nil) && time.Now().Before(deadline) {
+		// one datagram carries several sentences, the last one
+		// without line break and a corrupted one
+		sender.Write([]byte(sentence("GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,") +
+			"$GPHDT,87.5,T*00\r\n" +
+			sentence("GPHDT,87.5,T")[:16]))
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	fix := theCore.LastFix()
+	if (fix == nil) || !near(fix.Latitude, 48.1173) {
+		t.Fatalf("Expected fix from UDP, got %+v", fix)
+	}
+	if (fix.Heading == nil) || (*fix.Heading != 87.5) {
+		t.Errorf("Expected heading from UDP, got %v", fix.Heading)
+	}
+	status := input.Status().(*InputStatus)
+	if !status.Connected || (status.Errors == 0) {
+		t.Errorf("Expected connected input with checksum errors, got %+v", status)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Expected input to stop cleanly, got %s", err)
+	}
+}
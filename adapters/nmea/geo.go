@@ -0,0 +1,54 @@
+package nmea
+
+import (
+	"math"
+
+	"github.com/moosethebrown/ship-net-bridge/core"
+)
+
+const (
+	earthRadius   = 6371000.0
+	metersPerMile = 1852.0
+	// knotsPerMps converts meters per second to knots
+	knotsPerMps = 3600 / metersPerMile
+)
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+// distance returns great-circle distance between points in meters
+func distance(lat1 float64, lon1 float64, lat2 float64, lon2 float64) float64 {
+	dLat := radians(lat2 - lat1)
+	dLon := radians(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(radians(lat1))*math.Cos(radians(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// bearing returns initial true bearing from the first point to the second,
+// degrees
+func bearing(lat1 float64, lon1 float64, lat2 float64, lon2 float64) float64 {
+	dLon := radians(lon2 - lon1)
+	y := math.Sin(dLon) * math.Cos(radians(lat2))
+	x := math.Cos(radians(lat1))*math.Sin(radians(lat2)) -
+		math.Sin(radians(lat1))*math.Cos(radians(lat2))*math.Cos(dLon)
+	return math.Mod(math.Atan2(y, x)*180/math.Pi+360, 360)
+}
+
+// crossTrack returns distance of position from the leg between waypoints
+// in meters, positive when position is right of the leg; legs of drones are
+// short enough for a local flat projection
+func crossTrack(from core.Waypoint, to core.Waypoint, lat float64, lon float64) float64 {
+	scale := math.Cos(radians(from.Latitude))
+	legX := radians(to.Longitude-from.Longitude) * scale
+	legY := radians(to.Latitude - from.Latitude)
+	posX := radians(lon-from.Longitude) * scale
+	posY := radians(lat - from.Latitude)
+
+	length := math.Hypot(legX, legY)
+	if length == 0 {
+		return 0
+	}
+	return (legY*posX - legX*posY) / length * earthRadius
+}
@@ -0,0 +1,179 @@
+package nmea
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/moosethebrown/ship-net-bridge/core"
+	"github.com/moosethebrown/ship-net-bridge/metrics"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// DefaultBaudRate is the NMEA 0183 standard rate
+	DefaultBaudRate = 4800
+	// DefaultInterval is how often fixes are passed to the core
+	DefaultInterval = time.Second
+)
+
+// Input reads sentences of a GNSS receiver, compass or multiplexer and passes
+// the merged fix to the core at most once per interval, only when a new
+// position has been received
+type Input struct {
+	address    string
+	baudRate   int
+	interval   time.Duration
+	theCore    *core.Core
+	logger     *zerolog.Logger
+	stateMutex sync.Mutex
+	state      fixState
+	connected  bool
+	sentences  int
+	errors     int
+	lastFix    time.Time
+}
+
+type InputStatus struct {
+	Address   string     `json:"address"`
+	Connected bool       `json:"connected"`
+	Sentences int        `json:"sentences"`
+	Errors    int        `json:"errors"`
+	LastFix   *time.Time `json:"lastFix,omitempty"`
+}
+
+func NewInput(address string, baudRate int, interval time.Duration,
+	theCore *core.Core, logger *zerolog.Logger) *Input {
+	if baudRate == 0 {
+		baudRate = DefaultBaudRate
+	}
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	return &Input{
+		address:  address,
+		baudRate: baudRate,
+		interval: interval,
+		theCore:  theCore,
+		logger:   logger,
+	}
+}
+
+// Run returns an error when the source can't be opened or fails,
+// it's up to the caller to run it again
+func (in *Input) Run(ctx context.Context) error {
+	source, err := Open(ctx, in.address, in.baudRate)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		in.logger.Error().Err(err).Msgf("Failed to open %s", in.address)
+		metrics.Errors.WithLabelValues("nmea", "connect").Inc()
+		return err
+	}
+	// closing the source ends read
+	defer source.Close()
+	in.setConnected(true)
+	defer in.setConnected(false)
+
+	readErr := make(chan error, 1)
+	go func() {
+		readErr <- ReadLines(source, in.handleLine)
+	}()
+
+	ticker := time.NewTicker(in.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			in.publish()
+		case err := <-readErr:
+			if ctx.Err() != nil {
+				return nil
+			}
+			in.logger.Error().Err(err).Msgf("Failed to read %s", in.address)
+			metrics.Errors.WithLabelValues("nmea", "read").Inc()
+			return err
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Status can be called from any goroutine
+func (in *Input) Status() any {
+	in.stateMutex.Lock()
+	defer in.stateMutex.Unlock()
+
+	status := &InputStatus{
+		Address:   in.address,
+		Connected: in.connected,
+		Sentences: in.sentences,
+		Errors:    in.errors,
+	}
+	if !in.lastFix.IsZero() {
+		lastFix := in.lastFix
+		status.LastFix = &lastFix
+	}
+	return status
+}
+
+func (in *Input) Healthy() bool {
+	in.stateMutex.Lock()
+	defer in.stateMutex.Unlock()
+
+	return in.connected
+}
+
+func (in *Input) handleLine(line string, now time.Time) {
+	if line == "" {
+		return
+	}
+
+	s, err := Parse(line)
+	in.stateMutex.Lock()
+	if err == nil {
+		err = in.state.update(s, now)
+	}
+	if err != nil {
+		in.errors++
+	} else {
+		in.sentences++
+	}
+	in.stateMutex.Unlock()
+
+	if err != nil {
+		in.logger.Debug().Err(err).Msg("invalid NMEA sentence")
+		if errors.Is(err, ErrChecksum) {
+			metrics.Errors.WithLabelValues("nmea", "checksum").Inc()
+		} else {
+			metrics.Errors.WithLabelValues("nmea", "malformed").Inc()
+		}
+		return
+	}
+	metrics.NmeaSentences.WithLabelValues(sentenceLabel(s.Type)).Inc()
+}
+
+// publish passes the fix to the core if a position has been received
+// since the previous call
+func (in *Input) publish() {
+	in.stateMutex.Lock()
+	fix := in.state.take(time.Now())
+	if fix != nil {
+		in.lastFix = fix.Timestamp
+	}
+	in.stateMutex.Unlock()
+
+	if fix != nil {
+		in.theCore.HandleFix(fix)
+	}
+}
+
+func (in *Input) setConnected(connected bool) {
+	in.stateMutex.Lock()
+	in.connected = connected
+	in.stateMutex.Unlock()
+}
@@ -0,0 +1,390 @@
+package nmea
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moosethebrown/ship-net-bridge/core"
+	"github.com/moosethebrown/ship-net-bridge/metrics"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// DefaultMaxAge is how long telemetry is served after it was received
+	DefaultMaxAge = 10 * time.Second
+	// DefaultArrivalRadius is the distance from a waypoint, in meters,
+	// at which it counts as reached
+	DefaultArrivalRadius = 10.0
+)
+
+const (
+	// talker of sent sentences, a GNSS receiver to chartplotters
+	talker = "GP"
+	// maxData is room for sentence data within the 82 characters
+	// NMEA 0183 allows: $<data>*hh\r\n
+	maxData = 82 - 6
+	// writeTimeout keeps a stalled TCP client from delaying others
+	writeTimeout = time.Second
+)
+
+// State is the vessel state Output serves, implemented by core.Core
+type State interface {
+	LastTelemetry() *core.Telemetry
+	Waypoints() []core.Waypoint
+	AutoNav() bool
+}
+
+// Output serves vessel state to chartplotters, e.g. OpenCPN, as NMEA 0183
+// sentences every interval: RMC, GGA and HDT from ship-nav telemetry, WPL
+// and RTE for the waypoints and, while autonav is on, RMB towards the active
+// waypoint. Sentences are sent to TCP clients and to a UDP (broadcast)
+// address. Ship-nav speed is taken as meters per second.
+//
+// Ship-nav doesn't report which waypoint it is heading to, the active
+// waypoint is the first one and moves on to the next one when the vessel
+// comes within arrivalRadius meters.
+type Output struct {
+	name          string
+	tcpAddress    string
+	udpAddress    string
+	interval      time.Duration
+	maxAge        time.Duration
+	arrivalRadius float64
+	state         State
+	logger        *zerolog.Logger
+	clientsMutex  sync.Mutex
+	clients       map[net.Conn]bool
+	// route and active are only used by Run's goroutine
+	route       []core.Waypoint
+	active      int
+	statusMutex sync.Mutex
+	sentences   int
+	lastSent    time.Time
+}
+
+type OutputStatus struct {
+	TcpAddress string     `json:"tcpAddress,omitempty"`
+	UdpAddress string     `json:"udpAddress,omitempty"`
+	Clients    int        `json:"clients"`
+	Sentences  int        `json:"sentences"`
+	LastSent   *time.Time `json:"lastSent,omitempty"`
+}
+
+// NewOutput creates an output naming the route and its waypoints after name,
+// e.g. the ship id; either address may be empty
+func NewOutput(name string, tcpAddress string, udpAddress string,
+	interval time.Duration, maxAge time.Duration, arrivalRadius float64,
+	state State, logger *zerolog.Logger) *Output {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	if maxAge <= 0 {
+		maxAge = DefaultMaxAge
+	}
+	if arrivalRadius <= 0 {
+		arrivalRadius = DefaultArrivalRadius
+	}
+	// fields can't contain sentence delimiters
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(",*$!\\\r\n", r) {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "" {
+		name = "SNB"
+	}
+
+	return &Output{
+		name:          name,
+		tcpAddress:    tcpAddress,
+		udpAddress:    udpAddress,
+		interval:      interval,
+		maxAge:        maxAge,
+		arrivalRadius: arrivalRadius,
+		state:         state,
+		logger:        logger,
+		clients:       make(map[net.Conn]bool),
+	}
+}
+
+// Run returns an error when the TCP address can't be listened on
+// or the UDP address can't be used
+func (o *Output) Run(ctx context.Context) error {
+	defer o.closeClients()
+	if o.tcpAddress != "" {
+		listener, err := net.Listen("tcp", o.tcpAddress)
+		if err != nil {
+			o.logger.Error().Err(err).Msgf("Failed to listen on %s", o.tcpAddress)
+			metrics.Errors.WithLabelValues("nmea-output", "listen").Inc()
+			return err
+		}
+		// closing the listener ends accept
+		defer listener.Close()
+		go o.accept(listener)
+	}
+
+	var udp net.Conn
+	if o.udpAddress != "" {
+		var err error
+		udp, err = net.Dial("udp", o.udpAddress)
+		if err != nil {
+			o.logger.Error().Err(err).Msgf("Failed to use %s", o.udpAddress)
+			metrics.Errors.WithLabelValues("nmea-output", "connect").Inc()
+			return err
+		}
+		defer udp.Close()
+	}
+
+	ticker := time.NewTicker(o.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			o.send(o.build(now), udp)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Status can be called from any goroutine
+func (o *Output) Status() any {
+	status := &OutputStatus{
+		TcpAddress: o.tcpAddress,
+		UdpAddress: o.udpAddress,
+	}
+
+	o.clientsMutex.Lock()
+	status.Clients = len(o.clients)
+	o.clientsMutex.Unlock()
+
+	o.statusMutex.Lock()
+	defer o.statusMutex.Unlock()
+	status.Sentences = o.sentences
+	if !o.lastSent.IsZero() {
+		lastSent := o.lastSent
+		status.LastSent = &lastSent
+	}
+	return status
+}
+
+func (o *Output) accept(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		o.logger.Info().Msgf("chartplotter %s connected", conn.RemoteAddr())
+		o.clientsMutex.Lock()
+		o.clients[conn] = true
+		o.clientsMutex.Unlock()
+
+		go func() {
+			// sentences from chartplotters aren't used, reading only tells
+			// when they disconnect
+			io.Copy(io.Discard, conn)
+			o.removeClient(conn)
+		}()
+	}
+}
+
+func (o *Output) removeClient(conn net.Conn) {
+	o.clientsMutex.Lock()
+	defer o.clientsMutex.Unlock()
+
+	if o.clients[conn] {
+		o.logger.Info().Msgf("chartplotter %s disconnected", conn.RemoteAddr())
+		delete(o.clients, conn)
+		conn.Close()
+	}
+}
+
+func (o *Output) closeClients() {
+	o.clientsMutex.Lock()
+	defer o.clientsMutex.Unlock()
+
+	for conn := range o.clients {
+		conn.Close()
+	}
+	o.clients = make(map[net.Conn]bool)
+}
+
+// send writes sentences to every TCP client at once and to UDP
+// one datagram per sentence
+func (o *Output) send(sentences []string, udp net.Conn) {
+	if len(sentences) == 0 {
+		return
+	}
+
+	if udp != nil {
+		for _, s := range sentences {
+			_, err := udp.Write([]byte(s))
+			if err != nil {
+				o.logger.Debug().Err(err).Msgf("failed to send to %s", o.udpAddress)
+				metrics.Errors.WithLabelValues("nmea-output", "write").Inc()
+				break
+			}
+		}
+	}
+
+	data := []byte(strings.Join(sentences, ""))
+	o.clientsMutex.Lock()
+	clients := make([]net.Conn, 0, len(o.clients))
+	for conn := range o.clients {
+		clients = append(clients, conn)
+	}
+	o.clientsMutex.Unlock()
+	for _, conn := range clients {
+		conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		_, err := conn.Write(data)
+		if err != nil {
+			o.logger.Warn().Err(err).Msgf("failed to send to chartplotter %s", conn.RemoteAddr())
+			metrics.Errors.WithLabelValues("nmea-output", "write").Inc()
+			o.removeClient(conn)
+		}
+	}
+
+	o.statusMutex.Lock()
+	o.sentences += len(sentences)
+	o.lastSent = time.Now()
+	o.statusMutex.Unlock()
+}
+
+// build returns sentences describing the current state, position ones
+// only while telemetry is fresh
+func (o *Output) build(now time.Time) []string {
+	sentences := make([]string, 0)
+
+	waypoints := o.state.Waypoints()
+	if !slices.Equal(waypoints, o.route) {
+		o.route = waypoints
+		o.active = 0
+	}
+
+	t := o.state.LastTelemetry()
+	if (t != nil) && (now.Sub(t.Timestamp) <= o.maxAge) {
+		sentences = append(sentences, o.position(t)...)
+		if o.state.AutoNav() && (len(o.route) > 0) {
+			o.advance(t)
+			sentences = append(sentences, o.rmb(t))
+		}
+	}
+
+	return append(sentences, o.routeSentences()...)
+}
+
+func (o *Output) position(t *core.Telemetry) []string {
+	utc := t.Timestamp.UTC()
+	hms := utc.Format("150405.00")
+	lat, ns := formatCoordinate(t.Latitude, 2, "N", "S")
+	lon, ew := formatCoordinate(t.Longitude, 3, "E", "W")
+	heading := formatFloat(math.Mod(t.Heading+360, 360), 1)
+
+	return []string{
+		Encode(talker, "RMC", hms, "A", lat, ns, lon, ew,
+			formatFloat(t.Speed*knotsPerMps, 1), heading, utc.Format("020106"), "", "", "A"),
+		Encode(talker, "GGA", hms, lat, ns, lon, ew, "1", "", "", "", "M", "", "M", "", ""),
+		Encode(talker, "HDT", heading, "T"),
+	}
+}
+
+// advance moves the active waypoint on once the vessel reaches it,
+// the last waypoint stays active
+func (o *Output) advance(t *core.Telemetry) {
+	for o.active < len(o.route)-1 {
+		wp := o.route[o.active]
+		if distance(t.Latitude, t.Longitude, wp.Latitude, wp.Longitude) > o.arrivalRadius {
+			return
+		}
+		o.active++
+	}
+}
+
+// rmb describes navigation towards the active waypoint, cross track error
+// is relative to the leg from the previous waypoint
+func (o *Output) rmb(t *core.Telemetry) string {
+	dest := o.route[o.active]
+	lat, ns := formatCoordinate(dest.Latitude, 2, "N", "S")
+	lon, ew := formatCoordinate(dest.Longitude, 3, "E", "W")
+	meters := distance(t.Latitude, t.Longitude, dest.Latitude, dest.Longitude)
+	toDest := bearing(t.Latitude, t.Longitude, dest.Latitude, dest.Longitude)
+	closing := t.Speed * knotsPerMps * math.Cos(radians(toDest-t.Heading))
+
+	origin := ""
+	xte := 0.0
+	if o.active > 0 {
+		origin = o.waypointName(o.active - 1)
+		xte = crossTrack(o.route[o.active-1], dest, t.Latitude, t.Longitude)
+	}
+	// right of the leg, steer left
+	steer := "R"
+	if xte > 0 {
+		steer = "L"
+	}
+	arrival := "V"
+	if meters <= o.arrivalRadius {
+		arrival = "A"
+	}
+
+	return Encode(talker, "RMB", "A",
+		formatFloat(math.Min(math.Abs(xte)/metersPerMile, 9.99), 2), steer,
+		origin, o.waypointName(o.active), lat, ns, lon, ew,
+		formatFloat(meters/metersPerMile, 3), formatFloat(toDest, 1),
+		formatFloat(closing, 1), arrival, "A")
+}
+
+// routeSentences lists waypoints (WPL) and the route through them (RTE),
+// split into as many RTE sentences as needed
+func (o *Output) routeSentences() []string {
+	if len(o.route) == 0 {
+		return nil
+	}
+
+	sentences := make([]string, 0, len(o.route)+1)
+	for i, wp := range o.route {
+		lat, ns := formatCoordinate(wp.Latitude, 2, "N", "S")
+		lon, ew := formatCoordinate(wp.Longitude, 3, "E", "W")
+		sentences = append(sentences, Encode(talker, "WPL", lat, ns, lon, ew, o.waypointName(i)))
+	}
+
+	// talker, type, sentence count and number, route type and name
+	header := len(talker+"RTE,99,99,c,") + len(o.name)
+	groups := make([][]string, 0)
+	group := make([]string, 0)
+	length := header
+	for i := range o.route {
+		id := o.waypointName(i)
+		if (len(group) > 0) && (length+1+len(id) > maxData) {
+			groups = append(groups, group)
+			group = make([]string, 0)
+			length = header
+		}
+		group = append(group, id)
+		length += 1 + len(id)
+	}
+	groups = append(groups, group)
+
+	for i, ids := range groups {
+		fields := append([]string{strconv.Itoa(len(groups)), strconv.Itoa(i + 1), "c", o.name}, ids...)
+		sentences = append(sentences, Encode(talker, "RTE", fields...))
+	}
+	return sentences
+}
+
+func (o *Output) waypointName(i int) string {
+	return fmt.Sprintf("%s-%d", o.name, i+1)
+}
+
+func formatFloat(value float64, precision int) string {
+	return strconv.FormatFloat(value, 'f', precision, 64)
+}
@@ -0,0 +1,164 @@
+package nmea
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/moosethebrown/ship-net-bridge/core"
+	"github.com/rs/zerolog"
+)
+
+type mockState struct {
+	mutex     sync.Mutex
+	telemetry *core.Telemetry
+	waypoints []core.Waypoint
+	autoNav   bool
+}
+
+func (m *mockState) LastTelemetry() *core.Telemetry {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.telemetry
+}
+
+func (m *mockState) Waypoints() []core.Waypoint {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.waypoints
+}
+
+func (m *mockState) AutoNav() bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.autoNav
+}
+
+func newOutput(state State, tcpAddress string) *Output {
+	logger := zerolog.New(os.Stdout).Level(zerolog.DebugLevel)
+	return NewOutput("drone,1", tcpAddress, "", 10*time.Millisecond, 0, 0, state, &logger)
+}
+
+// parse checks sentences and returns them by type
+func parse(t *testing.T, sentences []string) map[string][]*Sentence {
+	byType := make(map[string][]*Sentence)
+	for _, line := range sentences {
+		if len(line) > 82 {
+			t.Errorf("Sentence is longer than 82 characters: %q", line)
+		}
+		s, err := Parse(line)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %s", line, err)
+		}
+		byType[s.Type] = append(byType[s.Type], s)
+	}
+	return byType
+}
+
+func TestOutput(t *testing.T) {
+	now := time.Now()
+	state := &mockState{
+		telemetry: &core.Telemetry{Latitude: -33.758333, Longitude: 151.204167,
+			Heading: 90, Speed: 2, Timestamp: now},
+		waypoints: []core.Waypoint{
+			{Latitude: -33.758333, Longitude: 151.204267},
+			{Latitude: -33.758333, Longitude: 151.214167},
+		},
+		autoNav: true,
+	}
+	o := newOutput(state, "")
+
+	byType := parse(t, o.build(now))
+	for _, sentenceType := range []string{"RMC", "GGA", "HDT", "RMB", "WPL", "RTE"} {
+		if len(byType[sentenceType]) == 0 {
+			t.Errorf("Expected %s sentence", sentenceType)
+		}
+	}
+
+	// what is sent reads back as sent
+	fix := &fixState{}
+	for _, s := range append(byType["RMC"], byType["HDT"]...) {
+		if err := fix.update(s, now); err != nil {
+			t.Fatalf("Failed to read back %v: %s", s, err)
+		}
+	}
+	f := fix.take(now)
+	if !near(f.Latitude, -33.758333) || !near(f.Longitude, 151.204167) ||
+		(*f.Heading != 90) || (*f.Speed != 3.9) {
+		t.Errorf("Unexpected position sent %+v", f)
+	}
+
+	// the first waypoint is within arrival radius, the second one is active
+	rmb := byType["RMB"][0]
+	if (rmb.Field(3) != "drone1-1") || (rmb.Field(4) != "drone1-2") || (rmb.Field(10) != "90.0") ||
+		(rmb.Field(12) != "V") {
+		t.Errorf("Unexpected RMB %v", rmb.Fields)
+	}
+	if rte := byType["RTE"][0]; (rte.Field(3) != "drone1") || (rte.Field(5) != "drone1-2") {
+		t.Errorf("Unexpected RTE %v", rte.Fields)
+	}
+
+	// stale telemetry isn't sent, long routes are split
+	state.waypoints = make([]core.Waypoint, 30)
+	byType = parse(t, o.build(now.Add(DefaultMaxAge+time.Second)))
+	if (len(byType["RMC"]) != 0) || (len(byType["RMB"]) != 0) {
+		t.Errorf("Expected stale telemetry not to be sent")
+	}
+	if (len(byType["WPL"]) != 30) || (len(byType["RTE"]) < 2) {
+		t.Errorf("Expected 30 waypoints in several RTE sentences, got %d %d",
+			len(byType["WPL"]), len(byType["RTE"]))
+	}
+}
+
+func TestOutputTcp(t *testing.T) {
+	state := &mockState{
+		telemetry: &core.Telemetry{Latitude: 48.1173, Longitude: 11.5167, Timestamp: time.Now()},
+	}
+	o := newOutput(state, "127.0.0.1:0")
+
+	// find a free port
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen on tcp: %s", err)
+	}
+	o.tcpAddress = l.Addr().String()
+	l.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- o.Run(ctx)
+	}()
+
+	var conn net.Conn
+	for i := 0; i < 50; i++ {
+		conn, err = net.Dial("tcp", o.tcpAddress)
+		if err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("Failed to connect to %s: %s", o.tcpAddress, err)
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatalf("Failed to read sentence: %s", err)
+	}
+	if !strings.HasPrefix(line, "$GPRMC,") {
+		t.Errorf("Expected RMC sentence, got %q", line)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Expected output to stop cleanly, got %s", err)
+	}
+}
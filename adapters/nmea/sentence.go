@@ -0,0 +1,99 @@
+package nmea
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ErrChecksum is returned for sentences with a wrong checksum
+var ErrChecksum = errors.New("NMEA sentence checksum mismatch")
+
+// Sentence is a parsed NMEA 0183 sentence: $<talker><type>,<fields>*<checksum>,
+// e.g. $GPGGA,... has talker GP and type GGA; proprietary sentences
+// ($P...) have talker P. Encapsulation sentences, e.g. AIS !AIVDM,
+// start with ! instead.
+type Sentence struct {
+	Talker string
+	Type   string
+	Fields []string
+}
+
+func checksum(data string) byte {
+	var sum byte
+	for i := 0; i < len(data); i++ {
+		sum ^= data[i]
+	}
+	return sum
+}
+
+// Parse checks sentence checksum and splits it into fields, trailing
+// line breaks are ignored; sentences without checksum are rejected
+func Parse(line string) (*Sentence, error) {
+	line = strings.TrimRight(line, "\r\n")
+	if (len(line) == 0) || ((line[0] != '$') && (line[0] != '!')) {
+		return nil, fmt.Errorf("malformed NMEA sentence: %q", line)
+	}
+
+	end := strings.LastIndexByte(line, '*')
+	if (end < 0) || (len(line)-end != 3) {
+		return nil, fmt.Errorf("NMEA sentence checksum is missing: %q", line)
+	}
+	sum, err := strconv.ParseUint(line[end+1:], 16, 8)
+	if err != nil {
+		return nil, fmt.Errorf("malformed NMEA sentence checksum: %q", line)
+	}
+	data := line[1:end]
+	if checksum(data) != byte(sum) {
+		return nil, ErrChecksum
+	}
+
+	fields := strings.Split(data, ",")
+	address := fields[0]
+	s := &Sentence{
+		Fields: fields[1:],
+	}
+	switch {
+	case strings.HasPrefix(address, "P") && (len(address) > 1):
+		s.Talker = "P"
+		s.Type = address[1:]
+	case len(address) == 5:
+		s.Talker = address[:2]
+		s.Type = address[2:]
+	default:
+		return nil, fmt.Errorf("malformed NMEA sentence address: %q", line)
+	}
+	return s, nil
+}
+
+// Field returns field i, empty when the sentence is shorter
+func (s *Sentence) Field(i int) string {
+	if i >= len(s.Fields) {
+		return ""
+	}
+	return s.Fields[i]
+}
+
+// Encode builds a sentence with checksum and line break,
+// fields must not contain ',', '*' or '$'
+func Encode(talker string, sentenceType string, fields ...string) string {
+	data := talker + sentenceType + "," + strings.Join(fields, ",")
+	return fmt.Sprintf("$%s*%02X\r\n", data, checksum(data))
+}
+
+// formatCoordinate formats degrees as [d]ddmm.mmmm with hemisphere,
+// degreeDigits is 2 for latitude and 3 for longitude
+func formatCoordinate(value float64, degreeDigits int, positive string, negative string) (string, string) {
+	hemisphere := positive
+	if value < 0 {
+		hemisphere = negative
+		value = -value
+	}
+	// rounding minutes first keeps them below 60
+	minutes := math.Round(value*60*10000) / 10000
+	degrees := math.Floor(minutes / 60)
+	minutes -= degrees * 60
+	return fmt.Sprintf("%0*d%07.4f", degreeDigits, int(degrees), minutes), hemisphere
+}
@@ -0,0 +1,75 @@
+package nmea
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/moosethebrown/ship-net-bridge/adapters/daemon"
+	"github.com/moosethebrown/ship-net-bridge/address"
+)
+
+// maxSentence is well above the 82 characters NMEA 0183 allows,
+// some receivers exceed it
+const maxSentence = 1024
+
+// Open opens an NMEA source, see address.Nmea; baudRate applies to serial
+// ports, closing the source ends ReadLines
+func Open(ctx context.Context, source string, baudRate int) (io.ReadCloser, error) {
+	network, addr, err := address.Nmea(source)
+	if err != nil {
+		return nil, err
+	}
+
+	switch network {
+	case "serial":
+		return daemon.OpenSerial(addr, baudRate)
+	case "udp":
+		udpAddr, err := net.ResolveUDPAddr("udp", addr)
+		if err != nil {
+			return nil, err
+		}
+		return net.ListenUDP("udp", udpAddr)
+	default:
+		dialer := &net.Dialer{
+			Timeout: daemon.DefaultDialTimeout,
+		}
+		return dialer.DialContext(ctx, "tcp", addr)
+	}
+}
+
+// ReadLines calls handle with every line read from source, without line
+// break, until source fails or is closed
+func ReadLines(source io.Reader, handle func(line string, now time.Time)) error {
+	if conn, ok := source.(*net.UDPConn); ok {
+		return readDatagrams(conn, handle)
+	}
+
+	scanner := bufio.NewScanner(source)
+	scanner.Buffer(make([]byte, maxSentence), maxSentence)
+	for scanner.Scan() {
+		handle(strings.TrimRight(scanner.Text(), "\r"), time.Now())
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+// readDatagrams handles datagrams of one or more sentences each
+func readDatagrams(conn *net.UDPConn, handle func(line string, now time.Time)) error {
+	buf := make([]byte, 65536)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, line := range strings.Split(string(buf[:n]), "\n") {
+			handle(strings.TrimRight(line, "\r"), now)
+		}
+	}
+}
@@ -1,71 +1,316 @@
 package shipcontrol
 
 import (
-	"net"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"time"
 
+	"github.com/moosethebrown/ship-net-bridge/adapters/daemon"
 	"github.com/moosethebrown/ship-net-bridge/core"
+	"github.com/moosethebrown/ship-net-bridge/metrics"
+	"github.com/moosethebrown/ship-net-bridge/queue"
 	"github.com/rs/zerolog"
 )
 
+// DefaultSafeState is sent to ship-control on emergency stop and shutdown
+// unless configured otherwise
+var DefaultSafeState = []byte(`{"type":"cmd","cmd":"set_speed","data":"0"}`)
+
+// safeStateTimeout bounds sending safe-state command on shutdown
+const safeStateTimeout = time.Second
+
+type request struct {
+	msg    []byte
+	header core.Request
+	origin *core.Origin
+}
+
 type Adapter struct {
-	socketName string
-	theCore    *core.Core
-	rqChan     chan []byte
-	stopChan   chan bool
-	respBuf    []byte
-	logger     *zerolog.Logger
+	endpoint     *daemon.Endpoint
+	theCore      *core.Core
+	requests     *queue.Priority[*request]
+	safeState    []byte
+	drainTimeout time.Duration
+	logger       *zerolog.Logger
+	connected    atomic.Bool
 }
 
-func NewAdapter(socketName string, theCore *core.Core, queueSize int, logger *zerolog.Logger) *Adapter {
-	return &Adapter{
-		socketName: socketName,
-		theCore:    theCore,
-		rqChan:     make(chan []byte, queueSize),
-		stopChan:   make(chan bool, 1),
-		respBuf:    make([]byte, 4096),
-		logger:     logger,
+type Status struct {
+	Socket       string         `json:"socket"`
+	Connected    bool           `json:"connected"`
+	RequestQueue int            `json:"requestQueue"`
+	ClassQueues  map[string]int `json:"classQueues"`
+}
+
+// NewAdapter creates ship-control adapter, nil safeState means DefaultSafeState
+func NewAdapter(endpoint *daemon.Endpoint, theCore *core.Core, queueSize int,
+	overflowPolicy queue.Policy, safeState []byte, drainTimeout time.Duration,
+	logger *zerolog.Logger) *Adapter {
+	if safeState == nil {
+		safeState = DefaultSafeState
+	}
+
+	a := &Adapter{
+		endpoint: endpoint,
+		theCore:  theCore,
+		requests: core.NewRequestQueue(queueSize, func(queued *request, rq *request) bool {
+			return core.Supersedes(&queued.header, &rq.header)
+		}),
+		safeState:    safeState,
+		drainTimeout: drainTimeout,
+		logger:       logger,
 	}
+	a.requests.SetPolicy(overflowPolicy)
+
+	return a
 }
 
-func (a *Adapter) Run() {
-	conn, err := net.Dial("unix", a.socketName)
+// Run returns an error when connection to the daemon fails, it's up to
+// the caller to run it again. When ctx is cancelled queued requests are sent
+// for up to drainTimeout, followed by the safe-state command; without
+// a working connection one more attempt to connect is made for that.
+func (a *Adapter) Run(ctx context.Context) error {
+	if ctx.Err() != nil {
+		a.shutdown(nil)
+		return nil
+	}
+
+	conn, err := a.endpoint.Connect(ctx)
 	if err != nil {
-		a.logger.Error().Err(err).Msg("Failed to connect to socket")
-		return
+		if ctx.Err() != nil {
+			a.shutdown(nil)
+			return nil
+		}
+		a.logger.Error().Err(err).Msg("Failed to connect to daemon")
+		metrics.Errors.WithLabelValues("ship-control", "connect").Inc()
+		return err
 	}
 	defer conn.Close()
+	a.connected.Store(true)
+	defer a.connected.Store(false)
 
-main_loop:
 	for {
 		select {
-		case msg := <-a.rqChan:
-			a.send(conn, msg)
-		case <-a.stopChan:
-			break main_loop
+		case <-a.requests.Ready():
+			rq, ok := a.requests.Pop()
+			if !ok {
+				continue
+			}
+			err := a.send(conn, rq)
+			if errors.Is(err, daemon.ErrNoResponse) {
+				if rq.origin != nil {
+					a.theCore.HandleError(rq.origin, rq.header.Cmd, "no response from ship-control")
+				}
+				continue
+			}
+			if err != nil {
+				if rq.origin != nil {
+					a.theCore.HandleError(rq.origin, rq.header.Cmd, "ship-control connection failed")
+				}
+				return err
+			}
+		case <-ctx.Done():
+			a.shutdown(conn)
+			return nil
 		}
 	}
 }
 
-func (a *Adapter) Stop() {
-	a.stopChan <- true
+// shutdown sends queued requests and the safe-state command, conn is nil
+// when not connected; a new connection is made for the safe-state command
+// when there is none or sending queued requests failed
+func (a *Adapter) shutdown(conn *daemon.Conn) {
+	if conn == nil {
+		conn = a.connectForShutdown()
+		if conn == nil {
+			return
+		}
+		defer conn.Close()
+	}
+
+	conn.SetLimit(time.Now().Add(a.drainTimeout))
+	drained := true
+	for {
+		rq, ok := a.requests.Pop()
+		if !ok {
+			break
+		}
+		err := a.send(conn, rq)
+		if errors.Is(err, daemon.ErrNoResponse) {
+			continue
+		}
+		if err != nil {
+			drained = false
+			break
+		}
+	}
+	if n := a.requests.Len(); n > 0 {
+		a.logger.Warn().Msgf("dropping %d queued requests on shutdown", n)
+	}
+	if !drained {
+		// responses on conn can't be matched to requests anymore
+		conn = a.connectForShutdown()
+		if conn == nil {
+			return
+		}
+		defer conn.Close()
+	}
+
+	a.logger.Info().Msg("sending safe-state command")
+	conn.SetLimit(time.Now().Add(safeStateTimeout))
+	err := a.send(conn, &request{
+		msg: a.safeState,
+		header: core.Request{
+			Type: core.RequestTypeCmd,
+		},
+	})
+	if err != nil {
+		a.logger.Error().Err(err).Msg("failed to send safe-state command")
+		metrics.Errors.WithLabelValues("ship-control", "safe_state").Inc()
+	}
+}
+
+// connectForShutdown connects once more to send the safe-state command,
+// returns nil when that fails
+func (a *Adapter) connectForShutdown() *daemon.Conn {
+	ctx, cancel := context.WithTimeout(context.Background(), safeStateTimeout)
+	defer cancel()
+
+	conn, err := a.endpoint.Connect(ctx)
+	if err != nil {
+		a.logger.Error().Err(err).Msg("not connected to ship-control, safe-state command not sent")
+		metrics.Errors.WithLabelValues("ship-control", "safe_state").Inc()
+		return nil
+	}
+	return conn
+}
+
+// ShutdownTimeout is how long Run may take to return after ctx is cancelled:
+// connecting if needed, draining queued requests, connecting again if that
+// failed and sending the safe-state command
+func (a *Adapter) ShutdownTimeout() time.Duration {
+	return a.drainTimeout + 3*safeStateTimeout
+}
+
+// SetOverflowPolicy can be called any time
+func (a *Adapter) SetOverflowPolicy(policy queue.Policy) {
+	a.requests.SetPolicy(policy)
+}
+
+// Status can be called from any goroutine
+func (a *Adapter) Status() any {
+	return &Status{
+		Socket:       a.endpoint.Address,
+		Connected:    a.connected.Load(),
+		RequestQueue: a.requests.Len(),
+		ClassQueues:  core.ClassDepths(a.requests.Lens()),
+	}
+}
+
+func (a *Adapter) QueueDepths() map[string]int {
+	depths := make(map[string]int)
+	for class, n := range core.ClassDepths(a.requests.Lens()) {
+		depths["requests_"+class] = n
+	}
+	return depths
 }
 
-func (a *Adapter) SendRequest(msg []byte) {
-	a.rqChan <- msg
+func (a *Adapter) Healthy() bool {
+	return a.connected.Load()
 }
 
-func (a *Adapter) send(conn net.Conn, msg []byte) {
-	_, err := conn.Write(msg)
+func (a *Adapter) SendRequest(msg []byte, origin *core.Origin) {
+	rq := &request{
+		msg:    msg,
+		origin: origin,
+	}
+	// only type and command are needed to prioritize the request
+	err := json.Unmarshal(msg, &rq.header)
 	if err != nil {
-		a.logger.Error().Err(err).Msg("failed to send message to ship-control")
+		a.logger.Error().Err(err).Msg("failed to unmarshal request")
+		metrics.Errors.WithLabelValues("ship-control", "unmarshal_request").Inc()
+	}
+
+	a.push(rq)
+}
+
+// EmergencyStop drops queued control commands and sends safe-state command
+// before any other request
+func (a *Adapter) EmergencyStop(origin *core.Origin) {
+	for _, rq := range a.requests.Drain(core.ClassPriority(core.CommandClassControl)) {
+		if rq.origin != nil {
+			a.theCore.HandleError(rq.origin, rq.header.Cmd, "cancelled by emergency stop")
+		}
+	}
+
+	a.push(&request{
+		msg: a.safeState,
+		header: core.Request{
+			Type: core.RequestTypeCmd,
+			Cmd:  core.CmdEmergencyStop,
+		},
+		origin: origin,
+	})
+}
+
+// push queues request by its command class, it never blocks
+func (a *Adapter) push(rq *request) {
+	class := core.CommandClass(&rq.header)
+	result := core.PushRequest(a.requests, class, rq)
+	if result.Superseded {
+		metrics.Superseded.WithLabelValues("ship-control").Inc()
+		if result.Item.origin != nil {
+			a.theCore.HandleError(result.Item.origin, result.Item.header.Cmd,
+				"superseded by a newer command")
+		}
 		return
 	}
 
-	n, err := conn.Read(a.respBuf)
+	if result.Exceeded {
+		a.logger.Warn().Msgf("%s request queue is full, queuing %s beyond the limit", class, rq.header.Cmd)
+		metrics.Overflows.WithLabelValues("ship-control", "requests_"+class, core.OverflowExceeded).Inc()
+		return
+	}
+	if result.Queued && !result.Dropped {
+		return
+	}
+	policy := a.requests.Policy()
+	metrics.Overflows.WithLabelValues("ship-control", "requests_"+class, policy.String()).Inc()
+	if result.Dropped {
+		a.logger.Warn().Msgf("%s request queue is full, dropping the oldest request %s",
+			core.CommandClass(&result.Item.header), result.Item.header.Cmd)
+		if result.Item.origin != nil {
+			a.theCore.HandleError(result.Item.origin, result.Item.header.Cmd,
+				"dropped, ship-control queue is full")
+		}
+		return
+	}
+	a.logger.Error().Msgf("%s request queue is full, dropping %s", class, rq.header.Cmd)
+	if (policy == queue.Reject) && (rq.origin != nil) {
+		a.theCore.HandleError(rq.origin, rq.header.Cmd, "ship-control queue is full")
+	}
+}
+
+func (a *Adapter) send(conn *daemon.Conn, rq *request) error {
+	start := time.Now()
+	_, err := conn.Write(rq.msg)
+	if err != nil {
+		a.logger.Error().Err(err).Msg("failed to send message to ship-control")
+		metrics.Errors.WithLabelValues("ship-control", "write").Inc()
+		return err
+	}
+
+	resp, err := conn.ReadResponse()
 	if err != nil {
 		a.logger.Error().Err(err).Msg("failed to read response from ship-control")
-		return
+		metrics.Errors.WithLabelValues("ship-control", "read").Inc()
+		return err
 	}
+	metrics.DaemonRoundTrip.WithLabelValues("ship-control").Observe(time.Since(start).Seconds())
+
+	a.theCore.HandleResponse(rq.origin, resp)
 
-	a.theCore.HandleResponse(a.respBuf[:n])
+	return nil
 }
@@ -1,129 +1,262 @@
 package shipnav
 
 import (
+	"context"
 	"encoding/json"
-	"net"
+	"errors"
+	"sync/atomic"
+	"time"
 
+	"github.com/moosethebrown/ship-net-bridge/adapters/daemon"
 	"github.com/moosethebrown/ship-net-bridge/core"
+	"github.com/moosethebrown/ship-net-bridge/metrics"
+	"github.com/moosethebrown/ship-net-bridge/queue"
 	"github.com/rs/zerolog"
 )
 
 type cmd struct {
 	cmd       string
 	waypoints []*core.Waypoint
+	origin    *core.Origin
 }
 
 type Adapter struct {
-	socketName string
-	stopChan   chan bool
-	cmdChan    chan *cmd
-	respBuf    []byte
-	theCore    *core.Core
-	logger     *zerolog.Logger
+	endpoint     *daemon.Endpoint
+	cmds         *queue.Priority[*cmd]
+	drainTimeout time.Duration
+	theCore      *core.Core
+	logger       *zerolog.Logger
+	connected    atomic.Bool
 }
 
-func NewAdapter(sockeName string, theCore *core.Core, queueSize int, logger *zerolog.Logger) *Adapter {
-	return &Adapter{
-		socketName: sockeName,
-		stopChan:   make(chan bool, 1),
-		cmdChan:    make(chan *cmd, queueSize),
-		respBuf:    make([]byte, 4096),
-		theCore:    theCore,
-		logger:     logger,
+type Status struct {
+	Socket    string         `json:"socket"`
+	Connected bool           `json:"connected"`
+	CmdQueue  int            `json:"cmdQueue"`
+	CmdQueues map[string]int `json:"cmdQueues"`
+}
+
+func NewAdapter(endpoint *daemon.Endpoint, theCore *core.Core, queueSize int,
+	overflowPolicy queue.Policy, drainTimeout time.Duration, logger *zerolog.Logger) *Adapter {
+	a := &Adapter{
+		endpoint:     endpoint,
+		cmds:         core.NewRequestQueue[*cmd](queueSize, nil),
+		drainTimeout: drainTimeout,
+		theCore:      theCore,
+		logger:       logger,
 	}
+	a.cmds.SetPolicy(overflowPolicy)
+
+	return a
 }
 
-func (a *Adapter) Run() {
-	conn, err := net.Dial("unix", a.socketName)
+// Run returns an error when connection to the daemon fails, it's up to
+// the caller to run it again. When ctx is cancelled queued commands are sent
+// for up to drainTimeout.
+func (a *Adapter) Run(ctx context.Context) error {
+	conn, err := a.endpoint.Connect(ctx)
 	if err != nil {
-		a.logger.Error().Err(err).Msg("Failed to connect to socket")
-		return
+		if ctx.Err() != nil {
+			return nil
+		}
+		a.logger.Error().Err(err).Msg("Failed to connect to daemon")
+		metrics.Errors.WithLabelValues("ship-nav", "connect").Inc()
+		return err
 	}
 	defer conn.Close()
+	a.connected.Store(true)
+	defer a.connected.Store(false)
 
-main_loop:
 	for {
 		select {
-		case c := <-a.cmdChan:
-			a.sendMessage(conn, c)
-		case <-a.stopChan:
-			break main_loop
+		case <-a.cmds.Ready():
+			c, ok := a.cmds.Pop()
+			if !ok {
+				continue
+			}
+			err := a.sendMessage(conn, c)
+			if errors.Is(err, daemon.ErrNoResponse) {
+				if c.origin != nil {
+					a.theCore.HandleError(c.origin, c.cmd, "no response from ship-nav")
+				}
+				continue
+			}
+			if err != nil {
+				if c.origin != nil {
+					a.theCore.HandleError(c.origin, c.cmd, "ship-nav connection failed")
+				}
+				return err
+			}
+		case <-ctx.Done():
+			a.drain(conn)
+			return nil
+		}
+	}
+}
+
+func (a *Adapter) drain(conn *daemon.Conn) {
+	conn.SetLimit(time.Now().Add(a.drainTimeout))
+	for {
+		c, ok := a.cmds.Pop()
+		if !ok {
+			break
+		}
+		err := a.sendMessage(conn, c)
+		if (err != nil) && !errors.Is(err, daemon.ErrNoResponse) {
+			break
 		}
 	}
+	if n := a.cmds.Len(); n > 0 {
+		a.logger.Warn().Msgf("dropping %d queued commands on shutdown", n)
+	}
 }
 
-func (a *Adapter) Stop() {
-	a.stopChan <- true
+// SetOverflowPolicy can be called any time
+func (a *Adapter) SetOverflowPolicy(policy queue.Policy) {
+	a.cmds.SetPolicy(policy)
 }
 
-func (a *Adapter) Query() {
-	a.cmdChan <- &cmd{
-		cmd: cmdQuery,
+// Status can be called from any goroutine
+func (a *Adapter) Status() any {
+	return &Status{
+		Socket:    a.endpoint.Address,
+		Connected: a.connected.Load(),
+		CmdQueue:  a.cmds.Len(),
+		CmdQueues: core.ClassDepths(a.cmds.Lens()),
 	}
 }
 
-func (a *Adapter) NavStart() {
-	a.cmdChan <- &cmd{
-		cmd: cmdNavStart,
+func (a *Adapter) QueueDepths() map[string]int {
+	depths := make(map[string]int)
+	for class, n := range core.ClassDepths(a.cmds.Lens()) {
+		depths["commands_"+class] = n
 	}
+	return depths
+}
+
+func (a *Adapter) Healthy() bool {
+	return a.connected.Load()
+}
+
+func (a *Adapter) Query(origin *core.Origin) {
+	a.push(&cmd{
+		cmd:    cmdQuery,
+		origin: origin,
+	})
+}
+
+func (a *Adapter) NavStart(origin *core.Origin) {
+	a.push(&cmd{
+		cmd:    cmdNavStart,
+		origin: origin,
+	})
 }
 
 func (a *Adapter) NavStop() {
-	a.cmdChan <- &cmd{
+	a.push(&cmd{
 		cmd: cmdNavStop,
-	}
+	})
 }
 
 func (a *Adapter) NetLoss() {
-	a.cmdChan <- &cmd{
+	a.push(&cmd{
 		cmd: cmdNetLoss,
-	}
+	})
 }
 
-func (a *Adapter) SetWaypoints(waypoints []*core.Waypoint) {
-	a.cmdChan <- &cmd{
-		cmd:       cmdNetLoss,
+func (a *Adapter) SetWaypoints(waypoints []*core.Waypoint, origin *core.Origin) {
+	a.push(&cmd{
+		cmd:       cmdSetWaypoints,
 		waypoints: waypoints,
-	}
+		origin:    origin,
+	})
 }
 
-func (a *Adapter) AddWaypoint(waypoint *core.Waypoint) {
+func (a *Adapter) AddWaypoint(waypoint *core.Waypoint, origin *core.Origin) {
 	c := &cmd{
 		cmd:       cmdAddWaypoint,
 		waypoints: make([]*core.Waypoint, 1),
+		origin:    origin,
 	}
 	c.waypoints[0] = waypoint
-	a.cmdChan <- c
+	a.push(c)
 }
 
-func (a *Adapter) ClearWaypoints() {
-	a.cmdChan <- &cmd{
-		cmd: cmdClearWaypoints,
-	}
+func (a *Adapter) ClearWaypoints(origin *core.Origin) {
+	a.push(&cmd{
+		cmd:    cmdClearWaypoints,
+		origin: origin,
+	})
 }
 
-func (a *Adapter) SetHomeWaypoint(waypoint *core.Waypoint) {
+func (a *Adapter) SetHomeWaypoint(waypoint *core.Waypoint, origin *core.Origin) {
 	c := &cmd{
 		cmd:       cmdSetHomeWaypoint,
 		waypoints: make([]*core.Waypoint, 1),
+		origin:    origin,
 	}
 	c.waypoints[0] = waypoint
-	a.cmdChan <- c
+	a.push(c)
+}
+
+func (a *Adapter) StartCalibration(origin *core.Origin) {
+	a.push(&cmd{
+		cmd:    cmdStartCalibration,
+		origin: origin,
+	})
 }
 
-func (a *Adapter) StartCalibration() {
-	a.cmdChan <- &cmd{
-		cmd: cmdStartCalibration,
+func (a *Adapter) StopCalibration(origin *core.Origin) {
+	a.push(&cmd{
+		cmd:    cmdStopCalibration,
+		origin: origin,
+	})
+}
+
+// commandClass tells the priority of a command: stopping navigation goes
+// ahead of waypoint uploads, which go ahead of queries
+func commandClass(c *cmd) string {
+	switch c.cmd {
+	case cmdNavStop, cmdNetLoss:
+		return core.CommandClassSafety
+	case cmdQuery:
+		return core.CommandClassQuery
+	default:
+		return core.CommandClassNavigation
 	}
 }
 
-func (a *Adapter) StopCalibration() {
-	a.cmdChan <- &cmd{
-		cmd: cmdStopCalibration,
+// push queues command by its class, it never blocks
+func (a *Adapter) push(c *cmd) {
+	class := commandClass(c)
+	result := core.PushRequest(a.cmds, class, c)
+	if result.Exceeded {
+		a.logger.Warn().Msgf("%s command queue is full, queuing %s beyond the limit", class, c.cmd)
+		metrics.Overflows.WithLabelValues("ship-nav", "commands_"+class, core.OverflowExceeded).Inc()
+		return
+	}
+	if result.Queued && !result.Dropped {
+		return
+	}
+
+	policy := a.cmds.Policy()
+	metrics.Overflows.WithLabelValues("ship-nav", "commands_"+class, policy.String()).Inc()
+	if result.Dropped {
+		a.logger.Warn().Msgf("%s command queue is full, dropping the oldest command %s",
+			commandClass(result.Item), result.Item.cmd)
+		if result.Item.origin != nil {
+			a.theCore.HandleError(result.Item.origin, result.Item.cmd,
+				"dropped, ship-nav queue is full")
+		}
+		return
+	}
+	a.logger.Error().Msgf("%s command queue is full, dropping %s", class, c.cmd)
+	if (policy == queue.Reject) && (c.origin != nil) {
+		a.theCore.HandleError(c.origin, c.cmd, "ship-nav queue is full")
 	}
 }
 
-func (a *Adapter) sendMessage(conn net.Conn, command *cmd) {
+func (a *Adapter) sendMessage(conn *daemon.Conn, command *cmd) error {
 	rq := &Request{}
 
 	if command.cmd == cmdQuery {
@@ -137,20 +270,48 @@ func (a *Adapter) sendMessage(conn net.Conn, command *cmd) {
 	data, err := json.Marshal(rq)
 	if err != nil {
 		a.logger.Error().Err(err).Msg("failed to marshal query")
-		return
+		metrics.Errors.WithLabelValues("ship-nav", "marshal").Inc()
+		return nil
 	}
 
+	start := time.Now()
 	_, err = conn.Write(data)
 	if err != nil {
 		a.logger.Error().Err(err).Msg("failed to send query")
-		return
+		metrics.Errors.WithLabelValues("ship-nav", "write").Inc()
+		return err
 	}
 
-	n, err := conn.Read(a.respBuf)
+	resp, err := conn.ReadResponse()
 	if err != nil {
 		a.logger.Error().Err(err).Msg("failed to receive query response")
+		metrics.Errors.WithLabelValues("ship-nav", "read").Inc()
+		return err
+	}
+	metrics.DaemonRoundTrip.WithLabelValues("ship-nav").Observe(time.Since(start).Seconds())
+
+	if command.cmd == cmdQuery {
+		a.handleTelemetry(resp)
+	}
+
+	a.theCore.HandleResponse(command.origin, resp)
+
+	return nil
+}
+
+func (a *Adapter) handleTelemetry(resp []byte) {
+	t := &core.Telemetry{}
+	err := json.Unmarshal(resp, t)
+	if err != nil {
+		a.logger.Error().Err(err).Msg("failed to unmarshal query response")
+		metrics.Errors.WithLabelValues("ship-nav", "unmarshal_response").Inc()
+		return
+	}
+	if (t.Latitude == 0) && (t.Longitude == 0) {
+		// no position fix yet
 		return
 	}
+	t.Timestamp = time.Now()
 
-	a.theCore.HandleResponse(a.respBuf[:n])
+	a.theCore.HandleTelemetry(t)
 }
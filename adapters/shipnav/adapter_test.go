@@ -0,0 +1,49 @@
+package shipnav
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/moosethebrown/ship-net-bridge/adapters/daemon"
+	"github.com/moosethebrown/ship-net-bridge/core"
+	"github.com/moosethebrown/ship-net-bridge/queue"
+	"github.com/rs/zerolog"
+)
+
+func TestSetWaypoints(t *testing.T) {
+	logger := zerolog.New(os.Stdout).Level(zerolog.DebugLevel)
+	theCore := core.NewCore(nil, nil, 3000, &logger)
+	a := NewAdapter(&daemon.Endpoint{Address: "/tmp/sn.sock"}, theCore, 0, queue.Reject, time.Second, &logger)
+
+	a.SetWaypoints([]*core.Waypoint{{Latitude: 56.36, Longitude: 43.9}}, nil)
+	c, ok := a.cmds.Pop()
+	if !ok {
+		t.Fatalf("Expected the command to be queued")
+	}
+	if class := commandClass(c); class != core.CommandClassNavigation {
+		t.Errorf("Expected waypoint upload to be a navigation command, got %s", class)
+	}
+
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	received := make(chan *Request, 1)
+	go func() {
+		rq := &Request{}
+		json.NewDecoder(server).Decode(rq)
+		received <- rq
+		server.Write([]byte(`{"type":"ack"}`))
+	}()
+
+	err := a.sendMessage(daemon.NewConn(client, time.Second), c)
+	if err != nil {
+		t.Fatalf("Failed to send command: %s", err)
+	}
+	rq := <-received
+	if (rq.Type != rqTypeCmd) || (rq.Cmd != cmdSetWaypoints) || (len(rq.Waypoints) != 1) {
+		t.Errorf("Unexpected request %+v", rq)
+	}
+}
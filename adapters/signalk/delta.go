@@ -0,0 +1,153 @@
+package signalk
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"math"
+	"path"
+	"time"
+)
+
+const (
+	// Version of the Signal K specification the deltas follow
+	Version = "1.7.0"
+	// timeFormat is ISO 8601 in UTC with milliseconds, as Signal K uses
+	timeFormat = "2006-01-02T15:04:05.000Z"
+	// batteryId names the battery ship-nav reports on
+	batteryId = "main"
+)
+
+// Delta is a Signal K delta message updating values of a vessel
+type Delta struct {
+	Context string    `json:"context"`
+	Updates []*Update `json:"updates"`
+}
+
+type Update struct {
+	Source    string   `json:"$source"`
+	Timestamp string   `json:"timestamp"`
+	Values    []*Value `json:"values"`
+}
+
+// Value is the value at path, in SI units; nil clears it
+type Value struct {
+	Path  string `json:"path"`
+	Value any    `json:"value"`
+}
+
+type Position struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// VesselUrn identifies a vessel by MMSI or, without one, by a UUID
+// derived from the ship id, so that it stays the same across restarts
+func VesselUrn(shipId string, mmsi string) string {
+	if mmsi != "" {
+		return "urn:mrn:imo:mmsi:" + mmsi
+	}
+	return "urn:mrn:signalk:uuid:" + uuid(shipId)
+}
+
+// uuid returns a name-based (version 5 like) UUID of name
+func uuid(name string) string {
+	sum := sha1.Sum([]byte("ship-net-bridge:" + name))
+	sum[6] = (sum[6] & 0x0f) | 0x50
+	sum[8] = (sum[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// buildDelta maps ship-nav telemetry, if fresher than maxAge, and the core's
+// navigation state to Signal K paths; ship-nav speed is taken as meters
+// per second
+func buildDelta(context string, routeHref string, state State,
+	maxAge time.Duration, now time.Time) *Delta {
+	delta := &Delta{
+		Context: context,
+		Updates: make([]*Update, 0, 2),
+	}
+
+	if t := state.LastTelemetry(); (t != nil) && (now.Sub(t.Timestamp) <= maxAge) {
+		values := []*Value{
+			{Path: "navigation.position", Value: &Position{Latitude: t.Latitude, Longitude: t.Longitude}},
+			{Path: "navigation.headingTrue", Value: radians(t.Heading)},
+			{Path: "navigation.speedOverGround", Value: t.Speed},
+		}
+		if b := t.Battery; b != nil {
+			prefix := "electrical.batteries." + batteryId + "."
+			if b.Voltage != nil {
+				values = append(values, &Value{Path: prefix + "voltage", Value: *b.Voltage})
+			}
+			if b.Current != nil {
+				values = append(values, &Value{Path: prefix + "current", Value: *b.Current})
+			}
+			if b.Charge != nil {
+				values = append(values, &Value{Path: prefix + "capacity.stateOfCharge", Value: *b.Charge / 100})
+			}
+		}
+		delta.Updates = append(delta.Updates, &Update{
+			Source:    "ship-net-bridge.ship-nav",
+			Timestamp: t.Timestamp.UTC().Format(timeFormat),
+			Values:    values,
+		})
+	}
+
+	autopilot := "standby"
+	if state.AutoNav() {
+		autopilot = "auto"
+	}
+	var activeRoute any
+	if len(state.Waypoints()) > 0 {
+		activeRoute = routeHref
+	}
+	delta.Updates = append(delta.Updates, &Update{
+		Source:    "ship-net-bridge.core",
+		Timestamp: now.UTC().Format(timeFormat),
+		Values: []*Value{
+			{Path: "steering.autopilot.state", Value: autopilot},
+			{Path: "navigation.courseGreatCircle.activeRoute.href", Value: activeRoute},
+		},
+	})
+	return delta
+}
+
+// filter returns a copy of delta with values whose paths match one
+// of patterns, see path.Match; nil if none does
+func (d *Delta) filter(patterns []string) *Delta {
+	filtered := &Delta{
+		Context: d.Context,
+		Updates: make([]*Update, 0, len(d.Updates)),
+	}
+	for _, u := range d.Updates {
+		values := make([]*Value, 0, len(u.Values))
+		for _, v := range u.Values {
+			if matchAny(patterns, v.Path) {
+				values = append(values, v)
+			}
+		}
+		if len(values) > 0 {
+			filtered.Updates = append(filtered.Updates, &Update{
+				Source:    u.Source,
+				Timestamp: u.Timestamp,
+				Values:    values,
+			})
+		}
+	}
+	if len(filtered.Updates) == 0 {
+		return nil
+	}
+	return filtered
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func radians(degrees float64) float64 {
+	return math.Mod(math.Mod(degrees, 360)+360, 360) * math.Pi / 180
+}
@@ -0,0 +1,517 @@
+package signalk
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/moosethebrown/ship-net-bridge/core"
+	"github.com/moosethebrown/ship-net-bridge/metrics"
+	"github.com/moosethebrown/ship-net-bridge/queue"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// DefaultInterval is how often deltas are sent
+	DefaultInterval = time.Second
+	// DefaultMaxAge is how long telemetry is served after it was received
+	DefaultMaxAge = 10 * time.Second
+)
+
+const (
+	streamPath      = "/signalk/v1/stream"
+	apiPath         = "/signalk/v1/api/"
+	routesPath      = "/signalk/v1/api/resources/routes"
+	writeTimeout    = 5 * time.Second
+	pongTimeout     = 30 * time.Second
+	pingInterval    = 10 * time.Second
+	shutdownTimeout = 3 * time.Second
+	maxMessageSize  = 16 * 1024
+	clientQueueSize = 16
+)
+
+// State is the vessel state Server serves, implemented by core.Core
+type State interface {
+	LastTelemetry() *core.Telemetry
+	Waypoints() []core.Waypoint
+	AutoNav() bool
+}
+
+// Publisher sends deltas on, implemented by mqtt.Vessel
+type Publisher interface {
+	Publish(topic string, payload []byte)
+}
+
+// client subscriptions are path patterns, see path.Match
+type client struct {
+	conn          *websocket.Conn
+	sendChan      chan []byte
+	subscriptions []string
+}
+
+// Server maps vessel state to the Signal K data model and sends it every
+// interval as delta messages to clients of a Signal K WebSocket stream and,
+// optionally, to an MQTT topic. Besides the stream it serves the discovery
+// document and the active route as a route resource, so that Signal K
+// clients, e.g. instrument displays and chartplotters, can connect as they
+// would to a Signal K server.
+type Server struct {
+	name         string
+	address      string
+	mqttTopic    string
+	interval     time.Duration
+	maxAge       time.Duration
+	context      string
+	routeId      string
+	state        State
+	publisher    Publisher
+	upgrader     websocket.Upgrader
+	server       *http.Server
+	logger       *zerolog.Logger
+	clientsMutex sync.Mutex
+	clients      map[*client]bool
+	statusMutex  sync.Mutex
+	deltas       int
+	lastSent     time.Time
+}
+
+type Status struct {
+	Address   string     `json:"address,omitempty"`
+	MqttTopic string     `json:"mqttTopic,omitempty"`
+	Self      string     `json:"self"`
+	Clients   int        `json:"clients"`
+	Deltas    int        `json:"deltas"`
+	LastSent  *time.Time `json:"lastSent,omitempty"`
+}
+
+// NewServer creates a server for the vessel shipId, identified by mmsi
+// if not empty; address or publisher may be empty or nil, deltas are then
+// only published or only streamed
+func NewServer(shipId string, mmsi string, address string, allowedOrigins []string,
+	publisher Publisher, mqttTopic string, interval time.Duration, maxAge time.Duration,
+	state State, logger *zerolog.Logger) *Server {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	if maxAge <= 0 {
+		maxAge = DefaultMaxAge
+	}
+
+	s := &Server{
+		name:      shipId,
+		address:   address,
+		mqttTopic: mqttTopic,
+		interval:  interval,
+		maxAge:    maxAge,
+		context:   "vessels." + VesselUrn(shipId, mmsi),
+		routeId:   "urn:mrn:signalk:uuid:" + uuid(shipId+":route"),
+		state:     state,
+		publisher: publisher,
+		logger:    logger,
+		clients:   make(map[*client]bool),
+	}
+
+	s.upgrader = websocket.Upgrader{
+		ReadBufferSize:  4096,
+		WriteBufferSize: 4096,
+	}
+	if len(allowedOrigins) > 0 {
+		s.upgrader.CheckOrigin = func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			return (origin == "") || slices.Contains(allowedOrigins, "*") ||
+				slices.Contains(allowedOrigins, origin)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /signalk", s.handleDiscovery)
+	mux.HandleFunc("GET "+streamPath, s.handleStream)
+	mux.HandleFunc("GET "+routesPath, s.handleRoutes)
+	mux.HandleFunc("GET "+routesPath+"/{id}", s.handleRoute)
+	s.server = &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	return s
+}
+
+// Run serves until ctx is cancelled, returns an error when the address
+// can't be listened on
+func (s *Server) Run(ctx context.Context) error {
+	errChan := make(chan error, 1)
+	if s.address != "" {
+		s.logger.Info().Msgf("listening on %s", s.address)
+		go func() {
+			errChan <- s.server.ListenAndServe()
+		}()
+	}
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			s.send(buildDelta(s.context, s.routeHref(), s.state, s.maxAge, now))
+		case err := <-errChan:
+			s.logger.Error().Err(err).Msgf("Failed to listen on %s", s.address)
+			metrics.Errors.WithLabelValues("signalk", "listen").Inc()
+			return err
+		case <-ctx.Done():
+			// a shut down http.Server can't serve again, so it's only
+			// done here and not when listening fails
+			if s.address != "" {
+				s.shutdown()
+				<-errChan
+			}
+			return nil
+		}
+	}
+}
+
+func (s *Server) shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err := s.server.Shutdown(ctx)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("failed to shut down signal k server")
+	}
+
+	// hijacked connections are not closed by http.Server.Shutdown
+	s.clientsMutex.Lock()
+	defer s.clientsMutex.Unlock()
+	for cl := range s.clients {
+		cl.conn.Close()
+	}
+}
+
+// Status can be called from any goroutine
+func (s *Server) Status() any {
+	status := &Status{
+		Address:   s.address,
+		MqttTopic: s.mqttTopic,
+		Self:      s.context,
+	}
+
+	s.clientsMutex.Lock()
+	status.Clients = len(s.clients)
+	s.clientsMutex.Unlock()
+
+	s.statusMutex.Lock()
+	defer s.statusMutex.Unlock()
+	status.Deltas = s.deltas
+	if !s.lastSent.IsZero() {
+		lastSent := s.lastSent
+		status.LastSent = &lastSent
+	}
+	return status
+}
+
+func (s *Server) routeHref() string {
+	return "/resources/routes/" + s.routeId
+}
+
+func (s *Server) send(delta *Delta) {
+	if s.publisher != nil && s.mqttTopic != "" {
+		msg, err := json.Marshal(delta)
+		if err != nil {
+			s.logger.Error().Err(err).Msg("failed to marshal delta")
+			return
+		}
+		s.publisher.Publish(s.mqttTopic, msg)
+	}
+
+	s.clientsMutex.Lock()
+	for cl := range s.clients {
+		filtered := delta.filter(cl.subscriptions)
+		if filtered == nil {
+			continue
+		}
+		msg, err := json.Marshal(filtered)
+		if err != nil {
+			s.logger.Error().Err(err).Msg("failed to marshal delta")
+			continue
+		}
+		s.enqueue(cl, msg)
+	}
+	s.clientsMutex.Unlock()
+
+	s.statusMutex.Lock()
+	s.deltas++
+	s.lastSent = time.Now()
+	s.statusMutex.Unlock()
+}
+
+// enqueue must be called with clientsMutex locked
+func (s *Server) enqueue(cl *client, msg []byte) {
+	select {
+	case cl.sendChan <- msg:
+	default:
+		s.logger.Error().Msgf("send queue of client %s is full, dropping delta",
+			cl.conn.RemoteAddr())
+		metrics.Overflows.WithLabelValues("signalk", "client",
+			queue.DropNewest.String()).Inc()
+	}
+}
+
+type discovery struct {
+	Endpoints map[string]*endpoint `json:"endpoints"`
+	Server    *serverInfo          `json:"server"`
+}
+
+type endpoint struct {
+	Version string `json:"version"`
+	Http    string `json:"signalk-http"`
+	Ws      string `json:"signalk-ws"`
+}
+
+type serverInfo struct {
+	Id      string `json:"id"`
+	Version string `json:"version"`
+}
+
+func (s *Server) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, &discovery{
+		Endpoints: map[string]*endpoint{
+			"v1": {
+				Version: Version,
+				Http:    "http://" + r.Host + apiPath,
+				Ws:      "ws://" + r.Host + streamPath,
+			},
+		},
+		Server: &serverInfo{
+			Id:      "ship-net-bridge",
+			Version: Version,
+		},
+	})
+}
+
+// route is a Signal K route resource, a GeoJSON feature
+type route struct {
+	Name    string   `json:"name"`
+	Feature *feature `json:"feature"`
+}
+
+type feature struct {
+	Type       string         `json:"type"`
+	Geometry   *geometry      `json:"geometry"`
+	Properties map[string]any `json:"properties"`
+}
+
+type geometry struct {
+	Type string `json:"type"`
+	// Coordinates are longitude, latitude pairs
+	Coordinates [][2]float64 `json:"coordinates"`
+}
+
+// activeRoute returns nil when there are no waypoints
+func (s *Server) activeRoute() *route {
+	waypoints := s.state.Waypoints()
+	if len(waypoints) == 0 {
+		return nil
+	}
+	coordinates := make([][2]float64, len(waypoints))
+	for i, wp := range waypoints {
+		coordinates[i] = [2]float64{wp.Longitude, wp.Latitude}
+	}
+	return &route{
+		Name: s.name,
+		Feature: &feature{
+			Type: "Feature",
+			Geometry: &geometry{
+				Type:        "LineString",
+				Coordinates: coordinates,
+			},
+			Properties: map[string]any{},
+		},
+	}
+}
+
+func (s *Server) handleRoutes(w http.ResponseWriter, r *http.Request) {
+	routes := map[string]*route{}
+	if rt := s.activeRoute(); rt != nil {
+		routes[s.routeId] = rt
+	}
+	writeJson(w, routes)
+}
+
+func (s *Server) handleRoute(w http.ResponseWriter, r *http.Request) {
+	rt := s.activeRoute()
+	if (rt == nil) || (r.PathValue("id") != s.routeId) {
+		http.Error(w, "route not found", http.StatusNotFound)
+		return
+	}
+	writeJson(w, rt)
+}
+
+func writeJson(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(value)
+}
+
+type hello struct {
+	Name      string   `json:"name"`
+	Version   string   `json:"version"`
+	Self      string   `json:"self"`
+	Roles     []string `json:"roles"`
+	Timestamp string   `json:"timestamp"`
+}
+
+// subscription is a subscribe or unsubscribe message of a client
+type subscription struct {
+	Context     string      `json:"context"`
+	Subscribe   []*pathSpec `json:"subscribe"`
+	Unsubscribe []*pathSpec `json:"unsubscribe"`
+}
+
+type pathSpec struct {
+	Path string `json:"path"`
+}
+
+// handleStream subscribes clients to all paths of the vessel, unless
+// the subscribe query parameter is "none"; the vessel is the only one
+// served, so "all" is the same as "self"
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	var subscriptions []string
+	switch r.URL.Query().Get("subscribe") {
+	case "", "self", "all":
+		subscriptions = []string{"*"}
+	case "none":
+	default:
+		http.Error(w, "subscribe must be self, all or none", http.StatusBadRequest)
+		return
+	}
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("failed to upgrade connection")
+		return
+	}
+
+	cl := &client{
+		conn:          conn,
+		sendChan:      make(chan []byte, clientQueueSize),
+		subscriptions: subscriptions,
+	}
+
+	msg, err := json.Marshal(&hello{
+		Name:      "ship-net-bridge",
+		Version:   Version,
+		Self:      s.context,
+		Roles:     []string{"master", "main"},
+		Timestamp: time.Now().UTC().Format(timeFormat),
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Msg("failed to marshal hello message")
+		conn.Close()
+		return
+	}
+	cl.sendChan <- msg
+
+	s.clientsMutex.Lock()
+	s.clients[cl] = true
+	s.clientsMutex.Unlock()
+	s.logger.Info().Msgf("client %s connected", conn.RemoteAddr())
+
+	go s.writeLoop(cl)
+	s.readLoop(cl)
+
+	s.clientsMutex.Lock()
+	delete(s.clients, cl)
+	close(cl.sendChan)
+	s.clientsMutex.Unlock()
+	s.logger.Info().Msgf("client %s disconnected", conn.RemoteAddr())
+}
+
+func (s *Server) readLoop(cl *client) {
+	defer cl.conn.Close()
+
+	cl.conn.SetReadLimit(maxMessageSize)
+	cl.conn.SetReadDeadline(time.Now().Add(pongTimeout))
+	cl.conn.SetPongHandler(func(string) error {
+		return cl.conn.SetReadDeadline(time.Now().Add(pongTimeout))
+	})
+
+	for {
+		msgType, msg, err := cl.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure,
+				websocket.CloseGoingAway) {
+				s.logger.Error().Err(err).Msgf("failed to read from client %s",
+					cl.conn.RemoteAddr())
+			}
+			return
+		}
+		if msgType != websocket.TextMessage {
+			continue
+		}
+
+		sub := &subscription{}
+		if err := json.Unmarshal(msg, sub); err != nil {
+			s.logger.Error().Err(err).Msgf("invalid message from client %s", cl.conn.RemoteAddr())
+			metrics.Errors.WithLabelValues("signalk", "message").Inc()
+			continue
+		}
+		s.subscribe(cl, sub)
+	}
+}
+
+// subscribe applies a subscription for the vessel, ignores it
+// for any other context
+func (s *Server) subscribe(cl *client, sub *subscription) {
+	if !matchAny([]string{sub.Context}, s.context) && (sub.Context != "vessels.self") {
+		return
+	}
+
+	s.clientsMutex.Lock()
+	defer s.clientsMutex.Unlock()
+	for _, spec := range sub.Unsubscribe {
+		if spec.Path == "*" {
+			cl.subscriptions = nil
+			continue
+		}
+		cl.subscriptions = slices.DeleteFunc(cl.subscriptions, func(pattern string) bool {
+			return pattern == spec.Path
+		})
+	}
+	for _, spec := range sub.Subscribe {
+		if (spec.Path != "") && !slices.Contains(cl.subscriptions, spec.Path) {
+			cl.subscriptions = append(cl.subscriptions, spec.Path)
+		}
+	}
+}
+
+func (s *Server) writeLoop(cl *client) {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	defer cl.conn.Close()
+
+	for {
+		select {
+		case msg, ok := <-cl.sendChan:
+			if !ok {
+				return
+			}
+			cl.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			err := cl.conn.WriteMessage(websocket.TextMessage, msg)
+			if err != nil {
+				s.logger.Error().Err(err).Msgf("failed to write to client %s",
+					cl.conn.RemoteAddr())
+				return
+			}
+		case <-ticker.C:
+			cl.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			err := cl.conn.WriteMessage(websocket.PingMessage, nil)
+			if err != nil {
+				return
+			}
+		}
+	}
+}
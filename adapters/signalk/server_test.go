@@ -0,0 +1,218 @@
+package signalk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/moosethebrown/ship-net-bridge/core"
+	"github.com/rs/zerolog"
+)
+
+type mockState struct {
+	telemetry *core.Telemetry
+	waypoints []core.Waypoint
+	autoNav   bool
+}
+
+func (m *mockState) LastTelemetry() *core.Telemetry {
+	return m.telemetry
+}
+
+func (m *mockState) Waypoints() []core.Waypoint {
+	return m.waypoints
+}
+
+func (m *mockState) AutoNav() bool {
+	return m.autoNav
+}
+
+type mockPublisher struct {
+	topic   string
+	payload []byte
+}
+
+func (m *mockPublisher) Publish(topic string, payload []byte) {
+	m.topic = topic
+	m.payload = payload
+}
+
+func values(delta *Delta) map[string]any {
+	values := make(map[string]any)
+	for _, u := range delta.Updates {
+		for _, v := range u.Values {
+			values[v.Path] = v.Value
+		}
+	}
+	return values
+}
+
+func TestDelta(t *testing.T) {
+	now := time.Now()
+	charge := 80.0
+	state := &mockState{
+		telemetry: &core.Telemetry{Latitude: 56.348284, Longitude: 43.95941, Heading: -90, Speed: 2,
+			Battery: &core.Battery{Charge: &charge}, Timestamp: now},
+		autoNav: true,
+	}
+
+	delta := buildDelta("vessels.self", "/resources/routes/r", state, time.Second, now)
+	v := values(delta)
+	if pos := v["navigation.position"].(*Position); (pos.Latitude != 56.348284) || (pos.Longitude != 43.95941) {
+		t.Errorf("Unexpected position %+v", pos)
+	}
+	if heading := v["navigation.headingTrue"].(float64); math.Abs(heading-1.5*math.Pi) > 1e-9 {
+		t.Errorf("Unexpected heading %f", heading)
+	}
+	if (v["navigation.speedOverGround"] != 2.0) || (v["electrical.batteries.main.capacity.stateOfCharge"] != 0.8) {
+		t.Errorf("Unexpected values %+v", v)
+	}
+	if _, ok := v["electrical.batteries.main.voltage"]; ok {
+		t.Errorf("Expected voltage not reported by ship-nav to be missing")
+	}
+	if (v["steering.autopilot.state"] != "auto") || (v["navigation.courseGreatCircle.activeRoute.href"] != nil) {
+		t.Errorf("Unexpected navigation state %+v", v)
+	}
+
+	// stale telemetry isn't sent
+	state.waypoints = []core.Waypoint{{Latitude: 56.36, Longitude: 43.9}}
+	delta = buildDelta("vessels.self", "/resources/routes/r", state, time.Second, now.Add(2*time.Second))
+	v = values(delta)
+	if (len(delta.Updates) != 1) || (v["navigation.courseGreatCircle.activeRoute.href"] != "/resources/routes/r") {
+		t.Errorf("Unexpected delta %+v", v)
+	}
+
+	if filtered := delta.filter([]string{"navigation.*"}); (filtered == nil) || (len(values(filtered)) != 1) {
+		t.Errorf("Expected only the active route, got %+v", filtered)
+	}
+	if filtered := delta.filter([]string{"electrical.*"}); filtered != nil {
+		t.Errorf("Expected nothing, got %+v", filtered)
+	}
+}
+
+func TestVesselUrn(t *testing.T) {
+	if urn := VesselUrn("ship", "230123450"); urn != "urn:mrn:imo:mmsi:230123450" {
+		t.Errorf("Unexpected urn %s", urn)
+	}
+	urn := VesselUrn("ship", "")
+	if (urn != VesselUrn("ship", "")) || (urn == VesselUrn("other", "")) ||
+		!strings.HasPrefix(urn, "urn:mrn:signalk:uuid:") || (len(urn) != 21+36) || (urn[21+14] != '5') {
+		t.Errorf("Unexpected urn %s", urn)
+	}
+}
+
+func TestStream(t *testing.T) {
+	logger := zerolog.New(os.Stdout).Level(zerolog.DebugLevel)
+	state := &mockState{
+		telemetry: &core.Telemetry{Latitude: 56.348284, Longitude: 43.95941, Heading: 90, Speed: 2, Timestamp: time.Now()},
+		waypoints: []core.Waypoint{{Latitude: 56.36, Longitude: 43.9}, {Latitude: 56.37, Longitude: 43.8}},
+	}
+	publisher := &mockPublisher{}
+	s := NewServer("ship", "", "127.0.0.1:0", nil, publisher, "ship/ship/signalk", 0, 0, state, &logger)
+	srv := httptest.NewServer(s.server.Handler)
+	defer srv.Close()
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + streamPath
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %s", err)
+	}
+	defer conn.Close()
+	read := func(v any) {
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		if err := conn.ReadJSON(v); err != nil {
+			t.Fatalf("Failed to read message: %s", err)
+		}
+	}
+
+	h := &hello{}
+	read(h)
+	if (h.Self != s.context) || (h.Version != Version) {
+		t.Errorf("Unexpected hello %+v", h)
+	}
+
+	conn.WriteJSON(&subscription{
+		Context:     "vessels.self",
+		Unsubscribe: []*pathSpec{{Path: "*"}},
+		Subscribe:   []*pathSpec{{Path: "navigation.position"}},
+	})
+	// the subscription is applied by the read loop
+	time.Sleep(100 * time.Millisecond)
+
+	s.send(buildDelta(s.context, s.routeHref(), state, s.maxAge, time.Now()))
+	delta := &Delta{}
+	read(delta)
+	if (delta.Context != s.context) || (len(delta.Updates) != 1) || (len(delta.Updates[0].Values) != 1) ||
+		(delta.Updates[0].Values[0].Path != "navigation.position") {
+		t.Errorf("Unexpected delta %+v", delta)
+	}
+
+	// everything is published to MQTT
+	published := &Delta{}
+	json.Unmarshal(publisher.payload, published)
+	if (publisher.topic != "ship/ship/signalk") || (len(published.Updates) != 2) {
+		t.Errorf("Unexpected published delta %s", publisher.payload)
+	}
+
+	resp, err := srv.Client().Get(srv.URL + routesPath + "/" + s.routeId)
+	if err != nil {
+		t.Fatalf("Failed to get route: %s", err)
+	}
+	defer resp.Body.Close()
+	rt := &route{}
+	json.NewDecoder(resp.Body).Decode(rt)
+	if (rt.Feature == nil) || (len(rt.Feature.Geometry.Coordinates) != 2) ||
+		(rt.Feature.Geometry.Coordinates[1] != [2]float64{43.8, 56.37}) {
+		t.Errorf("Unexpected route %+v", rt)
+	}
+}
+
+func TestRestart(t *testing.T) {
+	logger := zerolog.New(os.Stdout).Level(zerolog.DebugLevel)
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	address := busy.Addr().String()
+	s := NewServer("ship", "", address, nil, &mockPublisher{}, "", 0, 0, &mockState{}, &logger)
+
+	// the address is taken, the supervisor runs the server again later
+	err = s.Run(context.Background())
+	if (err == nil) || errors.Is(err, http.ErrServerClosed) {
+		t.Fatalf("Expected listen error, got %v", err)
+	}
+	busy.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- s.Run(ctx)
+	}()
+	var resp *http.Response
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		resp, err = http.Get("http://" + address + "/signalk")
+		if err == nil {
+			resp.Body.Close()
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Errorf("Expected the server to serve after restart, got %s", err)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Expected the server to stop cleanly, got %s", err)
+	}
+}
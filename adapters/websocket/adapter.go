@@ -0,0 +1,330 @@
+package websocket
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/moosethebrown/ship-net-bridge/core"
+	"github.com/moosethebrown/ship-net-bridge/metrics"
+	"github.com/moosethebrown/ship-net-bridge/queue"
+	"github.com/rs/zerolog"
+)
+
+const (
+	writeTimeout    = 5 * time.Second
+	pongTimeout     = 30 * time.Second
+	pingInterval    = 10 * time.Second
+	shutdownTimeout = 3 * time.Second
+	maxMessageSize  = 64 * 1024
+
+	defaultClientQueueSize = 100
+)
+
+// client is a transport of its own, so that correlated responses
+// are sent only to the client that made the request
+type client struct {
+	adapter  *Adapter
+	conn     *websocket.Conn
+	sendChan chan []byte
+}
+
+// Adapter serves the same request/response protocol as the MQTT adapter
+// to clients connected over WebSocket: text messages received from clients
+// are requests, uncorrelated responses and announces are broadcast
+// to all clients
+type Adapter struct {
+	address         string
+	path            string
+	token           string
+	shipId          string
+	clientQueueSize int
+	core            *core.Core
+	upgrader        websocket.Upgrader
+	server          *http.Server
+	clients         map[*client]bool
+	clientsMutex    sync.Mutex
+	logger          *zerolog.Logger
+}
+
+type Status struct {
+	Address string `json:"address"`
+	Clients int    `json:"clients"`
+}
+
+func NewAdapter(address string, path string, token string, shipId string,
+	allowedOrigins []string, clientQueueSize int, theCore *core.Core,
+	logger *zerolog.Logger) *Adapter {
+	if clientQueueSize <= 0 {
+		clientQueueSize = defaultClientQueueSize
+	}
+
+	a := &Adapter{
+		address:         address,
+		path:            path,
+		token:           token,
+		shipId:          shipId,
+		clientQueueSize: clientQueueSize,
+		core:            theCore,
+		clients:         make(map[*client]bool),
+		logger:          logger,
+	}
+
+	a.upgrader = websocket.Upgrader{
+		ReadBufferSize:  4096,
+		WriteBufferSize: 4096,
+	}
+	if len(allowedOrigins) > 0 {
+		a.upgrader.CheckOrigin = func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			return (origin == "") || slices.Contains(allowedOrigins, "*") ||
+				slices.Contains(allowedOrigins, origin)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, a.handleConnection)
+	a.server = &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	return a
+}
+
+// Run serves until ctx is cancelled
+func (a *Adapter) Run(ctx context.Context) error {
+	a.logger.Info().Msgf("listening on %s%s", a.address, a.path)
+	defer a.logger.Info().Msg("stopping")
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- a.server.ListenAndServe()
+	}()
+
+	select {
+	case <-ctx.Done():
+		a.shutdown()
+		<-errChan
+		return nil
+	case err := <-errChan:
+		a.logger.Error().Err(err).Msg("websocket server failed")
+		return err
+	}
+}
+
+func (a *Adapter) shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err := a.server.Shutdown(ctx)
+	if err != nil {
+		a.logger.Error().Err(err).Msg("failed to shut down websocket server")
+	}
+
+	// hijacked connections are not closed by http.Server.Shutdown
+	a.clientsMutex.Lock()
+	defer a.clientsMutex.Unlock()
+	for cl := range a.clients {
+		cl.conn.Close()
+	}
+}
+
+func (a *Adapter) Name() string {
+	return "websocket"
+}
+
+func (a *Adapter) SendResponse(resp []byte) {
+	a.broadcast(resp)
+}
+
+func (a *Adapter) Announce() {
+	msg, err := json.Marshal(&core.Response{
+		Type: core.ResponseTypeAnnounce,
+		Data: a.shipId,
+	})
+	if err != nil {
+		a.logger.Error().Err(err).Msg("failed to marshal announce message")
+		return
+	}
+
+	a.broadcast(msg)
+}
+
+// Status can be called from any goroutine
+func (a *Adapter) Status() any {
+	a.clientsMutex.Lock()
+	defer a.clientsMutex.Unlock()
+
+	return &Status{
+		Address: a.address,
+		Clients: len(a.clients),
+	}
+}
+
+func (a *Adapter) QueueDepths() map[string]int {
+	a.clientsMutex.Lock()
+	defer a.clientsMutex.Unlock()
+
+	depth := 0
+	for cl := range a.clients {
+		depth += len(cl.sendChan)
+	}
+
+	return map[string]int{
+		"responses": depth,
+	}
+}
+
+func (a *Adapter) broadcast(msg []byte) {
+	a.clientsMutex.Lock()
+	defer a.clientsMutex.Unlock()
+
+	for cl := range a.clients {
+		a.enqueue(cl, msg)
+	}
+}
+
+// enqueue must be called with clientsMutex locked
+func (a *Adapter) enqueue(cl *client, msg []byte) {
+	select {
+	case cl.sendChan <- msg:
+	default:
+		a.logger.Error().Msgf("send queue of client %s is full, dropping message",
+			cl.conn.RemoteAddr())
+		metrics.Overflows.WithLabelValues("websocket", "client",
+			queue.DropNewest.String()).Inc()
+	}
+}
+
+// authorized fails closed, nobody is without a token
+func (a *Adapter) authorized(r *http.Request) bool {
+	if a.token == "" {
+		return false
+	}
+
+	token := r.URL.Query().Get("token")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimPrefix(auth, "Bearer ")
+	}
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) == 1
+}
+
+func (a *Adapter) handleConnection(w http.ResponseWriter, r *http.Request) {
+	if !a.authorized(r) {
+		a.logger.Error().Msgf("unauthorized connection attempt from %s", r.RemoteAddr)
+		metrics.Errors.WithLabelValues("websocket", "unauthorized").Inc()
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	conn, err := a.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		a.logger.Error().Err(err).Msg("failed to upgrade connection")
+		return
+	}
+
+	cl := &client{
+		adapter:  a,
+		conn:     conn,
+		sendChan: make(chan []byte, a.clientQueueSize),
+	}
+
+	a.clientsMutex.Lock()
+	a.clients[cl] = true
+	a.clientsMutex.Unlock()
+	metrics.WebSocketClients.Inc()
+	a.logger.Info().Msgf("client %s connected", conn.RemoteAddr())
+
+	go a.writeLoop(cl)
+	a.readLoop(cl)
+
+	a.clientsMutex.Lock()
+	delete(a.clients, cl)
+	close(cl.sendChan)
+	a.clientsMutex.Unlock()
+	metrics.WebSocketClients.Dec()
+	a.logger.Info().Msgf("client %s disconnected", conn.RemoteAddr())
+}
+
+func (a *Adapter) readLoop(cl *client) {
+	defer cl.conn.Close()
+
+	cl.conn.SetReadLimit(maxMessageSize)
+	cl.conn.SetReadDeadline(time.Now().Add(pongTimeout))
+	cl.conn.SetPongHandler(func(string) error {
+		return cl.conn.SetReadDeadline(time.Now().Add(pongTimeout))
+	})
+
+	for {
+		msgType, msg, err := cl.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure,
+				websocket.CloseGoingAway) {
+				a.logger.Error().Err(err).Msgf("failed to read from client %s",
+					cl.conn.RemoteAddr())
+			}
+			return
+		}
+		if msgType != websocket.TextMessage {
+			continue
+		}
+
+		a.logger.Debug().Msgf("received request: %s", string(msg))
+		a.core.HandleRequest(cl, msg)
+	}
+}
+
+func (a *Adapter) writeLoop(cl *client) {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	defer cl.conn.Close()
+
+	for {
+		select {
+		case msg, ok := <-cl.sendChan:
+			if !ok {
+				return
+			}
+			cl.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			err := cl.conn.WriteMessage(websocket.TextMessage, msg)
+			if err != nil {
+				a.logger.Error().Err(err).Msgf("failed to write to client %s",
+					cl.conn.RemoteAddr())
+				return
+			}
+		case <-ticker.C:
+			cl.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			err := cl.conn.WriteMessage(websocket.PingMessage, nil)
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (cl *client) Name() string {
+	return "websocket " + cl.conn.RemoteAddr().String()
+}
+
+func (cl *client) SendResponse(resp []byte) {
+	cl.adapter.clientsMutex.Lock()
+	defer cl.adapter.clientsMutex.Unlock()
+
+	// the client may have disconnected while its request was processed
+	if cl.adapter.clients[cl] {
+		cl.adapter.enqueue(cl, resp)
+	}
+}
+
+// Announce is broadcast by the adapter itself
+func (cl *client) Announce() {
+}
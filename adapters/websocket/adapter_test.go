@@ -0,0 +1,92 @@
+package websocket
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/moosethebrown/ship-net-bridge/core"
+	"github.com/rs/zerolog"
+)
+
+func setup(token string) (*Adapter, *httptest.Server) {
+	logger := zerolog.New(os.Stdout).With().Timestamp().Logger().Level(zerolog.DebugLevel)
+
+	theCore := core.NewCore(nil, nil, 3000, &logger)
+	a := NewAdapter("127.0.0.1:0", "/ws", token, "TestShip", nil, 10, theCore, &logger)
+	srv := httptest.NewServer(http.HandlerFunc(a.handleConnection))
+
+	return a, srv
+}
+
+func waitForClients(a *Adapter, n int) bool {
+	for i := 0; i < 100; i++ {
+		a.clientsMutex.Lock()
+		count := len(a.clients)
+		a.clientsMutex.Unlock()
+		if count == n {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func TestBroadcast(t *testing.T) {
+	a, srv := setup("secret")
+	defer srv.Close()
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?token=secret", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %s", err)
+	}
+	defer conn.Close()
+
+	if !waitForClients(a, 1) {
+		t.Fatalf("Client was not registered")
+	}
+
+	a.SendResponse([]byte(`{"type":"query"}`))
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, msg, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("Failed to read response: %s", err)
+	}
+	if string(msg) != `{"type":"query"}` {
+		t.Errorf("Unexpected response: %s", string(msg))
+	}
+}
+
+func TestUnauthorized(t *testing.T) {
+	_, srv := setup("secret")
+	defer srv.Close()
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err == nil {
+		t.Fatalf("Expected connection without token to fail")
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", resp.StatusCode)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?token=secret", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect with token: %s", err)
+	}
+	conn.Close()
+	// without a configured token nobody is authorized, not even with an empty one
+	_, noToken := setup("")
+	defer noToken.Close()
+	url = "ws" + strings.TrimPrefix(noToken.URL, "http")
+	_, resp, err = websocket.DefaultDialer.Dial(url+"?token=", nil)
+	if (err == nil) || (resp.StatusCode != http.StatusUnauthorized) {
+		t.Errorf("Expected connection without configured token to fail, got %v", err)
+	}
+}
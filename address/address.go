@@ -0,0 +1,71 @@
+// Package address parses the addresses and names settings refer to, it is
+// shared by config validation and the adapters using them
+package address
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Daemon splits a daemon address into network and dial address: a unix
+// socket path or a unix:///path, tcp://host:port, tls://host:port or
+// serial:///dev/ttyUSB0 URL
+func Daemon(address string) (network string, addr string, err error) {
+	if !strings.Contains(address, "://") {
+		return "unix", address, nil
+	}
+
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", "", err
+	}
+	switch u.Scheme {
+	case "unix", "serial":
+		if u.Path == "" {
+			return "", "", fmt.Errorf("%s path is missing", u.Scheme)
+		}
+		return u.Scheme, u.Path, nil
+	case "tcp", "tls":
+		if (u.Hostname() == "") || (u.Port() == "") {
+			return "", "", fmt.Errorf("%s address must be of the form %s://host:port", u.Scheme, u.Scheme)
+		}
+		return u.Scheme, u.Host, nil
+	}
+	return "", "", fmt.Errorf("unsupported scheme %s, expected unix, tcp, tls or serial", u.Scheme)
+}
+
+// Nmea splits an NMEA source address into network and address:
+// serial:///dev/ttyUSB0, tcp://host:port (connecting to e.g. a multiplexer)
+// or udp://[host]:port (listening for broadcasts)
+func Nmea(address string) (network string, addr string, err error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", "", err
+	}
+	switch u.Scheme {
+	case "serial":
+		if u.Path == "" {
+			return "", "", errors.New("serial path is missing")
+		}
+		return u.Scheme, u.Path, nil
+	case "tcp":
+		if (u.Hostname() == "") || (u.Port() == "") {
+			return "", "", errors.New("tcp address must be of the form tcp://host:port")
+		}
+		return u.Scheme, u.Host, nil
+	case "udp":
+		if u.Port() == "" {
+			return "", "", errors.New("udp address must be of the form udp://[host]:port")
+		}
+		return u.Scheme, u.Host, nil
+	}
+	return "", "", fmt.Errorf("unsupported scheme %q, expected serial, tcp or udp", u.Scheme)
+}
+
+// Topic returns the default MQTT topic of shipId, e.g. ship/<shipId>/request
+// for name request
+func Topic(shipId string, name string) string {
+	return fmt.Sprintf("ship/%s/%s", shipId, name)
+}
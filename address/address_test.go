@@ -0,0 +1,26 @@
+package address
+
+import "testing"
+
+func TestDaemon(t *testing.T) {
+	for address, expected := range map[string][2]string{
+		"/tmp/scsocket":        {"unix", "/tmp/scsocket"},
+		"unix:///tmp/scsocket": {"unix", "/tmp/scsocket"},
+		"tcp://10.0.0.2:7001":  {"tcp", "10.0.0.2:7001"},
+		"tls://nav.local:7002": {"tls", "nav.local:7002"},
+		"http://10.0.0.2:7001": {"", ""},
+		"tcp://10.0.0.2":       {"", ""},
+		"unix://":              {"", ""},
+	} {
+		network, addr, err := Daemon(address)
+		if expected[0] == "" {
+			if err == nil {
+				t.Errorf("Expected %s to be rejected", address)
+			}
+			continue
+		}
+		if (err != nil) || (network != expected[0]) || (addr != expected[1]) {
+			t.Errorf("Expected %s to be %v, got %s %s %v", address, expected, network, addr, err)
+		}
+	}
+}
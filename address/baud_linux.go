@@ -0,0 +1,27 @@
+package address
+
+import "golang.org/x/sys/unix"
+
+var baudRates = map[int]uint32{
+	4800:   unix.B4800,
+	9600:   unix.B9600,
+	19200:  unix.B19200,
+	38400:  unix.B38400,
+	57600:  unix.B57600,
+	115200: unix.B115200,
+	230400: unix.B230400,
+	460800: unix.B460800,
+	921600: unix.B921600,
+}
+
+// ValidBaudRate tells whether serial ports can be opened with baudRate
+func ValidBaudRate(baudRate int) bool {
+	_, ok := baudRates[baudRate]
+	return ok
+}
+
+// BaudSpeed returns the termios speed of baudRate
+func BaudSpeed(baudRate int) (uint32, bool) {
+	speed, ok := baudRates[baudRate]
+	return speed, ok
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package address
+
+// ValidBaudRate tells whether serial ports can be opened with baudRate,
+// only the daemon default is accepted where serial ports aren't supported
+func ValidBaudRate(baudRate int) bool {
+	return baudRate == 115200
+}
@@ -0,0 +1,249 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/moosethebrown/ship-net-bridge/config"
+	"github.com/moosethebrown/ship-net-bridge/core"
+	"github.com/moosethebrown/ship-net-bridge/metrics"
+	"github.com/rs/zerolog"
+)
+
+const shutdownTimeout = 3 * time.Second
+
+type StatusReporter interface {
+	Status() any
+}
+
+type HealthChecker interface {
+	Healthy() bool
+}
+
+// Reloader re-reads configuration and applies what can be applied live
+type Reloader interface {
+	Reload() (*config.Changes, error)
+}
+
+type component struct {
+	name     string
+	reporter StatusReporter
+}
+
+type vessel struct {
+	shipId  string
+	theCore *core.Core
+}
+
+// Server is an HTTP server exposing bridge state for monitoring and debugging
+type Server struct {
+	address    string
+	cfg        *config.Config
+	cfgMutex   sync.Mutex
+	vessels    []*vessel
+	components []*component
+	reloader   Reloader
+	server     *http.Server
+	logger     *zerolog.Logger
+}
+
+type healthResponse struct {
+	Status    string   `json:"status"`
+	Unhealthy []string `json:"unhealthy,omitempty"`
+}
+
+type resultResponse struct {
+	Result string `json:"result"`
+}
+
+func NewServer(address string, cfg *config.Config, logger *zerolog.Logger) *Server {
+	s := &Server{
+		address:    address,
+		cfg:        cfg,
+		vessels:    make([]*vessel, 0),
+		components: make([]*component, 0),
+		logger:     logger,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", s.handleHealth)
+	mux.HandleFunc("GET /status", s.handleStatus)
+	mux.HandleFunc("GET /config", s.handleConfig)
+	mux.HandleFunc("POST /query", s.handleQuery)
+	mux.HandleFunc("POST /reload", s.handleReload)
+	mux.Handle("GET /metrics", metrics.Handler())
+
+	s.server = &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	return s
+}
+
+// AddComponent registers component reported by /status under the given name,
+// components implementing HealthChecker are also checked by /health
+func (s *Server) AddComponent(name string, reporter StatusReporter) {
+	s.components = append(s.components, &component{
+		name:     name,
+		reporter: reporter,
+	})
+}
+
+// AddVessel registers the core POST /query triggers a query on,
+// must be called before Run
+func (s *Server) AddVessel(shipId string, theCore *core.Core) {
+	s.vessels = append(s.vessels, &vessel{
+		shipId:  shipId,
+		theCore: theCore,
+	})
+}
+
+// SetReloader enables POST /reload, must be called before Run
+func (s *Server) SetReloader(reloader Reloader) {
+	s.reloader = reloader
+}
+
+// SetConfig replaces configuration reported by /config, can be called any time
+func (s *Server) SetConfig(cfg *config.Config) {
+	s.cfgMutex.Lock()
+	defer s.cfgMutex.Unlock()
+
+	s.cfg = cfg
+}
+
+// Run serves until ctx is cancelled
+func (s *Server) Run(ctx context.Context) error {
+	s.logger.Info().Msgf("listening on %s", s.address)
+	defer s.logger.Info().Msg("stopping")
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- s.server.ListenAndServe()
+	}()
+
+	select {
+	case <-ctx.Done():
+		s.shutdown()
+		<-errChan
+		return nil
+	case err := <-errChan:
+		s.logger.Error().Err(err).Msg("admin server failed")
+		return err
+	}
+}
+
+func (s *Server) shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err := s.server.Shutdown(ctx)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("failed to shut down admin server")
+	}
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	resp := &healthResponse{
+		Status: "ok",
+	}
+
+	for _, c := range s.components {
+		checker, ok := c.reporter.(HealthChecker)
+		if ok && !checker.Healthy() {
+			resp.Unhealthy = append(resp.Unhealthy, c.name)
+		}
+	}
+
+	status := http.StatusOK
+	if len(resp.Unhealthy) > 0 {
+		resp.Status = "unhealthy"
+		status = http.StatusServiceUnavailable
+	}
+
+	s.writeJson(w, status, resp)
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	resp := make(map[string]any, len(s.components))
+	for _, c := range s.components {
+		resp[c.name] = c.reporter.Status()
+	}
+
+	s.writeJson(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
+	s.cfgMutex.Lock()
+	cfg := s.cfg
+	s.cfgMutex.Unlock()
+
+	s.writeJson(w, http.StatusOK, cfg.Redacted())
+}
+
+func (s *Server) handleReload(w http.ResponseWriter, r *http.Request) {
+	if s.reloader == nil {
+		s.writeJson(w, http.StatusNotImplemented, &resultResponse{Result: "reload is not supported"})
+		return
+	}
+
+	changes, err := s.reloader.Reload()
+	if err != nil {
+		s.writeJson(w, http.StatusUnprocessableEntity, &resultResponse{Result: err.Error()})
+		return
+	}
+
+	s.writeJson(w, http.StatusOK, changes)
+}
+
+// handleQuery triggers a query on the vessel given by the vessel parameter,
+// on all vessels if it is not set
+func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
+	shipId := r.URL.Query().Get("vessel")
+	vessels := make([]*vessel, 0, len(s.vessels))
+	for _, v := range s.vessels {
+		if (shipId == "") || (v.shipId == shipId) {
+			vessels = append(vessels, v)
+		}
+	}
+	if len(vessels) == 0 {
+		s.writeJson(w, http.StatusNotFound, &resultResponse{Result: "unknown vessel"})
+		return
+	}
+
+	rq, err := json.Marshal(&core.Request{
+		Type: core.RequestTypeQuery,
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Msg("failed to marshal query")
+		s.writeJson(w, http.StatusInternalServerError, &resultResponse{Result: err.Error()})
+		return
+	}
+
+	for _, v := range vessels {
+		s.logger.Debug().Msgf("triggering query on %s", v.shipId)
+		v.theCore.HandleRequest(nil, rq)
+	}
+
+	s.writeJson(w, http.StatusAccepted, &resultResponse{Result: "ok"})
+}
+
+func (s *Server) writeJson(w http.ResponseWriter, status int, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("failed to marshal response")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, err = w.Write(data)
+	if err != nil {
+		s.logger.Debug().Err(err).Msg("failed to write response")
+	}
+}
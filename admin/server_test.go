@@ -0,0 +1,136 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/moosethebrown/ship-net-bridge/config"
+	"github.com/moosethebrown/ship-net-bridge/core"
+	"github.com/rs/zerolog"
+)
+
+type mockComponent struct {
+	healthy bool
+}
+
+func (m *mockComponent) Status() any {
+	return map[string]bool{"connected": m.healthy}
+}
+
+func (m *mockComponent) Healthy() bool {
+	return m.healthy
+}
+
+func setup() *Server {
+	logger := zerolog.New(os.Stdout).With().Timestamp().Logger().Level(zerolog.DebugLevel)
+
+	cfg := &config.Config{
+		Mqtt: &config.MqttConfig{
+			Username: "abcde",
+			Password: "12345",
+		},
+	}
+	theCore := core.NewCore(nil, nil, 3000, &logger)
+
+	s := NewServer("127.0.0.1:0", cfg, &logger)
+	s.AddVessel("TestShip", theCore)
+	return s
+}
+
+func get(s *Server, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func TestHealth(t *testing.T) {
+	s := setup()
+	component := &mockComponent{healthy: true}
+	s.AddComponent("mqtt", component)
+
+	rec := get(s, "/health")
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rec.Code)
+	}
+
+	component.healthy = false
+	rec = get(s, "/health")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "mqtt") {
+		t.Errorf("Expected unhealthy component to be listed, got %s", rec.Body.String())
+	}
+}
+
+func TestConfigRedacted(t *testing.T) {
+	s := setup()
+
+	rec := get(s, "/config")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	if strings.Contains(rec.Body.String(), "12345") {
+		t.Errorf("Password is not redacted: %s", rec.Body.String())
+	}
+	if s.cfg.Mqtt.Password != "12345" {
+		t.Errorf("Redaction modified the original config")
+	}
+}
+
+type mockReloader struct {
+	changes *config.Changes
+}
+
+func (m *mockReloader) Reload() (*config.Changes, error) {
+	return m.changes, nil
+}
+
+func TestReload(t *testing.T) {
+	s := setup()
+
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/reload", nil))
+	if rec.Code != http.StatusNotImplemented {
+		t.Errorf("Expected status 501 without reloader, got %d", rec.Code)
+	}
+
+	s.SetReloader(&mockReloader{changes: &config.Changes{
+		Applied:         []string{"logLevel"},
+		RestartRequired: []string{"admin.address"},
+	}})
+	rec = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/reload", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+
+	var changes config.Changes
+	err := json.Unmarshal(rec.Body.Bytes(), &changes)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal reload response: %s", err)
+	}
+	if (len(changes.RestartRequired) != 1) || (changes.RestartRequired[0] != "admin.address") {
+		t.Errorf("Expected admin.address to require restart, got %v", changes.RestartRequired)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	s := setup()
+
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/query?vessel=TestShip", nil))
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("Expected status 202, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/query?vessel=OtherShip", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown vessel, got %d", rec.Code)
+	}
+}
@@ -1,41 +1,93 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"sync"
 	"time"
 
+	"github.com/moosethebrown/ship-net-bridge/adapters/ais"
+	"github.com/moosethebrown/ship-net-bridge/adapters/daemon"
+	"github.com/moosethebrown/ship-net-bridge/adapters/grpc"
+	"github.com/moosethebrown/ship-net-bridge/adapters/mavlink"
 	"github.com/moosethebrown/ship-net-bridge/adapters/mqtt"
+	"github.com/moosethebrown/ship-net-bridge/adapters/nmea"
 	"github.com/moosethebrown/ship-net-bridge/adapters/shipcontrol"
 	"github.com/moosethebrown/ship-net-bridge/adapters/shipnav"
+	"github.com/moosethebrown/ship-net-bridge/adapters/signalk"
+	"github.com/moosethebrown/ship-net-bridge/adapters/websocket"
+	"github.com/moosethebrown/ship-net-bridge/admin"
 	"github.com/moosethebrown/ship-net-bridge/config"
 	"github.com/moosethebrown/ship-net-bridge/core"
+	"github.com/moosethebrown/ship-net-bridge/metrics"
+	"github.com/moosethebrown/ship-net-bridge/queue"
+	"github.com/moosethebrown/ship-net-bridge/supervisor"
+	"github.com/moosethebrown/ship-net-bridge/track"
 	"github.com/rs/zerolog"
 )
 
+// transport is a front-end managed by App
+type transport interface {
+	core.Transport
+	Run(ctx context.Context) error
+	Status() any
+	QueueDepths() map[string]int
+}
+
+const defaultShutdownTimeout = 10 * time.Second
+
 type App struct {
-	cfg                *config.Config
+	cfg             *config.Config
+	startCfg        *config.Config
+	logger          *zerolog.Logger
+	vessels         []*vessel
+	mqttAdapter     *mqtt.Adapter
+	grpcServer      *grpc.Server
+	adminServer     *admin.Server
+	supervisor      *supervisor.Supervisor
+	shutdownTimeout time.Duration
+	drainTimeout    time.Duration
+	configFile      string
+	configFormat    config.Format
+	overrides       config.Overrides
+	reloadMutex     sync.Mutex
+}
+
+// vessel is a ship served by the bridge with its own core, daemon adapters
+// and transports other than MQTT, whose connection is shared by all vessels
+type vessel struct {
+	shipId string
+	// prefix of component names, empty unless in multi-vessel mode
+	prefix             string
 	logger             *zerolog.Logger
-	mqttAdapter        *mqtt.Adapter
+	theCore            *core.Core
 	shipControlAdapter *shipcontrol.Adapter
 	shipNavAdapter     *shipnav.Adapter
-	theCore            *core.Core
-	wg                 sync.WaitGroup
+	nmeaInput          *nmea.Input
+	nmeaOutput         *nmea.Output
+	ais                *ais.Adapter
+	signalK            *signalk.Server
+	trackRecorder      *track.Recorder
+	transports         []transport
 }
 
-func NewApp(cfg *config.Config) *App {
+// NewApp creates the bridge from configuration read from configFile,
+// the file is read again on Reload and command-line overrides are reapplied
+func NewApp(cfg *config.Config, configFile string, configFormat config.Format,
+	overrides config.Overrides) *App {
 	app := &App{
-		cfg: cfg,
+		cfg:          cfg,
+		startCfg:     cfg,
+		configFile:   configFile,
+		configFormat: configFormat,
+		overrides:    overrides,
 	}
 
-	logLevel, err := zerolog.ParseLevel(cfg.LogLevel)
-	if err != nil {
-		fmt.Printf("Invalid logLevel: %s, error: %s", cfg.LogLevel, err.Error())
-		logLevel = zerolog.InfoLevel
-	}
-
-	logger := zerolog.New(os.Stdout).With().Timestamp().Logger().Level(logLevel)
+	// log level is global so that it can be changed on reload
+	app.applyLogLevel(cfg)
+	logger := zerolog.New(os.Stdout).With().Timestamp().Logger()
 	app.logger = &logger
 
 	app.init()
@@ -44,72 +96,468 @@ func NewApp(cfg *config.Config) *App {
 }
 
 func (app *App) Start() {
-	app.wg.Add(1)
-	go func() {
-		defer app.wg.Done()
-		app.theCore.Run()
-	}()
-
-	app.wg.Add(1)
-	go func() {
-		defer app.wg.Done()
-		app.shipControlAdapter.Run()
-	}()
-
-	app.wg.Add(1)
-	go func() {
-		defer app.wg.Done()
-		app.shipNavAdapter.Run()
-	}()
-
-	app.wg.Add(1)
-	go func() {
-		defer app.wg.Done()
-		err := app.mqttAdapter.Run()
-		if err != nil {
-			panic("mqtt adapter exited unexpectedly")
-		}
-	}()
+	app.supervisor.Start()
+}
+
+// Fatal receives an error when a component fails in a way
+// restarting can't fix, the app should be stopped then
+func (app *App) Fatal() <-chan error {
+	return app.supervisor.Fatal()
 }
 
 func (app *App) Stop() {
-	app.mqttAdapter.Stop()
-	app.shipNavAdapter.Stop()
-	app.shipControlAdapter.Stop()
-	app.theCore.Stop()
-	app.wg.Wait()
+	app.supervisor.Stop(app.shutdownTimeout)
 }
 
 func (app *App) init() {
-	coreLogger := app.logger.With().Str("component", "core").Logger()
-	app.theCore = core.NewCore(nil, nil, nil,
+	supervisorLogger := app.logger.With().Str("component", "supervisor").Logger()
+	app.shutdownTimeout = defaultShutdownTimeout
+	app.drainTimeout = core.DefaultDrainTimeout
+	if app.cfg.Supervisor != nil {
+		app.supervisor = supervisor.NewSupervisor(
+			time.Duration(app.cfg.Supervisor.MinBackoff)*time.Millisecond,
+			time.Duration(app.cfg.Supervisor.MaxBackoff)*time.Millisecond,
+			&supervisorLogger)
+		if app.cfg.Supervisor.ShutdownTimeout > 0 {
+			app.shutdownTimeout = time.Duration(app.cfg.Supervisor.ShutdownTimeout) * time.Millisecond
+		}
+		if app.cfg.Supervisor.DrainTimeout > 0 {
+			app.drainTimeout = time.Duration(app.cfg.Supervisor.DrainTimeout) * time.Millisecond
+		}
+	} else {
+		app.supervisor = supervisor.NewSupervisor(0, 0, &supervisorLogger)
+	}
+
+	overflowPolicy := app.overflowPolicy(app.cfg)
+	vesselConfigs := app.cfg.VesselList()
+
+	if (app.cfg.Mqtt != nil) && app.cfg.Mqtt.IsEnabled() {
+		clientId := app.cfg.Mqtt.ClientId
+		if clientId == "" {
+			clientId = vesselConfigs[0].ShipId
+		}
+		mqttLogger := app.logger.With().Str("component", "mqtt").Logger()
+		app.mqttAdapter = mqtt.NewAdapter(mqttBrokers(app.cfg.Mqtt),
+			app.cfg.Mqtt.ProtocolVersion,
+			time.Duration(app.cfg.Mqtt.MessageExpiry)*time.Millisecond,
+			app.cfg.Mqtt.OperatorProperty,
+			time.Duration(app.cfg.Mqtt.ConnTimeout)*time.Millisecond,
+			clientId,
+			app.cfg.Mqtt.AnnounceTopic,
+			time.Duration(app.cfg.Mqtt.AnnounceTimeout)*time.Millisecond,
+			time.Duration(app.cfg.Mqtt.DisconnectTimeout)*time.Millisecond,
+			app.cfg.Mqtt.FailoverThreshold,
+			time.Duration(app.cfg.Mqtt.FallbackInterval)*time.Millisecond,
+			time.Duration(app.cfg.Mqtt.ReconnectInterval)*time.Millisecond,
+			overflowPolicy,
+			&mqttLogger)
+		metrics.RegisterQueues(app.mqttAdapter.Name(), app.mqttAdapter)
+	}
+
+	if (app.cfg.Grpc != nil) && app.cfg.Grpc.IsEnabled() {
+		grpcLogger := app.logger.With().Str("component", "grpc").Logger()
+		app.grpcServer = grpc.NewServer(app.cfg.Grpc.Address,
+			app.cfg.Grpc.Token,
+			app.cfg.Grpc.CertFile,
+			app.cfg.Grpc.KeyFile,
+			time.Duration(app.cfg.Grpc.ResponseTimeout)*time.Millisecond,
+			time.Duration(app.cfg.Grpc.TelemetryInterval)*time.Millisecond,
+			app.cfg.Grpc.ClientQueueSize,
+			&grpcLogger)
+		metrics.RegisterQueues(app.grpcServer.Name(), app.grpcServer)
+	}
+
+	app.vessels = make([]*vessel, 0, len(vesselConfigs))
+	for _, vc := range vesselConfigs {
+		app.addVessel(vc, overflowPolicy)
+	}
+	// vessels drain one after another on shutdown
+	app.shutdownTimeout *= time.Duration(len(app.vessels))
+
+	if app.cfg.Admin != nil {
+		adminLogger := app.logger.With().Str("component", "admin").Logger()
+		app.adminServer = admin.NewServer(app.cfg.Admin.Address,
+			app.cfg,
+			&adminLogger)
+		for _, v := range app.vessels {
+			app.adminServer.AddVessel(v.shipId, v.theCore)
+			app.adminServer.AddComponent(v.prefix+"core", v.theCore)
+			app.adminServer.AddComponent(v.prefix+"shipControl", v.shipControlAdapter)
+			app.adminServer.AddComponent(v.prefix+"shipNav", v.shipNavAdapter)
+			if v.nmeaInput != nil {
+				app.adminServer.AddComponent(v.prefix+"nmeaInput", v.nmeaInput)
+			}
+			if v.nmeaOutput != nil {
+				app.adminServer.AddComponent(v.prefix+"nmeaOutput", v.nmeaOutput)
+			}
+			if v.ais != nil {
+				app.adminServer.AddComponent(v.prefix+"ais", v.ais)
+			}
+			if v.signalK != nil {
+				app.adminServer.AddComponent(v.prefix+"signalK", v.signalK)
+			}
+			for _, t := range v.transports {
+				app.adminServer.AddComponent(v.prefix+t.Name(), t)
+			}
+		}
+		if app.mqttAdapter != nil {
+			app.adminServer.AddComponent(app.mqttAdapter.Name(), app.mqttAdapter)
+		}
+		if app.grpcServer != nil {
+			app.adminServer.AddComponent(app.grpcServer.Name(), app.grpcServer)
+		}
+		app.adminServer.AddComponent("supervisor", app.supervisor)
+		app.adminServer.SetReloader(app)
+	}
+
+	// components are stopped in reverse order: NMEA and AIS inputs stop
+	// feeding cores, cores drain their queues into daemon adapters, which drain
+	// theirs and ship-control gets the safe-state command; transports stop
+	// after that so that responses of drained requests still reach them
+	if app.adminServer != nil {
+		app.supervisor.Add("admin", app.adminServer.Run)
+	}
+	if app.grpcServer != nil {
+		app.supervisor.Add(app.grpcServer.Name(), app.grpcServer.Run)
+	}
+	if app.mqttAdapter != nil {
+		app.supervisor.Add(app.mqttAdapter.Name(), app.mqttAdapter.Run)
+	}
+	for _, v := range app.vessels {
+		for _, t := range v.transports {
+			app.supervisor.Add(v.prefix+t.Name(), t.Run)
+		}
+	}
+	for _, v := range app.vessels {
+		// the safe-state command is sent even when shutdown takes too long
+		app.supervisor.AddWithTimeout(v.prefix+"ship-control", v.shipControlAdapter.Run,
+			v.shipControlAdapter.ShutdownTimeout())
+		app.supervisor.Add(v.prefix+"ship-nav", v.shipNavAdapter.Run)
+		theCore := v.theCore
+		app.supervisor.Add(v.prefix+"core", func(ctx context.Context) error {
+			theCore.Run(ctx)
+			return nil
+		})
+		if v.nmeaInput != nil {
+			app.supervisor.Add(v.prefix+"nmea-input", v.nmeaInput.Run)
+		}
+		if v.nmeaOutput != nil {
+			app.supervisor.Add(v.prefix+"nmea-output", v.nmeaOutput.Run)
+		}
+		if v.ais != nil {
+			app.supervisor.Add(v.prefix+"ais", v.ais.Run)
+		}
+		if v.signalK != nil {
+			app.supervisor.Add(v.prefix+"signalk", v.signalK.Run)
+		}
+	}
+}
+
+// addVessel creates core, daemon adapters and transports of a vessel
+func (app *App) addVessel(vc *config.VesselConfig, overflowPolicy queue.Policy) {
+	v := &vessel{
+		shipId:     vc.ShipId,
+		logger:     app.logger,
+		transports: make([]transport, 0),
+	}
+	if app.cfg.MultiVessel() {
+		v.prefix = vc.ShipId + "/"
+		logger := app.logger.With().Str("vessel", vc.ShipId).Logger()
+		v.logger = &logger
+	}
+	app.vessels = append(app.vessels, v)
+
+	coreLogger := v.logger.With().Str("component", "core").Logger()
+	v.theCore = core.NewCore(nil, nil,
 		app.cfg.AnnounceInterval, &coreLogger)
+	app.applyCoreSettings(v.theCore, app.cfg)
+	v.theCore.SetDrainTimeout(app.drainTimeout)
+
+	transports := 0
+	// publisher stays a nil interface without MQTT
+	var publisher signalk.Publisher
+	if app.mqttAdapter != nil {
+		mqttVessel := app.mqttAdapter.AddVessel(vc.ShipId, mqtt.Topics{
+			Request:  vc.RequestTopic,
+			Response: vc.ResponseTopic,
+			Status:   vc.StatusTopic,
+		}, v.theCore)
+		v.theCore.AddTransport(mqttVessel)
+		publisher = mqttVessel
+		transports++
+	}
+	if app.grpcServer != nil {
+		v.theCore.AddTransport(app.grpcServer.AddVessel(vc.ShipId, v.theCore))
+		transports++
+	}
 
-	mqttLogger := app.logger.With().Str("component", "mqtt").Logger()
-	app.mqttAdapter = mqtt.NewAdapter(app.cfg.Mqtt.Broker,
-		time.Duration(app.cfg.Mqtt.ConnTimeout)*time.Millisecond,
-		app.cfg.Mqtt.Username,
-		app.cfg.Mqtt.Password,
-		app.cfg.Mqtt.ShipId,
-		app.cfg.Mqtt.AnnounceTopic,
-		time.Duration(app.cfg.Mqtt.AnnounceTimeout)*time.Millisecond,
-		time.Duration(app.cfg.Mqtt.DisconnectTimeout)*time.Millisecond,
-		app.cfg.Mqtt.CertCheck,
-		app.theCore,
-		&mqttLogger)
-	app.theCore.SetMqttHandler(app.mqttAdapter)
-
-	shipControlLogger := app.logger.With().Str("component", "ship-control").Logger()
-	app.shipControlAdapter = shipcontrol.NewAdapter(app.cfg.ShipControl.SocketName,
-		app.theCore,
-		app.cfg.ShipControl.QueueSize,
+	if (vc.WebSocket != nil) && vc.WebSocket.IsEnabled() {
+		webSocketLogger := v.logger.With().Str("component", "websocket").Logger()
+		v.addTransport(websocket.NewAdapter(vc.WebSocket.Address,
+			vc.WebSocket.Path,
+			vc.WebSocket.Token,
+			vc.ShipId,
+			vc.WebSocket.AllowedOrigins,
+			vc.WebSocket.ClientQueueSize,
+			v.theCore,
+			&webSocketLogger))
+		transports++
+	}
+
+	if (vc.Mavlink != nil) && vc.Mavlink.IsEnabled() {
+		mavlinkLogger := v.logger.With().Str("component", "mavlink").Logger()
+		v.addTransport(mavlink.NewGateway(vc.Mavlink.Address,
+			vc.Mavlink.GcsAddress,
+			vc.Mavlink.SystemId,
+			time.Duration(vc.Mavlink.Interval)*time.Millisecond,
+			time.Duration(vc.Mavlink.MaxAge)*time.Millisecond,
+			vc.Mavlink.MaxSpeed,
+			vc.Mavlink.MaxSteering,
+			vc.Mavlink.Networks(),
+			v.theCore,
+			&mavlinkLogger))
+		transports++
+	}
+
+	if transports == 0 {
+		v.logger.Warn().Msg("no transports enabled, the ship can't be controlled")
+	}
+
+	var safeState []byte
+	if vc.ShipControl.SafeStateCommand != "" {
+		safeState = []byte(vc.ShipControl.SafeStateCommand)
+	}
+	shipControlLogger := v.logger.With().Str("component", "ship-control").Logger()
+	v.shipControlAdapter = shipcontrol.NewAdapter(daemonEndpoint(&vc.ShipControl.DaemonConfig),
+		v.theCore,
+		vc.ShipControl.QueueSize,
+		overflowPolicy,
+		safeState,
+		app.drainTimeout,
 		&shipControlLogger)
-	app.theCore.SetShipControl(app.shipControlAdapter)
+	v.theCore.SetShipControl(v.shipControlAdapter)
 
-	shipNavLogger := app.logger.With().Str("component", "ship-nav").Logger()
-	app.shipNavAdapter = shipnav.NewAdapter(app.cfg.ShipNav.SocketName,
-		app.theCore,
-		app.cfg.ShipNav.QueueSize,
+	shipNavLogger := v.logger.With().Str("component", "ship-nav").Logger()
+	v.shipNavAdapter = shipnav.NewAdapter(daemonEndpoint(&vc.ShipNav.DaemonConfig),
+		v.theCore,
+		vc.ShipNav.QueueSize,
+		overflowPolicy,
+		app.drainTimeout,
 		&shipNavLogger)
-	app.theCore.SetShipNav(app.shipNavAdapter)
+	v.theCore.SetShipNav(v.shipNavAdapter)
+
+	metrics.RegisterQueues(v.prefix+"core", v.theCore)
+	metrics.RegisterQueues(v.prefix+"ship-control", v.shipControlAdapter)
+	metrics.RegisterQueues(v.prefix+"ship-nav", v.shipNavAdapter)
+
+	if vc.NmeaInput != nil {
+		nmeaLogger := v.logger.With().Str("component", "nmea-input").Logger()
+		v.nmeaInput = nmea.NewInput(vc.NmeaInput.Address,
+			vc.NmeaInput.BaudRate,
+			time.Duration(vc.NmeaInput.Interval)*time.Millisecond,
+			v.theCore,
+			&nmeaLogger)
+	}
+
+	if vc.NmeaOutput != nil {
+		nmeaLogger := v.logger.With().Str("component", "nmea-output").Logger()
+		v.nmeaOutput = nmea.NewOutput(vc.ShipId,
+			vc.NmeaOutput.TcpAddress,
+			vc.NmeaOutput.UdpAddress,
+			time.Duration(vc.NmeaOutput.Interval)*time.Millisecond,
+			time.Duration(vc.NmeaOutput.MaxAge)*time.Millisecond,
+			float64(vc.NmeaOutput.ArrivalRadius),
+			v.theCore,
+			&nmeaLogger)
+	}
+
+	if vc.Ais != nil {
+		aisLogger := v.logger.With().Str("component", "ais").Logger()
+		v.ais = ais.NewAdapter(vc.Ais.Address,
+			vc.Ais.BaudRate,
+			time.Duration(vc.Ais.Interval)*time.Millisecond,
+			time.Duration(vc.Ais.MaxAge)*time.Millisecond,
+			float64(vc.Ais.Range),
+			ais.Risk{
+				WarnCpa:  float64(vc.Ais.WarnCpa),
+				WarnTcpa: float64(vc.Ais.WarnTcpa),
+				StopCpa:  float64(vc.Ais.NavStopCpa),
+				StopTcpa: float64(vc.Ais.NavStopTcpa),
+			},
+			v.theCore,
+			&aisLogger)
+	}
+
+	if vc.SignalK != nil {
+		signalKLogger := v.logger.With().Str("component", "signalk").Logger()
+		v.signalK = signalk.NewServer(vc.ShipId,
+			vc.SignalK.Mmsi,
+			vc.SignalK.Address,
+			vc.SignalK.AllowedOrigins,
+			publisher,
+			vc.SignalK.MqttTopic,
+			time.Duration(vc.SignalK.Interval)*time.Millisecond,
+			time.Duration(vc.SignalK.MaxAge)*time.Millisecond,
+			v.theCore,
+			&signalKLogger)
+	}
+
+	if app.cfg.Track != nil {
+		// vessels keep their tracks apart
+		exportDir := app.cfg.Track.ExportDir
+		if app.cfg.MultiVessel() && (exportDir != "") {
+			exportDir = filepath.Join(exportDir, vc.ShipId)
+		}
+		trackLogger := v.logger.With().Str("component", "track").Logger()
+		v.trackRecorder = track.NewRecorder(app.cfg.Track.MaxTracks,
+			app.cfg.Track.MaxPoints,
+			exportDir,
+			&trackLogger)
+		v.theCore.SetTrackRecorder(v.trackRecorder)
+	}
+}
+
+func (v *vessel) addTransport(t transport) {
+	v.transports = append(v.transports, t)
+	v.theCore.AddTransport(t)
+	metrics.RegisterQueues(v.prefix+t.Name(), t)
+}
+
+// Reload re-reads configuration file and applies settings that can be changed
+// live, other changed settings are reported as requiring restart
+func (app *App) Reload() (*config.Changes, error) {
+	app.reloadMutex.Lock()
+	defer app.reloadMutex.Unlock()
+
+	cfg, err := config.NewConfig(app.configFile, app.configFormat, app.overrides)
+	if err != nil {
+		app.logger.Error().Err(err).Msg("configuration reload failed")
+		return nil, err
+	}
+
+	changes, err := config.Diff(app.cfg, cfg)
+	if err == nil {
+		// settings requiring restart are compared to those the app started with,
+		// so that they are reported until the restart
+		var pending *config.Changes
+		pending, err = config.Diff(app.startCfg, cfg)
+		if err == nil {
+			if app.mqttAdapter == nil {
+				changes.RequireRestart("mqtt")
+				pending.RequireRestart("mqtt")
+			}
+			changes.RestartRequired = pending.RestartRequired
+		}
+	}
+	if err != nil {
+		app.logger.Error().Err(err).Msg("failed to compare configurations")
+		return nil, err
+	}
+
+	app.applyLogLevel(cfg)
+	overflowPolicy := app.overflowPolicy(cfg)
+	for _, v := range app.vessels {
+		app.applyCoreSettings(v.theCore, cfg)
+		v.shipControlAdapter.SetOverflowPolicy(overflowPolicy)
+		v.shipNavAdapter.SetOverflowPolicy(overflowPolicy)
+	}
+	if app.mqttAdapter != nil {
+		app.mqttAdapter.SetOverflowPolicy(overflowPolicy)
+		if changes.Changed("mqtt.broker", "mqtt.brokers", "mqtt.username",
+			"mqtt.password", "mqtt.passwordFile", "mqtt.certCheck") {
+			app.mqttAdapter.UpdateBrokers(mqttBrokers(cfg.Mqtt))
+		}
+	}
+
+	app.cfg = cfg
+	if app.adminServer != nil {
+		app.adminServer.SetConfig(cfg)
+	}
+
+	app.logger.Info().Strs("applied", changes.Applied).Msg("configuration reloaded")
+	if len(changes.RestartRequired) > 0 {
+		app.logger.Warn().Strs("settings", changes.RestartRequired).
+			Msg("changed settings take effect after restart")
+	}
+
+	return changes, nil
+}
+
+func (app *App) applyLogLevel(cfg *config.Config) {
+	logLevel, err := zerolog.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		fmt.Printf("Invalid logLevel: %s, error: %s", cfg.LogLevel, err.Error())
+		logLevel = zerolog.InfoLevel
+	}
+	zerolog.SetGlobalLevel(logLevel)
+}
+
+// applyCoreSettings applies core settings that can be changed live
+func (app *App) applyCoreSettings(theCore *core.Core, cfg *config.Config) {
+	theCore.SetAnnounceInterval(cfg.AnnounceInterval)
+	theCore.SetOverflowPolicy(app.overflowPolicy(cfg))
+
+	staleRequests := cfg.StaleRequests
+	if staleRequests == nil {
+		staleRequests = &config.StaleRequestsConfig{}
+	}
+	theCore.SetMaxAge(core.CommandClassControl,
+		time.Duration(staleRequests.Control)*time.Millisecond)
+	theCore.SetMaxAge(core.CommandClassNavigation,
+		time.Duration(staleRequests.Navigation)*time.Millisecond)
+	theCore.SetMaxAge(core.CommandClassQuery,
+		time.Duration(staleRequests.Query)*time.Millisecond)
+	theCore.SetClockSkewTolerance(
+		time.Duration(staleRequests.ClockSkewTolerance) * time.Millisecond)
+
+	requestQueues := cfg.RequestQueues
+	if requestQueues == nil {
+		requestQueues = &config.RequestQueuesConfig{}
+	}
+	theCore.SetQueueSize(core.CommandClassSafety, requestQueues.Safety)
+	theCore.SetQueueSize(core.CommandClassControl, requestQueues.Control)
+	theCore.SetQueueSize(core.CommandClassNavigation, requestQueues.Navigation)
+	theCore.SetQueueSize(core.CommandClassQuery, requestQueues.Query)
+}
+
+func (app *App) overflowPolicy(cfg *config.Config) queue.Policy {
+	policy, err := queue.ParsePolicy(cfg.OverflowPolicy)
+	if err != nil {
+		app.logger.Error().Err(err).Msgf("using %s overflow policy", policy)
+	}
+	return policy
+}
+
+func daemonEndpoint(cfg *config.DaemonConfig) *daemon.Endpoint {
+	endpoint := &daemon.Endpoint{
+		Address:        cfg.SocketName,
+		KeepAlive:      time.Duration(cfg.KeepAlive) * time.Millisecond,
+		RequestTimeout: time.Duration(cfg.RequestTimeout) * time.Millisecond,
+		BaudRate:       cfg.BaudRate,
+	}
+	if cfg.TLS != nil {
+		endpoint.TLS = &daemon.TLS{
+			CaFile:     cfg.TLS.CaFile,
+			CertFile:   cfg.TLS.CertFile,
+			KeyFile:    cfg.TLS.KeyFile,
+			ServerName: cfg.TLS.ServerName,
+		}
+	}
+	return endpoint
+}
+
+func mqttBrokers(cfg *config.MqttConfig) []*mqtt.Broker {
+	brokers := make([]*mqtt.Broker, 0)
+	for _, b := range cfg.BrokerList() {
+		brokers = append(brokers, &mqtt.Broker{
+			Url:       b.Url,
+			Username:  b.Username,
+			Password:  b.Password,
+			CertCheck: b.CertCheck,
+			CaFile:    b.CaFile,
+			CertFile:  b.CertFile,
+			KeyFile:   b.KeyFile,
+		})
+	}
+	return brokers
 }
@@ -2,42 +2,337 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"net"
 	"os"
 )
 
+const redactedValue = "<redacted>"
+
+// TransportConfig is embedded into front-end transport sections,
+// a transport is enabled when its section is present unless
+// "enabled" is set to false
+type TransportConfig struct {
+	Enabled *bool `json:"enabled,omitempty"`
+}
+
+func (c *TransportConfig) IsEnabled() bool {
+	return (c.Enabled == nil) || *c.Enabled
+}
+
+// BrokerConfig takes the password either inline or from passwordFile
+type BrokerConfig struct {
+	Url          string `json:"url"`
+	Username     string `json:"username"`
+	Password     string `json:"password"`
+	PasswordFile string `json:"passwordFile,omitempty"`
+	CertCheck    bool   `json:"certCheck"`
+	CaFile       string `json:"caFile"`
+	CertFile     string `json:"certFile"`
+	KeyFile      string `json:"keyFile"`
+}
+
+// MqttConfig describes either a single broker (broker, username, password,
+// passwordFile, certCheck) or a list of brokers in order of preference (brokers);
+// protocolVersion is 3 (MQTT 3.1.1, default) or 5, messageExpiry and
+// operatorProperty apply to MQTT 5 only, messageExpiry is rounded up to seconds
 type MqttConfig struct {
-	Broker            string `json:"broker"`
-	ConnTimeout       int    `json:"connTimeout"`
-	Username          string `json:"username"`
-	Password          string `json:"password"`
-	ShipId            string `json:"shipId"`
-	AnnounceTopic     string `json:"announceTopic"`
-	AnnounceTimeout   int    `json:"announceTimeout"`
-	DisconnectTimeout int    `json:"disconnectTimeout"`
-	CertCheck         bool   `json:"certCheck"`
+	TransportConfig
+	ProtocolVersion   int             `json:"protocolVersion"`
+	MessageExpiry     int             `json:"messageExpiry"`
+	OperatorProperty  string          `json:"operatorProperty"`
+	Broker            string          `json:"broker"`
+	Brokers           []*BrokerConfig `json:"brokers"`
+	ConnTimeout       int             `json:"connTimeout"`
+	Username          string          `json:"username"`
+	Password          string          `json:"password"`
+	PasswordFile      string          `json:"passwordFile,omitempty"`
+	ShipId            string          `json:"shipId"`
+	AnnounceTopic     string          `json:"announceTopic"`
+	AnnounceTimeout   int             `json:"announceTimeout"`
+	DisconnectTimeout int             `json:"disconnectTimeout"`
+	CertCheck         bool            `json:"certCheck"`
+	FailoverThreshold int             `json:"failoverThreshold"`
+	FallbackInterval  int             `json:"fallbackInterval"`
+	ReconnectInterval int             `json:"reconnectInterval"`
+	// ClientId defaults to the id of the (first) vessel
+	ClientId string `json:"clientId,omitempty"`
+}
+
+// BrokerList returns configured brokers in order of preference
+func (c *MqttConfig) BrokerList() []*BrokerConfig {
+	if len(c.Brokers) > 0 {
+		return c.Brokers
+	}
+	if c.Broker == "" {
+		return nil
+	}
+
+	return []*BrokerConfig{
+		{
+			Url:       c.Broker,
+			Username:  c.Username,
+			Password:  c.Password,
+			CertCheck: c.CertCheck,
+		},
+	}
+}
+
+// DaemonConfig is embedded into daemon sections: socketName is a unix socket
+// path or a unix://, tcp:// or tls:// URL, keepAlive (milliseconds) applies
+// to tcp and tls, negative disables keepalives. Ship-control may also be
+// a controller on a serial port, serial:///dev/ttyS0, at baudRate
+// (115200 by default). A request fails unless its response arrives within
+// requestTimeout (milliseconds, 5000 by default).
+type DaemonConfig struct {
+	SocketName     string           `json:"socketName"`
+	KeepAlive      int              `json:"keepAlive,omitempty"`
+	RequestTimeout int              `json:"requestTimeout,omitempty"`
+	TLS            *DaemonTLSConfig `json:"tls,omitempty"`
+	BaudRate       int              `json:"baudRate,omitempty"`
+}
+
+// DaemonTLSConfig applies to tls:// daemons, certFile and keyFile
+// enable mutual TLS
+type DaemonTLSConfig struct {
+	CaFile     string `json:"caFile"`
+	CertFile   string `json:"certFile"`
+	KeyFile    string `json:"keyFile"`
+	ServerName string `json:"serverName,omitempty"`
 }
 
 type ShipControlConfig struct {
-	SocketName string `json:"socketName"`
-	QueueSize  int    `json:"queueSize"`
+	DaemonConfig
+	QueueSize int `json:"queueSize"`
+	// SafeStateCommand is sent to ship-control on emergency stop and shutdown,
+	// empty means stop the engine
+	SafeStateCommand string `json:"safeStateCommand"`
 }
 
 type ShipNavConfig struct {
-	SocketName string `json:"socketName"`
-	QueueSize  int    `json:"queueSize"`
+	DaemonConfig
+	QueueSize int `json:"queueSize"`
+}
+
+type TrackConfig struct {
+	MaxTracks int    `json:"maxTracks"`
+	MaxPoints int    `json:"maxPoints"`
+	ExportDir string `json:"exportDir"`
+}
+
+type AdminConfig struct {
+	Address string `json:"address"`
+}
+
+// WebSocketConfig serves a vessel to WebSocket clients on address and path,
+// clients need token (or tokenFile) as bearer token or token query parameter
+type WebSocketConfig struct {
+	TransportConfig
+	Address         string   `json:"address"`
+	Path            string   `json:"path"`
+	Token           string   `json:"token"`
+	TokenFile       string   `json:"tokenFile,omitempty"`
+	AllowedOrigins  []string `json:"allowedOrigins"`
+	ClientQueueSize int      `json:"clientQueueSize"`
+}
+
+// GrpcConfig serves the gRPC API of all vessels on address; calls need
+// token (or tokenFile) as bearer token, TLS is used when certFile
+// and keyFile are set. Commands wait up to responseTimeout for a response
+// unless the call has a deadline, telemetry streams check for telemetry
+// every telemetryInterval (milliseconds).
+type GrpcConfig struct {
+	TransportConfig
+	Address           string `json:"address"`
+	Token             string `json:"token"`
+	TokenFile         string `json:"tokenFile,omitempty"`
+	CertFile          string `json:"certFile,omitempty"`
+	KeyFile           string `json:"keyFile,omitempty"`
+	ResponseTimeout   int    `json:"responseTimeout"`
+	TelemetryInterval int    `json:"telemetryInterval"`
+	ClientQueueSize   int    `json:"clientQueueSize"`
+}
+
+// MavlinkConfig serves ground control stations over MAVLink v2 on UDP:
+// address is listened on, gcsAddress gets messages before any station is
+// heard from, e.g. a broadcast address with the standard port 14550;
+// systemId (1-255) identifies the vessel. The position is sent every
+// interval, telemetry older than maxAge isn't (milliseconds). Full manual
+// control deflection maps to maxSpeed and maxSteering in ship-control units.
+// Stations outside allowedNetworks (CIDRs) are ignored; it's required as
+// MAVLink messages, e.g. MANUAL_CONTROL, aren't authenticated.
+type MavlinkConfig struct {
+	TransportConfig
+	Address         string   `json:"address"`
+	GcsAddress      string   `json:"gcsAddress,omitempty"`
+	SystemId        int      `json:"systemId"`
+	Interval        int      `json:"interval"`
+	MaxAge          int      `json:"maxAge"`
+	MaxSpeed        int      `json:"maxSpeed"`
+	MaxSteering     int      `json:"maxSteering"`
+	AllowedNetworks []string `json:"allowedNetworks,omitempty"`
+}
+
+// Networks returns parsed allowedNetworks, invalid ones are left out
+// and reported by Validate
+func (c *MavlinkConfig) Networks() []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(c.AllowedNetworks))
+	for _, network := range c.AllowedNetworks {
+		if _, ipNet, err := net.ParseCIDR(network); err == nil {
+			networks = append(networks, ipNet)
+		}
+	}
+	return networks
+}
+
+// NmeaInputConfig describes an NMEA 0183 position and heading source:
+// serial:///dev/ttyUSB0 at baudRate (4800 by default), tcp://host:port
+// or udp://[host]:port; the fix is published at most every interval
+// milliseconds
+type NmeaInputConfig struct {
+	Address  string `json:"address"`
+	BaudRate int    `json:"baudRate,omitempty"`
+	Interval int    `json:"interval"`
+}
+
+// NmeaOutputConfig serves telemetry and waypoints to chartplotters as
+// NMEA 0183: tcpAddress is listened on for clients, udpAddress receives
+// datagrams, e.g. a broadcast address; sentences are sent every interval,
+// telemetry older than maxAge isn't (milliseconds), arrivalRadius is
+// in meters
+type NmeaOutputConfig struct {
+	TcpAddress    string `json:"tcpAddress,omitempty"`
+	UdpAddress    string `json:"udpAddress,omitempty"`
+	Interval      int    `json:"interval"`
+	MaxAge        int    `json:"maxAge"`
+	ArrivalRadius int    `json:"arrivalRadius"`
+}
+
+// AisConfig describes an AIS receiver, its address is of the same form as
+// nmeaInput's (serial at 38400 baud by default); targets within range meters
+// (0 for all) are published every interval, targets not heard of for maxAge
+// are dropped (milliseconds). A collision warning is published for targets
+// whose closest point of approach comes within warnCpa meters in warnTcpa
+// seconds or less; autonav is stopped likewise for navStopCpa and
+// navStopTcpa, navStopCpa 0 disables it.
+type AisConfig struct {
+	Address     string `json:"address"`
+	BaudRate    int    `json:"baudRate,omitempty"`
+	Interval    int    `json:"interval"`
+	MaxAge      int    `json:"maxAge"`
+	Range       int    `json:"range"`
+	WarnCpa     int    `json:"warnCpa"`
+	WarnTcpa    int    `json:"warnTcpa"`
+	NavStopCpa  int    `json:"navStopCpa"`
+	NavStopTcpa int    `json:"navStopTcpa"`
+}
+
+// SignalKConfig serves vessel state as Signal K deltas: address is listened
+// on for WebSocket clients of /signalk/v1/stream, mqttTopic, if set, receives
+// the deltas over the MQTT connection; the vessel is identified by mmsi when
+// set. Deltas are sent every interval, telemetry older than maxAge
+// isn't (milliseconds).
+type SignalKConfig struct {
+	Address        string   `json:"address,omitempty"`
+	AllowedOrigins []string `json:"allowedOrigins,omitempty"`
+	MqttTopic      string   `json:"mqttTopic,omitempty"`
+	Mmsi           string   `json:"mmsi,omitempty"`
+	Interval       int      `json:"interval"`
+	MaxAge         int      `json:"maxAge"`
+}
+
+// StaleRequestsConfig sets maximum request age per command class
+// in milliseconds, 0 means no limit; requests with an age limit and
+// a timestamp more than clockSkewTolerance ahead are rejected
+type StaleRequestsConfig struct {
+	Control            int `json:"control"`
+	Navigation         int `json:"navigation"`
+	Query              int `json:"query"`
+	ClockSkewTolerance int `json:"clockSkewTolerance"`
+}
+
+// RequestQueuesConfig sets maximum number of queued requests
+// per command class in core, 0 means the default
+type RequestQueuesConfig struct {
+	Safety     int `json:"safety"`
+	Control    int `json:"control"`
+	Navigation int `json:"navigation"`
+	Query      int `json:"query"`
+}
+
+// SupervisorConfig sets how failed components are restarted, how long
+// queued commands are still executed on shutdown and how long shutdown
+// may take per vessel, milliseconds; ship-control has a budget of its own
+// to drain its queue and send the safe-state command
+type SupervisorConfig struct {
+	MinBackoff      int `json:"minBackoff"`
+	MaxBackoff      int `json:"maxBackoff"`
+	DrainTimeout    int `json:"drainTimeout"`
+	ShutdownTimeout int `json:"shutdownTimeout"`
+}
+
+// VesselConfig describes one of the ships served by the bridge in
+// multi-vessel mode; MQTT topics default to ship/<shipId>/request,
+// ship/<shipId>/response and ship/<shipId>/status
+type VesselConfig struct {
+	ShipId        string             `json:"shipId"`
+	RequestTopic  string             `json:"requestTopic,omitempty"`
+	ResponseTopic string             `json:"responseTopic,omitempty"`
+	StatusTopic   string             `json:"statusTopic,omitempty"`
+	ShipControl   *ShipControlConfig `json:"shipControl"`
+	ShipNav       *ShipNavConfig     `json:"shipNav"`
+	WebSocket     *WebSocketConfig   `json:"webSocket,omitempty"`
+	Mavlink       *MavlinkConfig     `json:"mavlink,omitempty"`
+	NmeaInput     *NmeaInputConfig   `json:"nmeaInput,omitempty"`
+	NmeaOutput    *NmeaOutputConfig  `json:"nmeaOutput,omitempty"`
+	Ais           *AisConfig         `json:"ais,omitempty"`
+	SignalK       *SignalKConfig     `json:"signalK,omitempty"`
 }
 
-// JSON-based bridge configuration
+// JSON-based bridge configuration; a single ship is described by shipId,
+// shipControl, shipNav, webSocket, mavlink, nmeaInput, nmeaOutput, ais and
+// signalK, several ships sharing the MQTT connection by vessels instead
 type Config struct {
-	Mqtt             *MqttConfig        `json:"mqtt"`
-	ShipControl      *ShipControlConfig `json:"shipControl"`
-	ShipNav          *ShipNavConfig     `json:"shipNav"`
-	AnnounceInterval int                `json:"announceInterval"`
-	LogLevel         string             `json:"logLevel"`
+	ShipId        string               `json:"shipId"`
+	Mqtt          *MqttConfig          `json:"mqtt"`
+	ShipControl   *ShipControlConfig   `json:"shipControl"`
+	ShipNav       *ShipNavConfig       `json:"shipNav"`
+	Track         *TrackConfig         `json:"track"`
+	Admin         *AdminConfig         `json:"admin"`
+	StaleRequests *StaleRequestsConfig `json:"staleRequests"`
+	RequestQueues *RequestQueuesConfig `json:"requestQueues"`
+	// OverflowPolicy applies to full request and response queues:
+	// reject (default), drop_newest or drop_oldest; safety commands
+	// are always queued, dropping a request of a lower class if needed
+	OverflowPolicy   string            `json:"overflowPolicy"`
+	WebSocket        *WebSocketConfig  `json:"webSocket"`
+	Mavlink          *MavlinkConfig    `json:"mavlink"`
+	Supervisor       *SupervisorConfig `json:"supervisor"`
+	AnnounceInterval int               `json:"announceInterval"`
+	LogLevel         string            `json:"logLevel"`
+	NmeaInput        *NmeaInputConfig  `json:"nmeaInput"`
+	NmeaOutput       *NmeaOutputConfig `json:"nmeaOutput"`
+	Ais              *AisConfig        `json:"ais"`
+	SignalK          *SignalKConfig    `json:"signalK"`
+	Grpc             *GrpcConfig       `json:"grpc"`
+	Vessels          []*VesselConfig   `json:"vessels,omitempty"`
 }
 
-func NewConfig(filename string) (*Config, error) {
+// NewConfig reads the configuration file in the given format, an empty format
+// is told by file extension (see FormatOf), and applies overrides; in order of
+// precedence, highest first, settings come from:
+//   - command-line flags (flags)
+//   - SNB_ environment variables, see EnvPrefix
+//   - the configuration file
+//   - defaults, see SetDefaults
+//
+// Secret file settings (passwordFile, tokenFile) are resolved after
+// overrides, relative paths are looked up in $CREDENTIALS_DIRECTORY
+// when it is set. A secret replaces a secret file of a lower precedence
+// and the other way round, within one source they are mutually exclusive.
+func NewConfig(filename string, format Format, flags Overrides) (*Config, error) {
 	file, err := os.Open(filename)
 	if err != nil {
 		return nil, err
@@ -49,11 +344,144 @@ func NewConfig(filename string) (*Config, error) {
 		return nil, err
 	}
 
+	if format == "" {
+		format = FormatOf(filename)
+	}
+	data, err = toJSON(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	return Parse(data, os.Environ(), flags)
+}
+
+// Parse decodes JSON configuration, applies overrides from environ and flags,
+// fills in defaults and validates it; all problems found, including
+// unknown settings, are reported at once
+func Parse(data []byte, environ []string, flags Overrides) (*Config, error) {
+	unknown, err := unknownFields(data)
+	if err != nil {
+		return nil, err
+	}
+
 	config := &Config{}
 	err = json.Unmarshal(data, config)
 	if err != nil {
 		return nil, err
 	}
 
+	errs := make([]error, 0)
+	for _, field := range unknown {
+		errs = append(errs, fmt.Errorf("%s: unknown setting", field))
+	}
+	paths, err := config.applyEnv(environ)
+	errs = append(errs, err, config.overrideSecrets(paths))
+	paths = make([]string, 0, len(flags))
+	for path, value := range flags {
+		err = config.Set(path, value)
+		if err == nil {
+			paths = append(paths, path)
+		}
+		errs = append(errs, err)
+	}
+	errs = append(errs, config.overrideSecrets(paths))
+	errs = append(errs, config.resolveSecrets(environ))
+	config.SetDefaults()
+
+	err = config.Validate()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	err = errors.Join(errs...)
+	if err != nil {
+		return nil, err
+	}
+
 	return config, nil
 }
+
+// VesselId returns ship id, falling back to mqtt.shipId for configurations
+// written before it became a top-level setting
+func (c *Config) VesselId() string {
+	if (c.ShipId == "") && (c.Mqtt != nil) {
+		return c.Mqtt.ShipId
+	}
+	return c.ShipId
+}
+
+// VesselList returns configured vessels, a single one built from top-level
+// settings unless vessels are listed
+func (c *Config) VesselList() []*VesselConfig {
+	if len(c.Vessels) > 0 {
+		return c.Vessels
+	}
+
+	return []*VesselConfig{
+		{
+			ShipId:      c.VesselId(),
+			ShipControl: c.ShipControl,
+			ShipNav:     c.ShipNav,
+			WebSocket:   c.WebSocket,
+			Mavlink:     c.Mavlink,
+			NmeaInput:   c.NmeaInput,
+			NmeaOutput:  c.NmeaOutput,
+			Ais:         c.Ais,
+			SignalK:     c.SignalK,
+		},
+	}
+}
+
+// MultiVessel tells whether vessels are listed
+func (c *Config) MultiVessel() bool {
+	return len(c.Vessels) > 0
+}
+
+// Redacted returns a copy of the configuration with secrets hidden
+func (c *Config) Redacted() *Config {
+	redacted := *c
+	if c.Mqtt != nil {
+		mqtt := *c.Mqtt
+		if mqtt.Password != "" {
+			mqtt.Password = redactedValue
+		}
+		mqtt.Brokers = make([]*BrokerConfig, 0, len(c.Mqtt.Brokers))
+		for _, b := range c.Mqtt.Brokers {
+			broker := *b
+			if broker.Password != "" {
+				broker.Password = redactedValue
+			}
+			mqtt.Brokers = append(mqtt.Brokers, &broker)
+		}
+		redacted.Mqtt = &mqtt
+	}
+	redacted.WebSocket = c.WebSocket.redacted()
+	if c.Grpc != nil {
+		grpc := *c.Grpc
+		if grpc.Token != "" {
+			grpc.Token = redactedValue
+		}
+		redacted.Grpc = &grpc
+	}
+	if c.Vessels != nil {
+		redacted.Vessels = make([]*VesselConfig, 0, len(c.Vessels))
+		for _, v := range c.Vessels {
+			vessel := *v
+			vessel.WebSocket = v.WebSocket.redacted()
+			redacted.Vessels = append(redacted.Vessels, &vessel)
+		}
+	}
+
+	return &redacted
+}
+
+func (c *WebSocketConfig) redacted() *WebSocketConfig {
+	if c == nil {
+		return nil
+	}
+
+	webSocket := *c
+	if webSocket.Token != "" {
+		webSocket.Token = redactedValue
+	}
+	return &webSocket
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Format of a configuration file; YAML and TOML files use the same
+// setting names as JSON ones
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+	FormatTOML Format = "toml"
+)
+
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "json":
+		return FormatJSON, nil
+	case "yaml", "yml":
+		return FormatYAML, nil
+	case "toml":
+		return FormatTOML, nil
+	}
+	return "", fmt.Errorf("unknown configuration format %s, expected json, yaml or toml", name)
+}
+
+// FormatOf tells configuration format by file extension,
+// files without a known extension are JSON
+func FormatOf(filename string) Format {
+	format, err := ParseFormat(strings.TrimPrefix(filepath.Ext(filename), "."))
+	if err != nil {
+		return FormatJSON
+	}
+	return format
+}
+
+// toJSON translates configuration data to JSON so that all formats
+// share decoding, unknown setting detection and validation
+func toJSON(data []byte, format Format) ([]byte, error) {
+	var tree any
+	switch format {
+	case FormatJSON, "":
+		return data, nil
+	case FormatYAML:
+		tree = make(map[string]any)
+		err := yaml.Unmarshal(data, &tree)
+		if err != nil {
+			return nil, err
+		}
+	case FormatTOML:
+		tree = make(map[string]any)
+		_, err := toml.Decode(string(data), &tree)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown configuration format %s", format)
+	}
+
+	return json.Marshal(tree)
+}
+
+// Convert translates configuration data between formats, keeping settings
+// as they are: defaults are not filled in and overrides are not applied
+func Convert(data []byte, from Format, to Format) ([]byte, error) {
+	jsonData, err := toJSON(data, from)
+	if err != nil {
+		return nil, err
+	}
+	// unknown settings are rejected rather than silently carried over
+	unknown, err := unknownFields(jsonData)
+	if err != nil {
+		return nil, err
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown settings: %s", strings.Join(unknown, ", "))
+	}
+
+	var out bytes.Buffer
+	switch to {
+	case FormatJSON:
+		err = json.Indent(&out, jsonData, "", "    ")
+		out.WriteString("\n")
+	case FormatYAML:
+		// JSON is valid YAML, decoding it into a node keeps setting order
+		var node yaml.Node
+		err = yaml.Unmarshal(jsonData, &node)
+		if err == nil {
+			clearStyle(&node)
+			encoder := yaml.NewEncoder(&out)
+			encoder.SetIndent(2)
+			err = encoder.Encode(&node)
+		}
+	case FormatTOML:
+		var tree map[string]any
+		decoder := json.NewDecoder(bytes.NewReader(jsonData))
+		decoder.UseNumber()
+		err = decoder.Decode(&tree)
+		if err == nil {
+			// TOML has no null, unset settings are left out
+			dropNulls(tree)
+			encoder := toml.NewEncoder(&out)
+			encoder.Indent = ""
+			err = encoder.Encode(tree)
+		}
+	default:
+		err = fmt.Errorf("unknown configuration format %s", to)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+// clearStyle turns JSON flow style and quoting into block style YAML
+func clearStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearStyle(child)
+	}
+}
+
+func dropNulls(value any) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			if child == nil {
+				delete(v, key)
+				continue
+			}
+			dropNulls(child)
+		}
+	case []any:
+		for _, child := range v {
+			dropNulls(child)
+		}
+	}
+}
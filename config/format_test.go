@@ -0,0 +1,63 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+const convertConfig = `{
+	"shipId": "ship",
+	"announceInterval": 1000,
+	"shipControl": {"socketName": "/tmp/sc.sock", "queueSize": 10},
+	"shipNav": {"socketName": "/tmp/sn.sock"},
+	"mqtt": {
+		"enabled": true,
+		"protocolVersion": 5,
+		"brokers": [
+			{"url": "ssl://localhost:8883", "password": "12345", "certCheck": true},
+			{"url": "tcp://backup:1883"}
+		]
+	},
+	"webSocket": {"enabled": false, "address": ":8081", "allowedOrigins": ["https://a.example"]},
+	"track": null
+}`
+
+func TestConvert(t *testing.T) {
+	expected, err := Parse([]byte(convertConfig), nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to parse JSON configuration: %s", err)
+	}
+
+	for _, format := range []Format{FormatYAML, FormatTOML, FormatJSON} {
+		data, err := Convert([]byte(convertConfig), FormatJSON, format)
+		if err != nil {
+			t.Fatalf("Failed to convert configuration to %s: %s", format, err)
+		}
+		jsonData, err := toJSON(data, format)
+		if err != nil {
+			t.Fatalf("Failed to decode %s configuration: %s\n%s", format, err, data)
+		}
+		cfg, err := Parse(jsonData, nil, nil)
+		if err != nil {
+			t.Fatalf("Failed to parse %s configuration: %s\n%s", format, err, data)
+		}
+
+		if !reflect.DeepEqual(cfg, expected) {
+			t.Errorf("Expected %s configuration to match JSON one, got:\n%s", format, data)
+		}
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	for filename, expected := range map[string]Format{
+		"/etc/ship-net-bridge.conf": FormatJSON,
+		"ship-net-bridge.json":      FormatJSON,
+		"ship-net-bridge.yml":       FormatYAML,
+		"/etc/ship-net-bridge.yaml": FormatYAML,
+		"/etc/ship-net-bridge.TOML": FormatTOML,
+	} {
+		if format := FormatOf(filename); format != expected {
+			t.Errorf("Expected %s to be %s, got %s", filename, expected, format)
+		}
+	}
+}
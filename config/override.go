@@ -0,0 +1,365 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// EnvPrefix starts names of environment variables overriding settings,
+// e.g. SNB_MQTT_PASSWORD overrides mqtt.password and
+// SNB_MQTT_BROKERS_0_URL overrides mqtt.brokers[0].url
+const EnvPrefix = "SNB_"
+
+// credentialsDirEnv is set by systemd for services using LoadCredential=,
+// relative secret file paths are resolved against it
+const credentialsDirEnv = "CREDENTIALS_DIRECTORY"
+
+// Overrides maps settings, named by their JSON path, e.g. mqtt.password,
+// to values taking precedence over the configuration file
+type Overrides map[string]string
+
+// Set changes a setting named by its JSON path, e.g. mqtt.brokers[0].url,
+// allocating missing sections; list items may be appended by using
+// the next free index, lists of strings are comma-separated
+func (c *Config) Set(path string, value string) error {
+	segments, err := splitPath(path)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	err = setValue(reflect.ValueOf(c).Elem(), segments, value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// secretNames are settings that may instead be read from a file named
+// by the setting with File suffix, e.g. mqtt.password and mqtt.passwordFile
+var secretNames = []string{"password", "token"}
+
+// applyEnv applies SNB_ variables found in environ, returns paths
+// of the settings changed
+func (c *Config) applyEnv(environ []string) ([]string, error) {
+	paths := make([]string, 0)
+	errs := make([]error, 0)
+	for _, entry := range environ {
+		name, value, _ := strings.Cut(entry, "=")
+		if !strings.HasPrefix(name, EnvPrefix) {
+			continue
+		}
+
+		tokens := strings.Split(strings.TrimPrefix(name, EnvPrefix), "_")
+		path, ok := envPath(reflect.TypeOf(Config{}), tokens)
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: unknown setting", name))
+			continue
+		}
+		err := c.Set(path, value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		paths = append(paths, path)
+	}
+	return paths, errors.Join(errs...)
+}
+
+// overrideSecrets lets a secret set by a layer of settings, i.e. environment
+// or flags, replace a secret file set by a lower layer and the other way
+// round; both set by the same layer are rejected by resolveSecrets
+func (c *Config) overrideSecrets(paths []string) error {
+	errs := make([]error, 0)
+	for _, path := range paths {
+		other, ok := secretCounterpart(path)
+		if !ok || slices.ContainsFunc(paths, func(p string) bool {
+			return strings.EqualFold(p, other)
+		}) {
+			continue
+		}
+		errs = append(errs, c.Set(other, ""))
+	}
+	return errors.Join(errs...)
+}
+
+// secretCounterpart returns the path of the secret file setting of a secret
+// and the other way round, e.g. mqtt.passwordFile for mqtt.password
+func secretCounterpart(path string) (string, bool) {
+	prefix, name := "", path
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		prefix, name = path[:i+1], path[i+1:]
+	}
+	for _, secret := range secretNames {
+		if strings.EqualFold(name, secret) {
+			return prefix + secret + "File", true
+		}
+		if strings.EqualFold(name, secret+"File") {
+			return prefix + secret, true
+		}
+	}
+	return "", false
+}
+
+// resolveSecrets replaces settings referencing secret files with contents
+// of those files
+func (c *Config) resolveSecrets(environ []string) error {
+	credentialsDir := ""
+	for _, entry := range environ {
+		name, value, _ := strings.Cut(entry, "=")
+		if name == credentialsDirEnv {
+			credentialsDir = value
+		}
+	}
+
+	v := &validator{}
+	if c.Mqtt != nil {
+		readSecret(v, "mqtt.password", &c.Mqtt.Password, c.Mqtt.PasswordFile, credentialsDir)
+		for i, b := range c.Mqtt.Brokers {
+			readSecret(v, fmt.Sprintf("mqtt.brokers[%d].password", i), &b.Password,
+				b.PasswordFile, credentialsDir)
+		}
+	}
+	if c.WebSocket != nil {
+		readSecret(v, "webSocket.token", &c.WebSocket.Token, c.WebSocket.TokenFile, credentialsDir)
+	}
+	if c.Grpc != nil {
+		readSecret(v, "grpc.token", &c.Grpc.Token, c.Grpc.TokenFile, credentialsDir)
+	}
+	for i, vessel := range c.Vessels {
+		if vessel.WebSocket != nil {
+			readSecret(v, fmt.Sprintf("vessels[%d].webSocket.token", i), &vessel.WebSocket.Token,
+				vessel.WebSocket.TokenFile, credentialsDir)
+		}
+	}
+	return errors.Join(v.errs...)
+}
+
+func readSecret(v *validator, field string, secret *string, filename string, credentialsDir string) {
+	if filename == "" {
+		return
+	}
+	if *secret != "" {
+		v.fail(field, "can't be used together with "+field+"File")
+		return
+	}
+
+	if !filepath.IsAbs(filename) && (credentialsDir != "") {
+		filename = filepath.Join(credentialsDir, filename)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		v.fail(field+"File", err.Error())
+		return
+	}
+	*secret = strings.TrimRight(string(data), "\r\n")
+}
+
+type pathSegment struct {
+	name  string
+	index int
+}
+
+// splitPath splits e.g. mqtt.brokers[0].url into mqtt, brokers, [0], url;
+// index segments have an empty name
+func splitPath(path string) ([]pathSegment, error) {
+	segments := make([]pathSegment, 0)
+	for _, part := range strings.Split(path, ".") {
+		name, rest, _ := strings.Cut(part, "[")
+		if name == "" {
+			return nil, errors.New("invalid setting name")
+		}
+		segments = append(segments, pathSegment{name: name})
+
+		for rest != "" {
+			indexStr, next, ok := strings.Cut(rest, "]")
+			index, err := strconv.Atoi(indexStr)
+			if !ok || (err != nil) || (index < 0) {
+				return nil, errors.New("invalid setting name")
+			}
+			segments = append(segments, pathSegment{index: index})
+			rest = strings.TrimPrefix(next, "[")
+		}
+	}
+	return segments, nil
+}
+
+func setValue(v reflect.Value, segments []pathSegment, value string) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setValue(v.Elem(), segments, value)
+	}
+	if len(segments) == 0 {
+		return parseValue(v, value)
+	}
+
+	segment := segments[0]
+	switch v.Kind() {
+	case reflect.Struct:
+		if segment.name == "" {
+			return errors.New("unknown setting")
+		}
+		field, ok := fieldByJsonName(v, segment.name)
+		if !ok {
+			return errors.New("unknown setting")
+		}
+		return setValue(field, segments[1:], value)
+	case reflect.Slice:
+		if segment.name != "" {
+			return errors.New("unknown setting")
+		}
+		if segment.index > v.Len() {
+			return fmt.Errorf("index %d is out of range", segment.index)
+		}
+		if segment.index == v.Len() {
+			v.Set(reflect.Append(v, reflect.Zero(v.Type().Elem())))
+		}
+		return setValue(v.Index(segment.index), segments[1:], value)
+	}
+	return errors.New("unknown setting")
+}
+
+func parseValue(v reflect.Value, value string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		v.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("must be true or false")
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return errors.New("is not a single setting")
+		}
+		items := make([]string, 0)
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return errors.New("is not a single setting")
+	}
+	return nil
+}
+
+// fieldByJsonName finds a struct field, including fields of embedded structs,
+// by JSON name, ignoring case like encoding/json does
+func fieldByJsonName(v reflect.Value, name string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			if found, ok := fieldByJsonName(v.Field(i), name); ok {
+				return found, true
+			}
+			continue
+		}
+		if jsonName, ok := jsonFieldName(field); ok && strings.EqualFold(jsonName, name) {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// envPath translates environment variable name tokens, e.g. SHIP_CONTROL,
+// SOCKET, NAME into a setting path, e.g. shipControl.socketName
+func envPath(t reflect.Type, tokens []string) (string, bool) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if len(tokens) == 0 {
+		return "", t.Kind() != reflect.Struct
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.Anonymous {
+				if path, ok := envPath(field.Type, tokens); ok {
+					return path, true
+				}
+				continue
+			}
+			name, ok := jsonFieldName(field)
+			if !ok {
+				continue
+			}
+			words := envWords(name)
+			if (len(words) > len(tokens)) || !slicesEqualFold(words, tokens[:len(words)]) {
+				continue
+			}
+			if rest, ok := envPath(field.Type, tokens[len(words):]); ok {
+				if (rest == "") || strings.HasPrefix(rest, "[") {
+					return name + rest, true
+				}
+				return name + "." + rest, true
+			}
+		}
+	case reflect.Slice:
+		index, err := strconv.Atoi(tokens[0])
+		if (err != nil) || (index < 0) {
+			return "", false
+		}
+		if rest, ok := envPath(t.Elem(), tokens[1:]); ok {
+			path := fmt.Sprintf("[%d]", index)
+			if (rest == "") || strings.HasPrefix(rest, "[") {
+				return path + rest, true
+			}
+			return path + "." + rest, true
+		}
+	}
+	return "", false
+}
+
+// envWords splits a camel case JSON name into words, e.g. socketName
+// into socket, Name
+func envWords(name string) []string {
+	words := make([]string, 0)
+	start := 0
+	for i, r := range name {
+		if (i > 0) && unicode.IsUpper(r) {
+			words = append(words, name[start:i])
+			start = i
+		}
+	}
+	return append(words, name[start:])
+}
+
+func slicesEqualFold(a []string, b []string) bool {
+	for i := range a {
+		if !strings.EqualFold(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func jsonFieldName(field reflect.StructField) (string, bool) {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if (name == "-") || !field.IsExported() {
+		return "", false
+	}
+	if name == "" {
+		name = field.Name
+	}
+	return name, true
+}
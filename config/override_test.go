@@ -0,0 +1,119 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const overrideConfig = `{
+	"shipId": "ship",
+	"logLevel": "info",
+	"shipControl": {"socketName": "/tmp/sc.sock"},
+	"shipNav": {"socketName": "/tmp/sn.sock"},
+	"mqtt": {
+		"enabled": true,
+		"brokers": [{"url": "tcp://localhost:1883", "passwordFile": "broker.pass"}]
+	}
+}`
+
+func TestOverrides(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "broker.pass"), []byte("s3cret\n"), 0600)
+	if err != nil {
+		t.Fatalf("Failed to write secret file: %s", err)
+	}
+
+	environ := []string{
+		"CREDENTIALS_DIRECTORY=" + dir,
+		"SNB_LOG_LEVEL=debug",
+		"SNB_SHIP_ID=env-ship",
+		"SNB_SHIP_CONTROL_QUEUE_SIZE=10",
+		"SNB_MQTT_BROKERS_1_URL=tcp://backup:1883",
+		"SNB_WEB_SOCKET_ADDRESS=:8080",
+		"SNB_WEB_SOCKET_TOKEN=ws-secret",
+		"SNB_WEB_SOCKET_ALLOWED_ORIGINS=https://a.example, https://b.example",
+		"PATH=/usr/bin",
+	}
+	flags := Overrides{"shipId": "flag-ship"}
+
+	cfg, err := Parse([]byte(overrideConfig), environ, flags)
+	if err != nil {
+		t.Fatalf("Failed to parse configuration: %s", err)
+	}
+
+	if cfg.LogLevel != "debug" {
+		t.Errorf("Expected log level from environment, got %s", cfg.LogLevel)
+	}
+	if cfg.ShipId != "flag-ship" {
+		t.Errorf("Expected flag to take precedence over environment, got %s", cfg.ShipId)
+	}
+	if cfg.ShipControl.QueueSize != 10 {
+		t.Errorf("Expected queue size from environment, got %d", cfg.ShipControl.QueueSize)
+	}
+	if (len(cfg.Mqtt.Brokers) != 2) || (cfg.Mqtt.Brokers[1].Url != "tcp://backup:1883") {
+		t.Errorf("Expected broker to be appended from environment, got %v", cfg.Mqtt.Brokers)
+	}
+	if cfg.Mqtt.Brokers[0].Password != "s3cret" {
+		t.Errorf("Expected password from secret file, got %q", cfg.Mqtt.Brokers[0].Password)
+	}
+	if (cfg.WebSocket == nil) || (len(cfg.WebSocket.AllowedOrigins) != 2) {
+		t.Errorf("Expected allowed origins from environment, got %v", cfg.WebSocket)
+	}
+}
+
+func TestOverrideErrors(t *testing.T) {
+	environ := []string{
+		"SNB_MQTT_PASWORD=typo",
+		"SNB_SHIP_NAV_QUEUE_SIZE=many",
+		"SNB_MQTT_BROKERS_0_PASSWORD=inline",
+		"SNB_MQTT_BROKERS_0_PASSWORD_FILE=broker.pass",
+	}
+
+	_, err := Parse([]byte(overrideConfig), environ, nil)
+	if err == nil {
+		t.Fatalf("Expected invalid overrides to be rejected")
+	}
+
+	msg := err.Error()
+	for _, expected := range []string{
+		"SNB_MQTT_PASWORD: unknown setting",
+		"SNB_SHIP_NAV_QUEUE_SIZE: shipNav.queueSize: must be an integer",
+		"mqtt.brokers[0].password: can't be used together with mqtt.brokers[0].passwordFile",
+	} {
+		if !strings.Contains(msg, expected) {
+			t.Errorf("Expected error %q, got:\n%s", expected, msg)
+		}
+	}
+}
+
+func TestSecretPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "broker.pass"), []byte("s3cret\n"), 0600)
+	if err != nil {
+		t.Fatalf("Failed to write secret file: %s", err)
+	}
+	environ := []string{"CREDENTIALS_DIRECTORY=" + dir}
+
+	// the environment replaces the secret file of the configuration file
+	cfg, err := Parse([]byte(overrideConfig),
+		append(environ, "SNB_MQTT_BROKERS_0_PASSWORD=inline"), nil)
+	if err != nil {
+		t.Fatalf("Failed to parse configuration: %s", err)
+	}
+	if cfg.Mqtt.Brokers[0].Password != "inline" {
+		t.Errorf("Expected password from environment, got %q", cfg.Mqtt.Brokers[0].Password)
+	}
+
+	// flags replace the secret of the environment
+	cfg, err = Parse([]byte(overrideConfig),
+		append(environ, "SNB_MQTT_BROKERS_0_PASSWORD=inline"),
+		Overrides{"mqtt.brokers[0].passwordFile": "broker.pass"})
+	if err != nil {
+		t.Fatalf("Failed to parse configuration: %s", err)
+	}
+	if cfg.Mqtt.Brokers[0].Password != "s3cret" {
+		t.Errorf("Expected password from secret file of flags, got %q", cfg.Mqtt.Brokers[0].Password)
+	}
+}
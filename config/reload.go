@@ -0,0 +1,146 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// reloadable lists settings (and sections, by prefix) that can be applied
+// without restarting the bridge
+var reloadable = []string{
+	"logLevel",
+	"announceInterval",
+	"staleRequests",
+	"requestQueues",
+	"overflowPolicy",
+	"mqtt.broker",
+	"mqtt.brokers",
+	"mqtt.username",
+	"mqtt.password",
+	"mqtt.passwordFile",
+	"mqtt.certCheck",
+}
+
+// Changes lists settings that differ between two configurations
+type Changes struct {
+	// Applied settings take effect immediately
+	Applied []string `json:"applied"`
+	// RestartRequired settings take effect after the bridge is restarted
+	RestartRequired []string `json:"restartRequired"`
+}
+
+// Diff compares configurations and sorts changed settings by whether they
+// can be reloaded; settings are named by their JSON path, e.g. mqtt.password
+func Diff(old *Config, updated *Config) (*Changes, error) {
+	oldSettings, err := flatten(old)
+	if err != nil {
+		return nil, err
+	}
+	newSettings, err := flatten(updated)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := make([]string, 0)
+	for path, value := range newSettings {
+		oldValue, ok := oldSettings[path]
+		if !ok || !reflect.DeepEqual(value, oldValue) {
+			changed = append(changed, path)
+		}
+	}
+	for path := range oldSettings {
+		if _, ok := newSettings[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+
+	changes := &Changes{
+		Applied:         make([]string, 0),
+		RestartRequired: make([]string, 0),
+	}
+	for _, path := range changed {
+		if isReloadable(path) {
+			changes.Applied = append(changes.Applied, path)
+		} else {
+			changes.RestartRequired = append(changes.RestartRequired, path)
+		}
+	}
+
+	return changes, nil
+}
+
+// Changed tells whether any applied setting in the given sections changed
+func (c *Changes) Changed(sections ...string) bool {
+	return slices.ContainsFunc(c.Applied, func(path string) bool {
+		return slices.ContainsFunc(sections, func(section string) bool {
+			return inSection(path, section)
+		})
+	})
+}
+
+// RequireRestart moves settings of the given section to RestartRequired,
+// e.g. for sections of components that are not running
+func (c *Changes) RequireRestart(section string) {
+	applied := make([]string, 0, len(c.Applied))
+	for _, path := range c.Applied {
+		if inSection(path, section) {
+			c.RestartRequired = append(c.RestartRequired, path)
+		} else {
+			applied = append(applied, path)
+		}
+	}
+	c.Applied = applied
+	sort.Strings(c.RestartRequired)
+}
+
+func isReloadable(path string) bool {
+	return slices.ContainsFunc(reloadable, func(section string) bool {
+		return inSection(path, section)
+	})
+}
+
+func inSection(path string, section string) bool {
+	return (path == section) || strings.HasPrefix(path, section+".") ||
+		strings.HasPrefix(path, section+"[")
+}
+
+// flatten maps JSON paths of all leaf settings to their values
+func flatten(c *Config) (map[string]any, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var tree any
+	err = json.Unmarshal(data, &tree)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := make(map[string]any)
+	flattenValue("", tree, settings)
+	return settings, nil
+}
+
+func flattenValue(path string, value any, settings map[string]any) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			if path == "" {
+				flattenValue(key, child, settings)
+			} else {
+				flattenValue(path+"."+key, child, settings)
+			}
+		}
+	case []any:
+		for i, child := range v {
+			flattenValue(fmt.Sprintf("%s[%d]", path, i), child, settings)
+		}
+	default:
+		settings[path] = v
+	}
+}
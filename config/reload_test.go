@@ -0,0 +1,54 @@
+package config
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	old := &Config{
+		ShipId:           "ship",
+		AnnounceInterval: 3000,
+		LogLevel:         "info",
+		Mqtt: &MqttConfig{
+			Brokers: []*BrokerConfig{
+				{Url: "tcp://localhost:1883", Password: "12345"},
+			},
+			AnnounceTopic: "ships",
+		},
+	}
+	updated := &Config{
+		ShipId:           "ship",
+		AnnounceInterval: 1000,
+		LogLevel:         "debug",
+		Mqtt: &MqttConfig{
+			Brokers: []*BrokerConfig{
+				{Url: "tcp://localhost:1883", Password: "54321"},
+			},
+			AnnounceTopic: "vessels",
+		},
+	}
+
+	changes, err := Diff(old, updated)
+	if err != nil {
+		t.Fatalf("Failed to compare configurations: %s", err)
+	}
+
+	for _, path := range []string{"announceInterval", "logLevel", "mqtt.brokers[0].password"} {
+		if !slices.Contains(changes.Applied, path) {
+			t.Errorf("Expected %s to be applied, got %v", path, changes.Applied)
+		}
+	}
+	if !slices.Equal(changes.RestartRequired, []string{"mqtt.announceTopic"}) {
+		t.Errorf("Expected only mqtt.announceTopic to require restart, got %v",
+			changes.RestartRequired)
+	}
+	if !changes.Changed("mqtt.brokers") {
+		t.Errorf("Expected mqtt.brokers to be changed")
+	}
+
+	changes.RequireRestart("mqtt")
+	if !slices.Contains(changes.RestartRequired, "mqtt.brokers[0].password") {
+		t.Errorf("Expected mqtt settings to require restart, got %v", changes.RestartRequired)
+	}
+}
@@ -0,0 +1,698 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/moosethebrown/ship-net-bridge/address"
+	"github.com/moosethebrown/ship-net-bridge/queue"
+	"github.com/rs/zerolog"
+)
+
+// Defaults applied to settings missing from the configuration file,
+// durations are in milliseconds
+const (
+	DefaultAnnounceInterval  = 3000
+	DefaultLogLevel          = "info"
+	DefaultQueueSize         = 100
+	DefaultRequestQueueSize  = 250
+	DefaultProtocolVersion   = 3
+	DefaultConnTimeout       = 3000
+	DefaultAnnounceTopic     = "Announce"
+	DefaultAnnounceTimeout   = 2000
+	DefaultDisconnectTimeout = 3000
+	DefaultFallbackInterval  = 60000
+	DefaultReconnectInterval = 5000
+	DefaultWebSocketPath     = "/ws"
+	DefaultClientQueueSize   = 100
+	DefaultMinBackoff        = 1000
+	DefaultMaxBackoff        = 30000
+	DefaultDrainTimeout      = 2000
+	DefaultShutdownTimeout   = 10000
+	DefaultNmeaInterval      = 1000
+	DefaultNmeaMaxAge        = 10000
+	DefaultArrivalRadius     = 10
+	DefaultSystemId          = 1
+	DefaultMavlinkInterval   = 500
+	DefaultMavlinkMaxAge     = 10000
+	DefaultControlScale      = 100
+	DefaultAisInterval       = 5000
+	DefaultAisMaxAge         = 360000
+	DefaultWarnCpa           = 500
+	DefaultWarnTcpa          = 600
+	DefaultNavStopTcpa       = 120
+	DefaultSignalKInterval   = 1000
+	DefaultSignalKMaxAge     = 10000
+	DefaultResponseTimeout   = 10000
+	DefaultTelemetryInterval = 1000
+)
+
+// SetDefaults fills in settings missing from the configuration file
+func (c *Config) SetDefaults() {
+	if c.AnnounceInterval == 0 {
+		c.AnnounceInterval = DefaultAnnounceInterval
+	}
+	if c.LogLevel == "" {
+		c.LogLevel = DefaultLogLevel
+	}
+	if c.OverflowPolicy == "" {
+		c.OverflowPolicy = queue.Reject.String()
+	}
+
+	// the single vessel built by VesselList shares sections with c
+	for _, vessel := range c.VesselList() {
+		vessel.setDefaults()
+	}
+
+	if c.Mqtt != nil {
+		setDefault(&c.Mqtt.ProtocolVersion, DefaultProtocolVersion)
+		setDefault(&c.Mqtt.ConnTimeout, DefaultConnTimeout)
+		setDefault(&c.Mqtt.AnnounceTimeout, DefaultAnnounceTimeout)
+		setDefault(&c.Mqtt.DisconnectTimeout, DefaultDisconnectTimeout)
+		setDefault(&c.Mqtt.FallbackInterval, DefaultFallbackInterval)
+		setDefault(&c.Mqtt.ReconnectInterval, DefaultReconnectInterval)
+		if c.Mqtt.AnnounceTopic == "" {
+			c.Mqtt.AnnounceTopic = DefaultAnnounceTopic
+		}
+	}
+	if c.Grpc != nil {
+		setDefault(&c.Grpc.ResponseTimeout, DefaultResponseTimeout)
+		setDefault(&c.Grpc.TelemetryInterval, DefaultTelemetryInterval)
+		setDefault(&c.Grpc.ClientQueueSize, DefaultClientQueueSize)
+	}
+
+	if c.RequestQueues == nil {
+		c.RequestQueues = &RequestQueuesConfig{}
+	}
+	setDefault(&c.RequestQueues.Safety, DefaultRequestQueueSize)
+	setDefault(&c.RequestQueues.Control, DefaultRequestQueueSize)
+	setDefault(&c.RequestQueues.Navigation, DefaultRequestQueueSize)
+	setDefault(&c.RequestQueues.Query, DefaultRequestQueueSize)
+
+	if c.Supervisor == nil {
+		c.Supervisor = &SupervisorConfig{}
+	}
+	setDefault(&c.Supervisor.MinBackoff, DefaultMinBackoff)
+	setDefault(&c.Supervisor.MaxBackoff, DefaultMaxBackoff)
+	setDefault(&c.Supervisor.DrainTimeout, DefaultDrainTimeout)
+	setDefault(&c.Supervisor.ShutdownTimeout, DefaultShutdownTimeout)
+}
+
+func (c *VesselConfig) setDefaults() {
+	if c.ShipControl != nil {
+		setDefault(&c.ShipControl.QueueSize, DefaultQueueSize)
+	}
+	if c.ShipNav != nil {
+		setDefault(&c.ShipNav.QueueSize, DefaultQueueSize)
+	}
+	if c.WebSocket != nil {
+		if c.WebSocket.Path == "" {
+			c.WebSocket.Path = DefaultWebSocketPath
+		}
+		setDefault(&c.WebSocket.ClientQueueSize, DefaultClientQueueSize)
+	}
+	if c.Mavlink != nil {
+		setDefault(&c.Mavlink.SystemId, DefaultSystemId)
+		setDefault(&c.Mavlink.Interval, DefaultMavlinkInterval)
+		setDefault(&c.Mavlink.MaxAge, DefaultMavlinkMaxAge)
+		setDefault(&c.Mavlink.MaxSpeed, DefaultControlScale)
+		setDefault(&c.Mavlink.MaxSteering, DefaultControlScale)
+	}
+	if c.NmeaInput != nil {
+		setDefault(&c.NmeaInput.Interval, DefaultNmeaInterval)
+	}
+	if c.NmeaOutput != nil {
+		setDefault(&c.NmeaOutput.Interval, DefaultNmeaInterval)
+		setDefault(&c.NmeaOutput.MaxAge, DefaultNmeaMaxAge)
+		setDefault(&c.NmeaOutput.ArrivalRadius, DefaultArrivalRadius)
+	}
+	if c.Ais != nil {
+		setDefault(&c.Ais.Interval, DefaultAisInterval)
+		setDefault(&c.Ais.MaxAge, DefaultAisMaxAge)
+		setDefault(&c.Ais.WarnCpa, DefaultWarnCpa)
+		setDefault(&c.Ais.WarnTcpa, DefaultWarnTcpa)
+		setDefault(&c.Ais.NavStopTcpa, DefaultNavStopTcpa)
+	}
+	if c.SignalK != nil {
+		setDefault(&c.SignalK.Interval, DefaultSignalKInterval)
+		setDefault(&c.SignalK.MaxAge, DefaultSignalKMaxAge)
+	}
+}
+
+func setDefault(value *int, defaultValue int) {
+	if *value == 0 {
+		*value = defaultValue
+	}
+}
+
+// Validate checks the whole configuration and reports all problems at once
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	if c.MultiVessel() {
+		c.validateVessels(v)
+	} else {
+		if c.VesselId() == "" {
+			v.fail("shipId", "is required")
+		}
+		c.VesselList()[0].validate(v, "")
+	}
+	if _, err := zerolog.ParseLevel(c.LogLevel); err != nil {
+		v.fail("logLevel", err.Error())
+	}
+	v.positive("announceInterval", c.AnnounceInterval)
+	if _, err := queue.ParsePolicy(c.OverflowPolicy); err != nil {
+		v.fail("overflowPolicy", err.Error())
+	}
+
+	if (c.Mqtt != nil) && c.Mqtt.IsEnabled() {
+		c.Mqtt.validate(v)
+	} else {
+		for i, vessel := range c.VesselList() {
+			if (vessel.SignalK != nil) && (vessel.SignalK.MqttTopic != "") {
+				section := "signalK.mqttTopic"
+				if c.MultiVessel() {
+					section = fmt.Sprintf("vessels[%d].%s", i, section)
+				}
+				v.fail(section, "requires mqtt to be enabled")
+			}
+		}
+	}
+	if (c.Grpc != nil) && c.Grpc.IsEnabled() {
+		c.Grpc.validate(v)
+	}
+	if c.Admin != nil {
+		v.required("admin.address", c.Admin.Address)
+	}
+	if c.Track != nil {
+		v.notNegative("track.maxTracks", c.Track.MaxTracks)
+		v.notNegative("track.maxPoints", c.Track.MaxPoints)
+	}
+	if c.StaleRequests != nil {
+		v.notNegative("staleRequests.control", c.StaleRequests.Control)
+		v.notNegative("staleRequests.navigation", c.StaleRequests.Navigation)
+		v.notNegative("staleRequests.query", c.StaleRequests.Query)
+		v.notNegative("staleRequests.clockSkewTolerance", c.StaleRequests.ClockSkewTolerance)
+	}
+	if c.RequestQueues != nil {
+		v.positive("requestQueues.safety", c.RequestQueues.Safety)
+		v.positive("requestQueues.control", c.RequestQueues.Control)
+		v.positive("requestQueues.navigation", c.RequestQueues.Navigation)
+		v.positive("requestQueues.query", c.RequestQueues.Query)
+	}
+	c.validateListeners(v)
+	if c.Supervisor != nil {
+		v.positive("supervisor.minBackoff", c.Supervisor.MinBackoff)
+		if c.Supervisor.MaxBackoff < c.Supervisor.MinBackoff {
+			v.fail("supervisor.maxBackoff", "must not be less than minBackoff")
+		}
+		v.positive("supervisor.drainTimeout", c.Supervisor.DrainTimeout)
+		v.positive("supervisor.shutdownTimeout", c.Supervisor.ShutdownTimeout)
+	}
+
+	return errors.Join(v.errs...)
+}
+
+func (c *Config) validateVessels(v *validator) {
+	if c.ShipId != "" {
+		v.fail("shipId", "can't be used together with vessels")
+	}
+	if c.ShipControl != nil {
+		v.fail("shipControl", "can't be used together with vessels")
+	}
+	if c.ShipNav != nil {
+		v.fail("shipNav", "can't be used together with vessels")
+	}
+	if c.WebSocket != nil {
+		v.fail("webSocket", "can't be used together with vessels, set it per vessel")
+	}
+	if c.Mavlink != nil {
+		v.fail("mavlink", "can't be used together with vessels, set it per vessel")
+	}
+	if c.NmeaInput != nil {
+		v.fail("nmeaInput", "can't be used together with vessels, set it per vessel")
+	}
+	if c.NmeaOutput != nil {
+		v.fail("nmeaOutput", "can't be used together with vessels, set it per vessel")
+	}
+	if c.Ais != nil {
+		v.fail("ais", "can't be used together with vessels, set it per vessel")
+	}
+	if c.SignalK != nil {
+		v.fail("signalK", "can't be used together with vessels, set it per vessel")
+	}
+
+	ids := make(map[string]bool)
+	// settings already using a topic or socket
+	topics := make(map[string]string)
+	sockets := make(map[string]string)
+	for i, vessel := range c.Vessels {
+		prefix := fmt.Sprintf("vessels[%d].", i)
+		if vessel.ShipId == "" {
+			v.fail(prefix+"shipId", "is required")
+		} else if ids[vessel.ShipId] {
+			v.fail(prefix+"shipId", "is used by another vessel")
+		}
+		ids[vessel.ShipId] = true
+
+		// default topics of one vessel may collide with topics set for another
+		v.unique(topics, prefix+"requestTopic", topicOr(vessel.RequestTopic, vessel.ShipId, "request"))
+		v.unique(topics, prefix+"responseTopic", topicOr(vessel.ResponseTopic, vessel.ShipId, "response"))
+		v.unique(topics, prefix+"statusTopic", topicOr(vessel.StatusTopic, vessel.ShipId, "status"))
+		if vessel.ShipControl != nil {
+			v.unique(sockets, prefix+"shipControl.socketName", vessel.ShipControl.SocketName)
+		}
+		if vessel.ShipNav != nil {
+			v.unique(sockets, prefix+"shipNav.socketName", vessel.ShipNav.SocketName)
+		}
+		vessel.validate(v, prefix)
+	}
+}
+
+// topicOr returns topic, or the default topic name of shipId when it's empty
+func topicOr(topic string, shipId string, name string) string {
+	if topic == "" {
+		return address.Topic(shipId, name)
+	}
+	return topic
+}
+
+// listener is an address a setting listens on
+type listener struct {
+	field   string
+	network string
+	host    string
+	port    string
+}
+
+// validateListeners rejects listen addresses used by more than one
+// setting, a server failing to listen would be restarted forever
+func (c *Config) validateListeners(v *validator) {
+	used := make([]listener, 0)
+	if c.Admin != nil {
+		v.uniqueListener(&used, "admin.address", "tcp", c.Admin.Address)
+	}
+	if (c.Grpc != nil) && c.Grpc.IsEnabled() {
+		v.uniqueListener(&used, "grpc.address", "tcp", c.Grpc.Address)
+	}
+	for i, vessel := range c.VesselList() {
+		prefix := ""
+		if c.MultiVessel() {
+			prefix = fmt.Sprintf("vessels[%d].", i)
+		}
+		if (vessel.WebSocket != nil) && vessel.WebSocket.IsEnabled() {
+			v.uniqueListener(&used, prefix+"webSocket.address", "tcp", vessel.WebSocket.Address)
+		}
+		if (vessel.Mavlink != nil) && vessel.Mavlink.IsEnabled() {
+			v.uniqueListener(&used, prefix+"mavlink.address", "udp", vessel.Mavlink.Address)
+		}
+		if vessel.NmeaOutput != nil {
+			v.uniqueListener(&used, prefix+"nmeaOutput.tcpAddress", "tcp", vessel.NmeaOutput.TcpAddress)
+		}
+		if vessel.SignalK != nil {
+			v.uniqueListener(&used, prefix+"signalK.address", "tcp", vessel.SignalK.Address)
+		}
+	}
+}
+
+// validate checks vessel sections, prefix is prepended to setting names
+func (c *VesselConfig) validate(v *validator, prefix string) {
+	if c.ShipControl == nil {
+		v.fail(prefix+"shipControl", "section is required")
+	} else {
+		c.ShipControl.DaemonConfig.validate(v, prefix+"shipControl", true)
+		v.positive(prefix+"shipControl.queueSize", c.ShipControl.QueueSize)
+		if (c.ShipControl.SafeStateCommand != "") &&
+			!json.Valid([]byte(c.ShipControl.SafeStateCommand)) {
+			v.fail(prefix+"shipControl.safeStateCommand", "is not valid JSON")
+		}
+	}
+	if c.ShipNav == nil {
+		v.fail(prefix+"shipNav", "section is required")
+	} else {
+		c.ShipNav.DaemonConfig.validate(v, prefix+"shipNav", false)
+		v.positive(prefix+"shipNav.queueSize", c.ShipNav.QueueSize)
+	}
+	if (c.WebSocket != nil) && c.WebSocket.IsEnabled() {
+		v.required(prefix+"webSocket.address", c.WebSocket.Address)
+		// anyone reaching the port could steer the vessel otherwise
+		v.required(prefix+"webSocket.token", c.WebSocket.Token)
+		if !strings.HasPrefix(c.WebSocket.Path, "/") {
+			v.fail(prefix+"webSocket.path", "must start with /")
+		}
+		v.positive(prefix+"webSocket.clientQueueSize", c.WebSocket.ClientQueueSize)
+	}
+	if (c.Mavlink != nil) && c.Mavlink.IsEnabled() {
+		c.Mavlink.validate(v, prefix+"mavlink")
+	}
+	if c.NmeaInput != nil {
+		c.NmeaInput.validate(v, prefix+"nmeaInput")
+	}
+	if c.NmeaOutput != nil {
+		c.NmeaOutput.validate(v, prefix+"nmeaOutput")
+	}
+	if c.Ais != nil {
+		c.Ais.validate(v, prefix+"ais")
+	}
+	if c.SignalK != nil {
+		c.SignalK.validate(v, prefix+"signalK")
+	}
+}
+
+func (c *MavlinkConfig) validate(v *validator, section string) {
+	v.required(section+".address", c.Address)
+	if c.Address != "" {
+		if _, _, err := net.SplitHostPort(c.Address); err != nil {
+			v.fail(section+".address", err.Error())
+		}
+	}
+	if c.GcsAddress != "" {
+		host, _, err := net.SplitHostPort(c.GcsAddress)
+		if err != nil {
+			v.fail(section+".gcsAddress", err.Error())
+		} else if host == "" {
+			v.fail(section+".gcsAddress", "host is required, e.g. a broadcast address")
+		}
+	}
+	if (c.SystemId < 1) || (c.SystemId > 255) {
+		v.fail(section+".systemId", "must be between 1 and 255")
+	}
+	v.positive(section+".interval", c.Interval)
+	v.positive(section+".maxAge", c.MaxAge)
+	v.positive(section+".maxSpeed", c.MaxSpeed)
+	v.positive(section+".maxSteering", c.MaxSteering)
+	if len(c.AllowedNetworks) == 0 {
+		v.fail(section+".allowedNetworks", "is required, MAVLink messages aren't authenticated")
+	}
+	for i, network := range c.AllowedNetworks {
+		if _, _, err := net.ParseCIDR(network); err != nil {
+			v.fail(fmt.Sprintf("%s.allowedNetworks[%d]", section, i), err.Error())
+		}
+	}
+}
+
+func (c *GrpcConfig) validate(v *validator) {
+	v.required("grpc.address", c.Address)
+	v.required("grpc.token", c.Token)
+	if c.Address != "" {
+		if _, _, err := net.SplitHostPort(c.Address); err != nil {
+			v.fail("grpc.address", err.Error())
+		}
+	}
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		v.fail("grpc", "certFile and keyFile must be set together")
+	}
+	v.positive("grpc.responseTimeout", c.ResponseTimeout)
+	v.positive("grpc.telemetryInterval", c.TelemetryInterval)
+	v.positive("grpc.clientQueueSize", c.ClientQueueSize)
+}
+
+func (c *NmeaOutputConfig) validate(v *validator, section string) {
+	v.positive(section+".interval", c.Interval)
+	v.positive(section+".maxAge", c.MaxAge)
+	v.positive(section+".arrivalRadius", c.ArrivalRadius)
+	if (c.TcpAddress == "") && (c.UdpAddress == "") {
+		v.fail(section, "tcpAddress or udpAddress is required")
+	}
+	if c.TcpAddress != "" {
+		if _, _, err := net.SplitHostPort(c.TcpAddress); err != nil {
+			v.fail(section+".tcpAddress", err.Error())
+		}
+	}
+	if c.UdpAddress != "" {
+		host, _, err := net.SplitHostPort(c.UdpAddress)
+		if err != nil {
+			v.fail(section+".udpAddress", err.Error())
+		} else if host == "" {
+			v.fail(section+".udpAddress", "host is required, e.g. a broadcast address")
+		}
+	}
+}
+
+func (c *NmeaInputConfig) validate(v *validator, section string) {
+	v.positive(section+".interval", c.Interval)
+	validateNmeaSource(v, section, c.Address, c.BaudRate)
+}
+
+func (c *AisConfig) validate(v *validator, section string) {
+	v.positive(section+".interval", c.Interval)
+	v.positive(section+".maxAge", c.MaxAge)
+	v.notNegative(section+".range", c.Range)
+	v.positive(section+".warnCpa", c.WarnCpa)
+	v.positive(section+".warnTcpa", c.WarnTcpa)
+	v.notNegative(section+".navStopCpa", c.NavStopCpa)
+	v.positive(section+".navStopTcpa", c.NavStopTcpa)
+	// autonav is stopped only for targets already warned about
+	if (c.NavStopCpa > 0) && ((c.NavStopCpa > c.WarnCpa) || (c.NavStopTcpa > c.WarnTcpa)) {
+		v.fail(section, "navStopCpa and navStopTcpa can't exceed warnCpa and warnTcpa")
+	}
+	validateNmeaSource(v, section, c.Address, c.BaudRate)
+}
+
+func (c *SignalKConfig) validate(v *validator, section string) {
+	v.positive(section+".interval", c.Interval)
+	v.positive(section+".maxAge", c.MaxAge)
+	if (c.Address == "") && (c.MqttTopic == "") {
+		v.fail(section, "address or mqttTopic is required")
+	}
+	if c.Address != "" {
+		if _, _, err := net.SplitHostPort(c.Address); err != nil {
+			v.fail(section+".address", err.Error())
+		}
+	}
+	if strings.ContainsAny(c.MqttTopic, "+#") {
+		v.fail(section+".mqttTopic", "can't contain wildcards")
+	}
+	for _, r := range c.Mmsi {
+		if (r < '0') || (r > '9') || (len(c.Mmsi) != 9) {
+			v.fail(section+".mmsi", "must be 9 digits")
+			break
+		}
+	}
+}
+
+// validateNmeaSource checks address and baudRate of an nmea.Open source
+func validateNmeaSource(v *validator, section string, source string, baudRate int) {
+	v.required(section+".address", source)
+	if source == "" {
+		return
+	}
+
+	network, _, err := address.Nmea(source)
+	if err != nil {
+		v.fail(section+".address", err.Error())
+		return
+	}
+	if baudRate != 0 {
+		if network != "serial" {
+			v.fail(section+".baudRate", "only applies to serial:// addresses")
+		} else if !address.ValidBaudRate(baudRate) {
+			v.fail(section+".baudRate", "is not supported")
+		}
+	}
+}
+
+// validate checks daemon address, TLS and serial settings of section
+func (c *DaemonConfig) validate(v *validator, section string, serial bool) {
+	v.required(section+".socketName", c.SocketName)
+	v.notNegative(section+".requestTimeout", c.RequestTimeout)
+	if c.SocketName == "" {
+		return
+	}
+
+	network, _, err := address.Daemon(c.SocketName)
+	if err != nil {
+		v.fail(section+".socketName", err.Error())
+		return
+	}
+	if (c.TLS != nil) && (network != "tls") {
+		v.fail(section+".tls", "only applies to tls:// addresses")
+	}
+	if (c.TLS != nil) && ((c.TLS.CertFile == "") != (c.TLS.KeyFile == "")) {
+		v.fail(section+".tls", "certFile and keyFile must be set together")
+	}
+	if (network == "serial") && !serial {
+		v.fail(section+".socketName", "serial ports are only supported for shipControl")
+	}
+	if c.BaudRate != 0 {
+		if network != "serial" {
+			v.fail(section+".baudRate", "only applies to serial:// addresses")
+		} else if !address.ValidBaudRate(c.BaudRate) {
+			v.fail(section+".baudRate", "is not supported")
+		}
+	}
+}
+
+func (c *MqttConfig) validate(v *validator) {
+	if (c.ProtocolVersion != 3) && (c.ProtocolVersion != 5) {
+		v.fail("mqtt.protocolVersion", "must be 3 or 5")
+	}
+	v.notNegative("mqtt.messageExpiry", c.MessageExpiry)
+	v.positive("mqtt.connTimeout", c.ConnTimeout)
+	v.positive("mqtt.announceTimeout", c.AnnounceTimeout)
+	v.positive("mqtt.disconnectTimeout", c.DisconnectTimeout)
+	v.notNegative("mqtt.failoverThreshold", c.FailoverThreshold)
+	v.positive("mqtt.fallbackInterval", c.FallbackInterval)
+	v.positive("mqtt.reconnectInterval", c.ReconnectInterval)
+
+	if (c.Broker != "") && (len(c.Brokers) > 0) {
+		v.fail("mqtt.broker", "can't be used together with mqtt.brokers")
+	}
+	brokers := c.BrokerList()
+	if len(brokers) == 0 {
+		v.fail("mqtt.brokers", "at least one broker is required")
+	}
+	for i, b := range brokers {
+		field := fmt.Sprintf("mqtt.brokers[%d].url", i)
+		if len(c.Brokers) == 0 {
+			field = "mqtt.broker"
+		}
+		u, err := url.Parse(b.Url)
+		if err != nil {
+			v.fail(field, err.Error())
+		} else if (u.Scheme == "") || (u.Host == "") {
+			v.fail(field, "must be of the form scheme://host:port")
+		}
+		if (b.CertFile == "") != (b.KeyFile == "") {
+			v.fail(fmt.Sprintf("mqtt.brokers[%d]", i), "certFile and keyFile must be set together")
+		}
+	}
+}
+
+// validator collects errors of a validation pass
+type validator struct {
+	errs []error
+}
+
+func (v *validator) fail(field string, msg string) {
+	v.errs = append(v.errs, fmt.Errorf("%s: %s", field, msg))
+}
+
+func (v *validator) required(field string, value string) {
+	if value == "" {
+		v.fail(field, "is required")
+	}
+}
+
+func (v *validator) positive(field string, value int) {
+	if value <= 0 {
+		v.fail(field, "must be positive")
+	}
+}
+
+func (v *validator) notNegative(field string, value int) {
+	if value < 0 {
+		v.fail(field, "must not be negative")
+	}
+}
+
+// unique fails when value, unless empty, is already used by another
+// setting in used, which maps values to settings using them
+func (v *validator) unique(used map[string]string, field string, value string) {
+	if value == "" {
+		return
+	}
+	if other, ok := used[value]; ok {
+		v.fail(field, fmt.Sprintf("%s is already used by %s", value, other))
+		return
+	}
+	used[value] = field
+}
+
+// uniqueListener fails when address is listened on by another setting:
+// the same port of the same network on the same host or on all interfaces.
+// Invalid addresses are left to the checks of their sections.
+func (v *validator) uniqueListener(used *[]listener, field string, network string, address string) {
+	host, port, err := net.SplitHostPort(address)
+	if (err != nil) || (port == "0") {
+		// port 0 is a free port chosen by the system
+		return
+	}
+	if (host == "0.0.0.0") || (host == "::") {
+		host = ""
+	}
+	for _, other := range *used {
+		if (other.network == network) && (other.port == port) &&
+			((other.host == host) || (other.host == "") || (host == "")) {
+			v.fail(field, fmt.Sprintf("%s is already used by %s", address, other.field))
+			return
+		}
+	}
+	*used = append(*used, listener{field: field, network: network, host: host, port: port})
+}
+
+// unknownFields returns JSON paths of settings in data that don't
+// correspond to any Config field, e.g. misspelled ones
+func unknownFields(data []byte) ([]string, error) {
+	var tree any
+	err := json.Unmarshal(data, &tree)
+	if err != nil {
+		return nil, err
+	}
+
+	unknown := make([]string, 0)
+	findUnknown("", tree, reflect.TypeOf(Config{}), &unknown)
+	sort.Strings(unknown)
+	return unknown, nil
+}
+
+func findUnknown(path string, value any, t reflect.Type, unknown *[]string) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		if t.Kind() != reflect.Struct {
+			return
+		}
+		fields := jsonFields(t)
+		for key, child := range v {
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+			// encoding/json matches keys case-insensitively
+			fieldType, ok := fields[strings.ToLower(key)]
+			if !ok {
+				*unknown = append(*unknown, childPath)
+				continue
+			}
+			findUnknown(childPath, child, fieldType, unknown)
+		}
+	case []any:
+		if t.Kind() != reflect.Slice {
+			return
+		}
+		for i, child := range v {
+			findUnknown(fmt.Sprintf("%s[%d]", path, i), child, t.Elem(), unknown)
+		}
+	}
+}
+
+// jsonFields maps lowercased JSON names of struct fields, including fields
+// of embedded structs, to their types
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			for name, fieldType := range jsonFields(field.Type) {
+				fields[name] = fieldType
+			}
+			continue
+		}
+		name, ok := jsonFieldName(field)
+		if !ok {
+			continue
+		}
+		fields[strings.ToLower(name)] = field.Type
+	}
+	return fields
+}
@@ -0,0 +1,453 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDefaults(t *testing.T) {
+	cfg, err := Parse([]byte(`{
+		"shipId": "ship",
+		"shipControl": {"socketName": "/tmp/sc.sock"},
+		"shipNav": {"socketName": "/tmp/sn.sock"},
+		"mqtt": {"enabled": true, "broker": "tcp://localhost:1883"}
+	}`), nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to parse configuration: %s", err)
+	}
+
+	if cfg.AnnounceInterval != DefaultAnnounceInterval {
+		t.Errorf("Expected default announce interval, got %d", cfg.AnnounceInterval)
+	}
+	if cfg.ShipControl.QueueSize != DefaultQueueSize {
+		t.Errorf("Expected default ship-control queue size, got %d", cfg.ShipControl.QueueSize)
+	}
+	if cfg.Mqtt.ConnTimeout != DefaultConnTimeout {
+		t.Errorf("Expected default mqtt connection timeout, got %d", cfg.Mqtt.ConnTimeout)
+	}
+	if cfg.Supervisor.ShutdownTimeout != DefaultShutdownTimeout {
+		t.Errorf("Expected default shutdown timeout, got %d", cfg.Supervisor.ShutdownTimeout)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	_, err := Parse([]byte(`{
+		"shipId": "ship",
+		"logLevel": "loud",
+		"annouceInterval": 1000,
+		"shipControl": {"socketName": "/tmp/sc.sock", "queueSize": -1},
+		"mqtt": {"enabled": true, "broker": "localhost", "AnnounceTopic": "ships", "protocolVersion": 4}
+	}`), nil, nil)
+	if err == nil {
+		t.Fatalf("Expected invalid configuration to be rejected")
+	}
+
+	msg := err.Error()
+	for _, expected := range []string{
+		"annouceInterval: unknown setting",
+		"logLevel:",
+		"shipControl.queueSize: must be positive",
+		"shipNav: section is required",
+		"mqtt.broker: must be of the form scheme://host:port",
+		"mqtt.protocolVersion: must be 3 or 5",
+	} {
+		if !strings.Contains(msg, expected) {
+			t.Errorf("Expected error %q, got:\n%s", expected, msg)
+		}
+	}
+	// keys are matched case-insensitively like encoding/json does
+	if strings.Contains(msg, "AnnounceTopic") {
+		t.Errorf("Expected AnnounceTopic to be accepted, got:\n%s", msg)
+	}
+}
+
+func TestVessels(t *testing.T) {
+	cfg, err := Parse([]byte(`{
+		"mqtt": {"broker": "tcp://localhost:1883"},
+		"vessels": [
+			{"shipId": "d1", "shipControl": {"socketName": "/tmp/sc1"}, "shipNav": {"socketName": "/tmp/sn1"}},
+			{"shipId": "d2", "requestTopic": "drones/d2/rq",
+				"shipControl": {"socketName": "/tmp/sc2"}, "shipNav": {"socketName": "/tmp/sn2"}}
+		]
+	}`), nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to parse configuration: %s", err)
+	}
+	vessels := cfg.VesselList()
+	if (len(vessels) != 2) || (vessels[1].ShipNav.QueueSize != DefaultQueueSize) {
+		t.Errorf("Expected 2 vessels with defaults applied, got %v", vessels)
+	}
+
+	_, err = Parse([]byte(`{
+		"mqtt": {"broker": "tcp://localhost:1883"},
+		"vessels": [
+			{"shipId": "d1", "shipControl": {"socketName": "/tmp/sc1"}, "shipNav": {"socketName": "/tmp/sn1"}},
+			{"shipId": "d2", "requestTopic": "ship/d1/request",
+				"shipControl": {"socketName": "/tmp/sc1"}, "shipNav": {"socketName": "/tmp/sc2"}}
+		]
+	}`), nil, nil)
+	if err == nil {
+		t.Fatalf("Expected vessels sharing topics and sockets to be rejected")
+	}
+	msg := err.Error()
+	for _, expected := range []string{
+		"vessels[1].requestTopic: ship/d1/request is already used by vessels[0].requestTopic",
+		"vessels[1].shipControl.socketName: /tmp/sc1 is already used by vessels[0].shipControl.socketName",
+	} {
+		if !strings.Contains(msg, expected) {
+			t.Errorf("Expected error %q, got:\n%s", expected, msg)
+		}
+	}
+
+	_, err = Parse([]byte(`{
+		"shipId": "ship",
+		"vessels": [
+			{"shipId": "d1", "shipControl": {"socketName": "/tmp/sc1"}, "shipNav": {"socketName": "/tmp/sn1"}},
+			{"shipId": "d1", "shipControl": {"socketName": "/tmp/sc2"}}
+		]
+	}`), nil, nil)
+	if err == nil {
+		t.Fatalf("Expected invalid vessels to be rejected")
+	}
+	msg = err.Error()
+	for _, expected := range []string{
+		"shipId: can't be used together with vessels",
+		"vessels[1].shipId: is used by another vessel",
+		"vessels[1].shipNav: section is required",
+	} {
+		if !strings.Contains(msg, expected) {
+			t.Errorf("Expected error %q, got:\n%s", expected, msg)
+		}
+	}
+}
+
+func TestListeners(t *testing.T) {
+	_, err := Parse([]byte(`{
+		"admin": {"address": "127.0.0.1:8080"},
+		"vessels": [
+			{"shipId": "d1", "shipControl": {"socketName": "/tmp/sc1"}, "shipNav": {"socketName": "/tmp/sn1"},
+				"webSocket": {"address": ":8081", "token": "secret"},
+				"mavlink": {"address": ":14550", "allowedNetworks": ["192.168.1.0/24"]},
+				"signalK": {"address": ":3000"}},
+			{"shipId": "d2", "shipControl": {"socketName": "/tmp/sc2"}, "shipNav": {"socketName": "/tmp/sn2"},
+				"webSocket": {"address": "0.0.0.0:8081", "token": "secret"},
+				"mavlink": {"address": ":14550", "allowedNetworks": ["192.168.1.0/24"]},
+				"nmeaOutput": {"tcpAddress": ":8080"},
+				"signalK": {"address": "127.0.0.1:3000"}}
+		]
+	}`), nil, nil)
+	if err == nil {
+		t.Fatalf("Expected vessels sharing listen addresses to be rejected")
+	}
+	msg := err.Error()
+	for _, expected := range []string{
+		"vessels[1].webSocket.address: 0.0.0.0:8081 is already used by vessels[0].webSocket.address",
+		"vessels[1].mavlink.address: :14550 is already used by vessels[0].mavlink.address",
+		"vessels[1].nmeaOutput.tcpAddress: :8080 is already used by admin.address",
+		"vessels[1].signalK.address: 127.0.0.1:3000 is already used by vessels[0].signalK.address",
+	} {
+		if !strings.Contains(msg, expected) {
+			t.Errorf("Expected error %q, got:\n%s", expected, msg)
+		}
+	}
+
+	// the same port on other interfaces or of another network is fine
+	_, err = Parse([]byte(`{
+		"admin": {"address": "127.0.0.1:8080"},
+		"shipId": "ship",
+		"shipControl": {"socketName": "/tmp/sc.sock"},
+		"shipNav": {"socketName": "/tmp/sn.sock"},
+		"webSocket": {"address": "192.168.1.2:8080", "token": "secret"},
+		"mavlink": {"address": ":8080", "allowedNetworks": ["192.168.1.0/24"]}
+	}`), nil, nil)
+	if err != nil {
+		t.Errorf("Expected distinct listen addresses to be accepted, got %s", err)
+	}
+}
+
+func TestDaemonAddress(t *testing.T) {
+	_, err := Parse([]byte(`{
+		"shipId": "ship",
+		"shipControl": {"socketName": "serial:///dev/ttyS1", "baudRate": 57600},
+		"shipNav": {"socketName": "tls://10.0.0.2:7002", "tls": {"caFile": "/etc/ca.pem"}}
+	}`), nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to parse configuration: %s", err)
+	}
+
+	_, err = Parse([]byte(`{
+		"shipId": "ship",
+		"shipControl": {"socketName": "tcp://10.0.0.2", "baudRate": 57600},
+		"shipNav": {"socketName": "serial:///dev/ttyS2", "tls": {"certFile": "/etc/nav.pem"}}
+	}`), nil, nil)
+	if err == nil {
+		t.Fatalf("Expected invalid daemon addresses to be rejected")
+	}
+	msg := err.Error()
+	for _, expected := range []string{
+		"shipControl.socketName: tcp address must be of the form tcp://host:port",
+		"shipNav.tls: only applies to tls:// addresses",
+		"shipNav.tls: certFile and keyFile must be set together",
+		"shipNav.socketName: serial ports are only supported for shipControl",
+	} {
+		if !strings.Contains(msg, expected) {
+			t.Errorf("Expected error %q, got:\n%s", expected, msg)
+		}
+	}
+}
+
+func TestNmeaInput(t *testing.T) {
+	cfg, err := Parse([]byte(`{
+		"shipId": "ship",
+		"shipControl": {"socketName": "/tmp/sc.sock"},
+		"shipNav": {"socketName": "/tmp/sn.sock"},
+		"nmeaInput": {"address": "serial:///dev/ttyUSB0", "baudRate": 4800}
+	}`), nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to parse configuration: %s", err)
+	}
+	if cfg.VesselList()[0].NmeaInput.Interval != DefaultNmeaInterval {
+		t.Errorf("Expected default NMEA interval, got %d", cfg.NmeaInput.Interval)
+	}
+
+	_, err = Parse([]byte(`{
+		"vessels": [
+			{"shipId": "d1", "shipControl": {"socketName": "/tmp/sc1"}, "shipNav": {"socketName": "/tmp/sn1"},
+				"nmeaInput": {"address": "udp://:10110", "baudRate": 4800}},
+			{"shipId": "d2", "shipControl": {"socketName": "/tmp/sc2"}, "shipNav": {"socketName": "/tmp/sn2"},
+				"nmeaInput": {"address": "http://gps.local"}}
+		],
+		"nmeaInput": {"address": "tcp://gps.local:10110"}
+	}`), nil, nil)
+	if err == nil {
+		t.Fatalf("Expected invalid NMEA inputs to be rejected")
+	}
+	msg := err.Error()
+	for _, expected := range []string{
+		"nmeaInput: can't be used together with vessels, set it per vessel",
+		"vessels[0].nmeaInput.baudRate: only applies to serial:// addresses",
+		`vessels[1].nmeaInput.address: unsupported scheme "http"`,
+	} {
+		if !strings.Contains(msg, expected) {
+			t.Errorf("Expected error %q, got:\n%s", expected, msg)
+		}
+	}
+}
+
+func TestNmeaOutput(t *testing.T) {
+	_, err := Parse([]byte(`{
+		"shipId": "ship",
+		"shipControl": {"socketName": "/tmp/sc.sock"},
+		"shipNav": {"socketName": "/tmp/sn.sock"},
+		"nmeaOutput": {"udpAddress": ":10110", "interval": -1}
+	}`), nil, nil)
+	if err == nil {
+		t.Fatalf("Expected invalid NMEA output to be rejected")
+	}
+	msg := err.Error()
+	for _, expected := range []string{
+		"nmeaOutput.interval: must be positive",
+		"nmeaOutput.udpAddress: host is required",
+	} {
+		if !strings.Contains(msg, expected) {
+			t.Errorf("Expected error %q, got:\n%s", expected, msg)
+		}
+	}
+
+	cfg, err := Parse([]byte(`{
+		"shipId": "ship",
+		"shipControl": {"socketName": "/tmp/sc.sock"},
+		"shipNav": {"socketName": "/tmp/sn.sock"},
+		"nmeaOutput": {"tcpAddress": ":10110", "udpAddress": "192.168.1.255:10110"}
+	}`), nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to parse configuration: %s", err)
+	}
+	if cfg.NmeaOutput.ArrivalRadius != DefaultArrivalRadius {
+		t.Errorf("Expected default arrival radius, got %d", cfg.NmeaOutput.ArrivalRadius)
+	}
+}
+
+func TestAis(t *testing.T) {
+	_, err := Parse([]byte(`{
+		"vessels": [
+			{"shipId": "a", "shipControl": {"socketName": "/tmp/a-sc.sock"}, "shipNav": {"socketName": "/tmp/a-sn.sock"},
+				"ais": {"address": "udp://:10111", "navStopCpa": 1000}},
+			{"shipId": "b", "shipControl": {"socketName": "/tmp/b-sc.sock"}, "shipNav": {"socketName": "/tmp/b-sn.sock"},
+				"ais": {"address": "tcp://ais.local:10110", "baudRate": 38400, "range": -1}}
+		],
+		"ais": {"address": "udp://:10111"}
+	}`), nil, nil)
+	if err == nil {
+		t.Fatalf("Expected invalid AIS settings to be rejected")
+	}
+	msg := err.Error()
+	for _, expected := range []string{
+		"ais: can't be used together with vessels, set it per vessel",
+		"vessels[0].ais: navStopCpa and navStopTcpa can't exceed warnCpa and warnTcpa",
+		"vessels[1].ais.baudRate: only applies to serial:// addresses",
+		"vessels[1].ais.range: must not be negative",
+	} {
+		if !strings.Contains(msg, expected) {
+			t.Errorf("Expected error %q, got:\n%s", expected, msg)
+		}
+	}
+
+	cfg, err := Parse([]byte(`{
+		"shipId": "ship",
+		"shipControl": {"socketName": "/tmp/sc.sock"},
+		"shipNav": {"socketName": "/tmp/sn.sock"},
+		"ais": {"address": "serial:///dev/ttyUSB1", "navStopCpa": 200}
+	}`), nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to parse configuration: %s", err)
+	}
+	ais := cfg.VesselList()[0].Ais
+	if (ais.WarnCpa != DefaultWarnCpa) || (ais.NavStopTcpa != DefaultNavStopTcpa) || (ais.Range != 0) {
+		t.Errorf("Expected AIS defaults, got %+v", ais)
+	}
+}
+
+func TestMavlink(t *testing.T) {
+	_, err := Parse([]byte(`{
+		"shipId": "ship",
+		"shipControl": {"socketName": "/tmp/sc.sock"},
+		"shipNav": {"socketName": "/tmp/sn.sock"},
+		"mavlink": {"address": "14550", "gcsAddress": ":14550", "systemId": 256,
+			"allowedNetworks": ["192.168.1.0/24", "10.0.0.1"]}
+	}`), nil, nil)
+	if err == nil {
+		t.Fatalf("Expected invalid MAVLink settings to be rejected")
+	}
+	msg := err.Error()
+	for _, expected := range []string{
+		"mavlink.address: address 14550: missing port in address",
+		"mavlink.gcsAddress: host is required",
+		"mavlink.systemId: must be between 1 and 255",
+		"mavlink.allowedNetworks[1]: invalid CIDR address",
+	} {
+		if !strings.Contains(msg, expected) {
+			t.Errorf("Expected error %q, got:\n%s", expected, msg)
+		}
+	}
+
+	// any host could take over the vessel otherwise
+	_, err = Parse([]byte(`{
+		"shipId": "ship",
+		"shipControl": {"socketName": "/tmp/sc.sock"},
+		"shipNav": {"socketName": "/tmp/sn.sock"},
+		"mavlink": {"address": ":14555"}
+	}`), nil, nil)
+	if (err == nil) || !strings.Contains(err.Error(), "mavlink.allowedNetworks: is required") {
+		t.Errorf("Expected allowedNetworks to be required, got %v", err)
+	}
+
+	cfg, err := Parse([]byte(`{
+		"shipId": "ship",
+		"shipControl": {"socketName": "/tmp/sc.sock"},
+		"shipNav": {"socketName": "/tmp/sn.sock"},
+		"mavlink": {"address": ":14555", "allowedNetworks": ["192.168.1.0/24"]}
+	}`), nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to parse configuration: %s", err)
+	}
+	mavlink := cfg.VesselList()[0].Mavlink
+	if (mavlink.SystemId != DefaultSystemId) || (mavlink.MaxSteering != DefaultControlScale) ||
+		(len(mavlink.Networks()) != 1) {
+		t.Errorf("Expected MAVLink defaults, got %+v", mavlink)
+	}
+}
+
+func TestSignalK(t *testing.T) {
+	_, err := Parse([]byte(`{
+		"vessels": [
+			{"shipId": "a", "shipControl": {"socketName": "/tmp/a-sc.sock"}, "shipNav": {"socketName": "/tmp/a-sn.sock"},
+				"signalK": {"mqttTopic": "ship/a/signalk", "mmsi": "23012345"}},
+			{"shipId": "b", "shipControl": {"socketName": "/tmp/b-sc.sock"}, "shipNav": {"socketName": "/tmp/b-sn.sock"},
+				"signalK": {"interval": -1}}
+		],
+		"signalK": {"address": ":3000"}
+	}`), nil, nil)
+	if err == nil {
+		t.Fatalf("Expected invalid Signal K settings to be rejected")
+	}
+	msg := err.Error()
+	for _, expected := range []string{
+		"signalK: can't be used together with vessels, set it per vessel",
+		"vessels[0].signalK.mmsi: must be 9 digits",
+		"vessels[0].signalK.mqttTopic: requires mqtt to be enabled",
+		"vessels[1].signalK: address or mqttTopic is required",
+		"vessels[1].signalK.interval: must be positive",
+	} {
+		if !strings.Contains(msg, expected) {
+			t.Errorf("Expected error %q, got:\n%s", expected, msg)
+		}
+	}
+
+	cfg, err := Parse([]byte(`{
+		"shipId": "ship",
+		"mqtt": {"enabled": true, "broker": "tcp://localhost:1883"},
+		"shipControl": {"socketName": "/tmp/sc.sock"},
+		"shipNav": {"socketName": "/tmp/sn.sock"},
+		"signalK": {"address": ":3000", "mqttTopic": "ship/ship/signalk", "mmsi": "230123450"}
+	}`), nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to parse configuration: %s", err)
+	}
+	signalK := cfg.VesselList()[0].SignalK
+	if (signalK.Interval != DefaultSignalKInterval) || (signalK.MaxAge != DefaultSignalKMaxAge) {
+		t.Errorf("Expected Signal K defaults, got %+v", signalK)
+	}
+}
+
+func TestWebSocketToken(t *testing.T) {
+	_, err := Parse([]byte(`{
+		"vessels": [
+			{"shipId": "a", "shipControl": {"socketName": "/tmp/a-sc.sock"}, "shipNav": {"socketName": "/tmp/a-sn.sock"},
+				"webSocket": {"address": ":8081"}}
+		]
+	}`), nil, nil)
+	if (err == nil) || !strings.Contains(err.Error(), "vessels[0].webSocket.token: is required") {
+		t.Errorf("Expected webSocket token to be required, got %v", err)
+	}
+}
+
+func TestGrpc(t *testing.T) {
+	_, err := Parse([]byte(`{
+		"shipId": "ship",
+		"shipControl": {"socketName": "/tmp/sc.sock"},
+		"shipNav": {"socketName": "/tmp/sn.sock"},
+		"grpc": {"address": "50051", "certFile": "/etc/bridge/cert.pem", "responseTimeout": -1}
+	}`), nil, nil)
+	if err == nil {
+		t.Fatalf("Expected invalid gRPC settings to be rejected")
+	}
+	msg := err.Error()
+	for _, expected := range []string{
+		"grpc.address: address 50051: missing port in address",
+		"grpc: certFile and keyFile must be set together",
+		"grpc.responseTimeout: must be positive",
+		"grpc.token: is required",
+	} {
+		if !strings.Contains(msg, expected) {
+			t.Errorf("Expected error %q, got:\n%s", expected, msg)
+		}
+	}
+
+	// the server is shared by vessels
+	cfg, err := Parse([]byte(`{
+		"vessels": [
+			{"shipId": "a", "shipControl": {"socketName": "/tmp/a-sc.sock"}, "shipNav": {"socketName": "/tmp/a-sn.sock"}}
+		],
+		"grpc": {"address": ":50051", "token": "secret"}
+	}`), nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to parse configuration: %s", err)
+	}
+	if (cfg.Grpc.ResponseTimeout != DefaultResponseTimeout) || (cfg.Grpc.ClientQueueSize != DefaultClientQueueSize) {
+		t.Errorf("Expected gRPC defaults, got %+v", cfg.Grpc)
+	}
+	if cfg.Redacted().Grpc.Token != redactedValue {
+		t.Errorf("Expected gRPC token to be redacted")
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/moosethebrown/ship-net-bridge/config"
+)
+
+// convertConfig implements "config convert [-from format] [-to format] input [output]",
+// formats are told by file extensions unless set, output goes to stdout
+// as YAML if not given; returns exit code
+func convertConfig(args []string) int {
+	flags := flag.NewFlagSet("config convert", flag.ContinueOnError)
+	from := flags.String("from", "", "input format: json, yaml or toml")
+	to := flags.String("to", "", "output format: json, yaml or toml")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s config convert [-from format] [-to format] input [output]\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	err := flags.Parse(args)
+	if err != nil {
+		return 2
+	}
+	if (flags.NArg() < 1) || (flags.NArg() > 2) {
+		flags.Usage()
+		return 2
+	}
+	input := flags.Arg(0)
+	output := flags.Arg(1)
+
+	fromFormat := config.FormatOf(input)
+	if *from != "" {
+		fromFormat, err = config.ParseFormat(*from)
+		if err != nil {
+			fmt.Println(err)
+			return 2
+		}
+	}
+	toFormat := config.FormatYAML
+	if output != "" {
+		toFormat = config.FormatOf(output)
+	}
+	if *to != "" {
+		toFormat, err = config.ParseFormat(*to)
+		if err != nil {
+			fmt.Println(err)
+			return 2
+		}
+	}
+
+	data, err := os.ReadFile(input)
+	if err != nil {
+		fmt.Printf("Error reading config: %s\n", err)
+		return 1
+	}
+	data, err = config.Convert(data, fromFormat, toFormat)
+	if err != nil {
+		fmt.Printf("Error converting config: %s\n", err)
+		return 1
+	}
+
+	if output == "" {
+		_, err = os.Stdout.Write(data)
+	} else {
+		err = os.WriteFile(output, data, 0600)
+	}
+	if err != nil {
+		fmt.Printf("Error writing config: %s\n", err)
+		return 1
+	}
+	return 0
+}
@@ -1,66 +1,151 @@
 package core
 
 import (
+	"context"
 	"encoding/json"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/moosethebrown/ship-net-bridge/metrics"
+	"github.com/moosethebrown/ship-net-bridge/queue"
 	"github.com/rs/zerolog"
 )
 
+// Daemon adapters pass origin of a command back to HandleResponse
+// so that the response reaches the transport the command came from
+
 type ShipControl interface {
-	SendRequest([]byte)
+	SendRequest([]byte, *Origin)
+	// EmergencyStop stops the ship ahead of any queued control commands
+	EmergencyStop(*Origin)
 }
 
 type ShipNav interface {
-	Query()
-	NavStart()
+	Query(*Origin)
+	NavStart(*Origin)
 	NavStop()
 	NetLoss()
-	SetWaypoints([]*Waypoint)
-	AddWaypoint(*Waypoint)
-	ClearWaypoints()
-	SetHomeWaypoint(*Waypoint)
-	StartCalibration()
-	StopCalibration()
+	SetWaypoints([]*Waypoint, *Origin)
+	AddWaypoint(*Waypoint, *Origin)
+	ClearWaypoints(*Origin)
+	SetHomeWaypoint(*Waypoint, *Origin)
+	StartCalibration(*Origin)
+	StopCalibration(*Origin)
 }
 
-type MqttHandler interface {
+// Transport is a front-end delivering operator requests to the core,
+// e.g. MQTT or WebSocket
+type Transport interface {
+	Name() string
 	SendResponse([]byte)
 	Announce()
 }
 
+// Origin identifies the transport and request id a response belongs to
+type Origin struct {
+	Transport Transport
+	RequestId string
+}
+
+type TrackRecorder interface {
+	Record(*Telemetry)
+	StartMission()
+	StopMission()
+	Export(format string, trackId int) ([]byte, error)
+	ExportToFile(format string, trackId int, fileName string) (string, error)
+}
+
+// DefaultDrainTimeout is how long queued requests are executed on shutdown
+const DefaultDrainTimeout = 2 * time.Second
+
 type Core struct {
 	shipControl      ShipControl
 	shipNav          ShipNav
-	mqttHandler      MqttHandler
-	announceInterval int
+	transports       []Transport
+	trackRecorder    TrackRecorder
+	announceInterval atomic.Int64
+	intervalChan     chan bool
 	logger           *zerolog.Logger
-	rqChan           chan *Request
-	respChan         chan []byte
-	stopChan         chan bool
+	requests         *queue.Priority[*Request]
+	responses        *queue.Priority[*response]
+	telemetry        *queue.Priority[*Telemetry]
+	fixes            *queue.Priority[*Fix]
 	netLossChan      chan bool
-	autoNav          bool
+	maxAge           map[string]time.Duration
+	skewTolerance    time.Duration
+	drainTimeout     time.Duration
+	autoNav          atomic.Bool
+	lastTelemetry    *Telemetry
+	lastFix          *Fix
+	waypoints        []Waypoint
+	clockSkew        *ClockSkew
+	statusMutex      sync.Mutex
+	limitsMutex      sync.RWMutex
+}
+
+type Status struct {
+	AutoNav           bool           `json:"autoNav"`
+	RequestQueue      int            `json:"requestQueue"`
+	RequestQueues     map[string]int `json:"requestQueues"`
+	ResponseQueue     int            `json:"responseQueue"`
+	TelemetryQueue    int            `json:"telemetryQueue"`
+	FixQueue          int            `json:"fixQueue"`
+	LastTelemetry     *Telemetry     `json:"lastTelemetry,omitempty"`
+	LastTelemetryTime *time.Time     `json:"lastTelemetryTime,omitempty"`
+	LastFix           *Fix           `json:"lastFix,omitempty"`
+	ClockSkew         *ClockSkew     `json:"clockSkew,omitempty"`
+}
+
+type response struct {
+	origin *Origin
+	data   []byte
 }
 
 func NewCore(shipControl ShipControl, shipNav ShipNav,
-	mqttHandler MqttHandler, announceInterval int, logger *zerolog.Logger) *Core {
-	return &Core{
-		shipControl:      shipControl,
-		shipNav:          shipNav,
-		mqttHandler:      mqttHandler,
-		announceInterval: announceInterval,
-		logger:           logger,
-		rqChan:           make(chan *Request, 1000),
-		respChan:         make(chan []byte, 1000),
-		stopChan:         make(chan bool, 1),
-		netLossChan:      make(chan bool, 1),
+	announceInterval int, logger *zerolog.Logger) *Core {
+	c := &Core{
+		shipControl:  shipControl,
+		shipNav:      shipNav,
+		transports:   make([]Transport, 0),
+		intervalChan: make(chan bool, 1),
+		logger:       logger,
+		requests:     NewRequestQueue(DefaultQueueSize, Supersedes),
+		responses:    queue.NewQueue[*response](1000),
+		telemetry:    newLatestQueue[*Telemetry](100),
+		fixes:        newLatestQueue[*Fix](10),
+		netLossChan:  make(chan bool, 1),
+		maxAge:       make(map[string]time.Duration),
+		drainTimeout: DefaultDrainTimeout,
+	}
+	c.announceInterval.Store(int64(announceInterval))
+
+	return c
+}
+
+// SetAnnounceInterval changes announce interval in milliseconds,
+// can be called any time
+func (c *Core) SetAnnounceInterval(announceInterval int) {
+	c.announceInterval.Store(int64(announceInterval))
+	select {
+	case c.intervalChan <- true:
+	default:
 	}
 }
 
-func (c *Core) SetMqttHandler(handler MqttHandler) {
-	c.mqttHandler = handler
+// SetDrainTimeout sets how long queued requests are still executed
+// after Run's context is cancelled. Must be called before Run.
+func (c *Core) SetDrainTimeout(timeout time.Duration) {
+	c.drainTimeout = timeout
+}
+
+// AddTransport registers a front-end, uncorrelated responses and announces
+// are sent to all registered transports; must be called before Run
+func (c *Core) AddTransport(transport Transport) {
+	c.transports = append(c.transports, transport)
 }
 
 func (c *Core) SetShipControl(shipControl ShipControl) {
@@ -71,106 +156,484 @@ func (c *Core) SetShipNav(shipNav ShipNav) {
 	c.shipNav = shipNav
 }
 
-func (c *Core) HandleRequest(msg []byte) {
+// SetTrackRecorder enables track recording, nil disables it
+func (c *Core) SetTrackRecorder(recorder TrackRecorder) {
+	c.trackRecorder = recorder
+}
+
+// HandleRequest is called by transports, requests with an id get responses
+// only through the transport they came from, transport may be nil
+func (c *Core) HandleRequest(transport Transport, msg []byte) {
+	c.HandleRequestWithMeta(transport, msg, nil)
+}
+
+// HandleRequestWithMeta is HandleRequest for transports that carry request
+// attributes outside of the message, meta may be nil
+func (c *Core) HandleRequestWithMeta(transport Transport, msg []byte, meta *RequestMeta) {
 	var rq Request
 	rq.waypoints = make([]*Waypoint, 0)
 
 	err := json.Unmarshal(msg, &rq)
 	if err != nil {
 		c.logger.Error().Err(err).Msg("failed to unmarshal request")
+		metrics.Errors.WithLabelValues("core", "unmarshal_request").Inc()
 		return
 	}
 	rq.rawData = msg
+	rq.received = time.Now()
+	if meta != nil {
+		rq.operator = meta.Operator
+		rq.deadline = meta.Deadline
+	}
+	c.checkClockSkew(&rq)
+	if (transport != nil) && ((rq.Id != "") || ((meta != nil) && meta.Correlated)) {
+		rq.origin = &Origin{
+			Transport: transport,
+			RequestId: rq.Id,
+		}
+	}
 	c.parseWaypoints(&rq)
 
-	c.rqChan <- &rq
+	c.enqueue(&rq)
+}
+
+// HandleResponse is called by daemon adapters, origin may be nil;
+// it never blocks, responses are dropped when the queue is full
+func (c *Core) HandleResponse(origin *Origin, resp []byte) {
+	result := c.responses.Push(0, &response{
+		origin: origin,
+		data:   resp,
+	})
+	if !result.Queued || result.Dropped {
+		c.logger.Error().Msgf("response queue is full, overflow policy %s",
+			c.responses.Policy())
+		metrics.Overflows.WithLabelValues("core", "responses",
+			c.responses.Policy().String()).Inc()
+	}
+}
+
+// HandleTelemetry never blocks, the oldest telemetry is dropped
+// when the queue is full
+func (c *Core) HandleTelemetry(t *Telemetry) {
+	if c.telemetry.Push(0, t).Dropped {
+		metrics.Overflows.WithLabelValues("core", "telemetry", queue.DropOldest.String()).Inc()
+	}
+}
+
+// HandleFix is called by NMEA inputs, it never blocks, the oldest fix
+// is dropped when the queue is full
+func (c *Core) HandleFix(f *Fix) {
+	if c.fixes.Push(0, f).Dropped {
+		metrics.Overflows.WithLabelValues("core", "fixes", queue.DropOldest.String()).Inc()
+	}
+}
 
+// LastFix returns the latest NMEA fix, nil if there is none yet;
+// it can be called from any goroutine
+func (c *Core) LastFix() *Fix {
+	c.statusMutex.Lock()
+	defer c.statusMutex.Unlock()
+	return c.lastFix
 }
 
-func (c *Core) HandleResponse(resp []byte) {
-	c.respChan <- resp
+// LastTelemetry returns the latest ship-nav telemetry, nil if there is
+// none yet; it can be called from any goroutine
+func (c *Core) LastTelemetry() *Telemetry {
+	c.statusMutex.Lock()
+	defer c.statusMutex.Unlock()
+	return c.lastTelemetry
 }
 
-func (c *Core) Run() {
-	ticker := time.NewTicker(time.Duration(time.Duration(c.announceInterval) * time.Millisecond))
+// Waypoints returns the waypoints last sent to ship-nav, in order;
+// it can be called from any goroutine
+func (c *Core) Waypoints() []Waypoint {
+	c.statusMutex.Lock()
+	defer c.statusMutex.Unlock()
+	return slices.Clone(c.waypoints)
+}
+
+// AutoNav tells whether ship-nav has been told to follow the waypoints,
+// it can be called from any goroutine
+func (c *Core) AutoNav() bool {
+	return c.autoNav.Load()
+}
+
+// NavStop stops autonav on behalf of the bridge itself, e.g. on collision
+// risk; it never blocks, the stop is queued as a safety command so that
+// it's never rejected or dropped when the queue is full
+func (c *Core) NavStop(reason string) {
+	c.logger.Warn().Msgf("stopping autonav: %s", reason)
+	c.enqueue(&Request{
+		Type:     RequestTypeCmd,
+		Cmd:      CmdNavStop,
+		received: time.Now(),
+	})
+}
+
+// Run returns when ctx is cancelled, after executing queued requests
+// for up to drain timeout
+func (c *Core) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(c.announceInterval.Load()) * time.Millisecond)
 	defer ticker.Stop()
 
 core_loop:
 	for {
 		select {
-		case rq := <-c.rqChan:
-			if rq.Type == RequestTypeCmd {
-				c.handleCommand(rq)
-			} else if rq.Type == RequestTypeQuery {
-				c.handleQuery()
-			} else {
-				c.logger.Error().Msgf("unknown request type: %s", rq.Type)
+		case <-c.requests.Ready():
+			rq, ok := c.requests.Pop()
+			if ok {
+				c.handleRequest(rq)
+			}
+		case <-c.responses.Ready():
+			resp, ok := c.responses.Pop()
+			if ok {
+				c.routeResponse(resp.origin, resp.data)
+			}
+		case <-c.telemetry.Ready():
+			t, ok := c.telemetry.Pop()
+			if !ok {
+				continue
 			}
-		case resp := <-c.respChan:
-			c.mqttHandler.SendResponse(resp)
+			c.statusMutex.Lock()
+			c.lastTelemetry = t
+			c.statusMutex.Unlock()
+			if c.trackRecorder != nil {
+				c.trackRecorder.Record(t)
+			}
+		case <-c.fixes.Ready():
+			f, ok := c.fixes.Pop()
+			if !ok {
+				continue
+			}
+			c.statusMutex.Lock()
+			c.lastFix = f
+			c.statusMutex.Unlock()
+			c.sendResponse(nil, &Response{
+				Type: ResponseTypeFix,
+				Fix:  f,
+			})
 		case <-ticker.C:
-			c.mqttHandler.Announce()
+			for _, transport := range c.transports {
+				transport.Announce()
+			}
+		case <-c.intervalChan:
+			ticker.Reset(time.Duration(c.announceInterval.Load()) * time.Millisecond)
 		case <-c.netLossChan:
 			c.shipNav.NetLoss()
-		case <-c.stopChan:
+		case <-ctx.Done():
+			c.drain()
 			break core_loop
 		}
 	}
 }
 
-func (c *Core) Stop() {
-	c.stopChan <- true
+// Status can be called from any goroutine
+func (c *Core) Status() any {
+	status := &Status{
+		AutoNav:        c.autoNav.Load(),
+		RequestQueue:   c.requests.Len(),
+		RequestQueues:  ClassDepths(c.requests.Lens()),
+		ResponseQueue:  c.responses.Len(),
+		TelemetryQueue: c.telemetry.Len(),
+		FixQueue:       c.fixes.Len(),
+	}
+
+	c.statusMutex.Lock()
+	defer c.statusMutex.Unlock()
+	if c.lastTelemetry != nil {
+		status.LastTelemetry = c.lastTelemetry
+		status.LastTelemetryTime = &c.lastTelemetry.Timestamp
+	}
+	status.LastFix = c.lastFix
+	if c.clockSkew != nil {
+		skew := *c.clockSkew
+		status.ClockSkew = &skew
+	}
+
+	return status
+}
+
+func (c *Core) QueueDepths() map[string]int {
+	depths := map[string]int{
+		"responses": c.responses.Len(),
+		"telemetry": c.telemetry.Len(),
+		"fixes":     c.fixes.Len(),
+	}
+	for class, n := range ClassDepths(c.requests.Lens()) {
+		depths["requests_"+class] = n
+	}
+	return depths
 }
 
+// NetLoss never blocks, a net loss already waiting to be handled is enough
 func (c *Core) NetLoss() {
-	c.netLossChan <- true
+	select {
+	case c.netLossChan <- true:
+	default:
+	}
+}
+
+// drain executes requests still queued on shutdown until the queue is empty
+// or drain timeout expires, then routes pending responses
+func (c *Core) drain() {
+	deadline := time.Now().Add(c.drainTimeout)
+	for time.Now().Before(deadline) {
+		rq, ok := c.requests.Pop()
+		if !ok {
+			break
+		}
+		c.handleRequest(rq)
+	}
+	if n := c.requests.Len(); n > 0 {
+		c.logger.Warn().Msgf("drain timeout expired, dropping %d queued requests", n)
+	}
+
+	for resp, ok := c.responses.Pop(); ok; resp, ok = c.responses.Pop() {
+		c.routeResponse(resp.origin, resp.data)
+	}
+}
+
+// requestLabels returns metric labels of rq, request types and commands
+// the core doesn't know are "unknown" so that clients can't create
+// any number of time series
+func requestLabels(rq *Request) (string, string) {
+	rqType := rq.Type
+	if (rqType != RequestTypeCmd) && (rqType != RequestTypeQuery) {
+		rqType = "unknown"
+	}
+	cmd := rq.Cmd
+	if (cmd != "") && !slices.Contains(Commands, cmd) {
+		cmd = "unknown"
+	}
+	return rqType, cmd
+}
+
+func (c *Core) handleRequest(rq *Request) {
+	metrics.Requests.WithLabelValues(requestLabels(rq)).Inc()
+
+	reason := c.staleReason(rq, time.Now())
+	if reason != "" {
+		c.logger.Warn().Msgf("rejecting stale %s request %s: %s", rq.Type, rq.Cmd, reason)
+		c.sendError(rq, "stale_request", reason)
+		return
+	}
+
+	if rq.Type == RequestTypeCmd {
+		c.handleCommand(rq)
+	} else if rq.Type == RequestTypeQuery {
+		c.handleQuery(rq)
+	} else {
+		c.logger.Error().Msgf("unknown request type: %s", rq.Type)
+		metrics.Errors.WithLabelValues("core", "unknown_request_type").Inc()
+	}
 }
 
 func (c *Core) handleCommand(rq *Request) {
-	if (rq.Cmd == CmdSpeedUp) || (rq.Cmd == CmdSpeedDown) ||
+	if rq.operator != "" {
+		c.logger.Info().Msgf("command %s from operator %s", rq.Cmd, rq.operator)
+	}
+
+	if rq.Cmd == CmdEmergencyStop {
+		c.logger.Warn().Msg("emergency stop")
+		c.stopAutoNav()
+		c.shipControl.EmergencyStop(rq.origin)
+	} else if rq.Cmd == CmdNavStop {
+		c.stopAutoNav()
+	} else if (rq.Cmd == CmdSpeedUp) || (rq.Cmd == CmdSpeedDown) ||
 		(rq.Cmd == CmdTurnLeft) || (rq.Cmd == CmdTurnRight) ||
 		(rq.Cmd == CmdSetSpeed) || (rq.Cmd == CmdSetSteering) {
 		// control commands go to ship-control directly
-		c.shipControl.SendRequest(rq.rawData)
-		if c.autoNav {
+		c.shipControl.SendRequest(rq.rawData, rq.origin)
+		if c.autoNav.Load() {
 			c.logger.Info().Msg("received control command, stopping autonav")
-			c.shipNav.NavStop()
-			c.autoNav = false
+			c.stopAutoNav()
 		}
 	} else if rq.Cmd == CmdSetWaypoints {
 		if len(rq.waypoints) == 0 {
 			c.logger.Error().Msgf("no waypoints provided for set_waypoints command")
+			metrics.Errors.WithLabelValues("core", "no_waypoints").Inc()
 			return
 		}
-		c.shipNav.SetWaypoints(rq.waypoints)
+		c.shipNav.SetWaypoints(rq.waypoints, rq.origin)
+		c.setWaypoints(rq.waypoints, false)
 	} else if rq.Cmd == CmdAddWaypoint {
 		if len(rq.waypoints) == 0 {
 			c.logger.Error().Msgf("no waypoints provided for add_waypoint command")
+			metrics.Errors.WithLabelValues("core", "no_waypoints").Inc()
 			return
 		}
-		c.shipNav.AddWaypoint(rq.waypoints[0])
+		c.shipNav.AddWaypoint(rq.waypoints[0], rq.origin)
+		c.setWaypoints(rq.waypoints[:1], true)
 	} else if rq.Cmd == CmdClearWaypoints {
-		c.shipNav.ClearWaypoints()
+		c.shipNav.ClearWaypoints(rq.origin)
+		c.setWaypoints(nil, false)
 	} else if rq.Cmd == CmdSetHomeWaypoint {
 		if len(rq.waypoints) == 0 {
 			c.logger.Error().Msgf("no waypoints provided for set_home_waypoint command")
+			metrics.Errors.WithLabelValues("core", "no_waypoints").Inc()
 			return
 		}
-		c.shipNav.SetHomeWaypoint(rq.waypoints[0])
+		c.shipNav.SetHomeWaypoint(rq.waypoints[0], rq.origin)
 	} else if rq.Cmd == CmdNavStart {
-		c.shipNav.NavStart()
-		c.autoNav = true
+		c.shipNav.NavStart(rq.origin)
+		c.autoNav.Store(true)
+		if c.trackRecorder != nil {
+			c.trackRecorder.StartMission()
+		}
 	} else if rq.Cmd == CmdStartCalibration {
-		c.shipNav.StartCalibration()
+		c.shipNav.StartCalibration(rq.origin)
 	} else if rq.Cmd == CmdStopCalibration {
-		c.shipNav.StopCalibration()
+		c.shipNav.StopCalibration(rq.origin)
+	} else if rq.Cmd == CmdExportTrack {
+		c.exportTrack(rq)
 	} else {
 		c.logger.Error().Msgf("unknown command: %s", rq.Cmd)
+		metrics.Errors.WithLabelValues("core", "unknown_command").Inc()
+	}
+}
+
+func (c *Core) stopAutoNav() {
+	c.shipNav.NavStop()
+	c.autoNav.Store(false)
+	if c.trackRecorder != nil {
+		c.trackRecorder.StopMission()
 	}
 }
 
-func (c *Core) handleQuery() {
-	c.shipNav.Query()
+// setWaypoints replaces or extends the waypoints kept for Waypoints
+func (c *Core) setWaypoints(waypoints []*Waypoint, add bool) {
+	c.statusMutex.Lock()
+	defer c.statusMutex.Unlock()
+
+	if !add {
+		c.waypoints = nil
+	}
+	for _, wp := range waypoints {
+		c.waypoints = append(c.waypoints, *wp)
+	}
+}
+
+func (c *Core) handleQuery(rq *Request) {
+	c.shipNav.Query(rq.origin)
+}
+
+// exportTrack handles export_track command, its data has the form
+// "format[;trackId[;fileName]]", trackId 0 or empty means the latest mission;
+// without fileName the exported track is sent back in the response
+func (c *Core) exportTrack(rq *Request) {
+	if c.trackRecorder == nil {
+		c.sendError(rq, "command_failed", "track recording is disabled")
+		return
+	}
+
+	params := strings.Split(rq.Data, ";")
+	format := params[0]
+	trackId := 0
+	if len(params) > 1 && params[1] != "" {
+		var err error
+		trackId, err = strconv.Atoi(params[1])
+		if err != nil {
+			c.logger.Error().Err(err).Msg("failed to parse track id")
+			c.sendError(rq, "command_failed", "invalid track id")
+			return
+		}
+	}
+
+	fileName := ""
+	if len(params) > 2 {
+		fileName = params[2]
+	}
+	// exporting may take a while, the result is routed by Run
+	// like daemon responses
+	go c.runExport(rq, format, trackId, fileName)
+}
+
+// runExport exports a track outside Run and queues the response
+func (c *Core) runExport(rq *Request, format string, trackId int, fileName string) {
+	resp := &Response{
+		Type: ResponseTypeTrack,
+		Cmd:  rq.Cmd,
+	}
+	var err error
+	if fileName != "" {
+		resp.Data, err = c.trackRecorder.ExportToFile(format, trackId, fileName)
+		if err == nil {
+			c.logger.Info().Msgf("track exported to %s", resp.Data)
+		}
+	} else {
+		var data []byte
+		data, err = c.trackRecorder.Export(format, trackId)
+		resp.Data = string(data)
+	}
+	if err != nil {
+		c.logger.Error().Err(err).Msg("failed to export track")
+		metrics.Errors.WithLabelValues("core", "command_failed").Inc()
+		resp = &Response{
+			Type:  ResponseTypeError,
+			Cmd:   rq.Cmd,
+			Error: err.Error(),
+		}
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("failed to marshal response")
+		return
+	}
+	c.HandleResponse(rq.origin, data)
+}
+
+// sendError responds to rq with msg, reason is counted as the error metric label
+func (c *Core) sendError(rq *Request, reason string, msg string) {
+	metrics.Errors.WithLabelValues("core", reason).Inc()
+	c.sendResponse(rq.origin, &Response{
+		Type:  ResponseTypeError,
+		Cmd:   rq.Cmd,
+		Error: msg,
+	})
+}
+
+func (c *Core) sendResponse(origin *Origin, resp *Response) {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("failed to marshal response")
+		return
+	}
+	c.routeResponse(origin, data)
+}
+
+// routeResponse sends correlated responses to the transport of the request
+// with request id added, uncorrelated ones to all transports
+func (c *Core) routeResponse(origin *Origin, resp []byte) {
+	if origin == nil {
+		for _, transport := range c.transports {
+			transport.SendResponse(resp)
+		}
+		return
+	}
+	if origin.RequestId == "" {
+		origin.Transport.SendResponse(resp)
+		return
+	}
+
+	var msg map[string]json.RawMessage
+	err := json.Unmarshal(resp, &msg)
+	if err != nil {
+		c.logger.Error().Err(err).Msgf("failed to add request id to response for %s",
+			origin.Transport.Name())
+		origin.Transport.SendResponse(resp)
+		return
+	}
+	msg["id"], err = json.Marshal(origin.RequestId)
+	if err == nil {
+		resp, err = json.Marshal(msg)
+	}
+	if err != nil {
+		c.logger.Error().Err(err).Msg("failed to marshal correlated response")
+		return
+	}
+
+	origin.Transport.SendResponse(resp)
 }
 
 func (c *Core) parseWaypoints(rq *Request) {
@@ -1,25 +1,34 @@
 package core
 
 import (
+	"context"
+	"encoding/json"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/moosethebrown/ship-net-bridge/metrics"
+	"github.com/moosethebrown/ship-net-bridge/queue"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/rs/zerolog"
 )
 
 type mockShipControl struct {
 }
 
-func (m *mockShipControl) SendRequest([]byte) {
+func (m *mockShipControl) SendRequest([]byte, *Origin) {
+}
+
+func (m *mockShipControl) EmergencyStop(*Origin) {
 }
 
 type mockShipNav struct {
 }
 
-func (m *mockShipNav) Query() {
+func (m *mockShipNav) Query(*Origin) {
 }
 
-func (m *mockShipNav) NavStart() {
+func (m *mockShipNav) NavStart(*Origin) {
 }
 
 func (m *mockShipNav) NavStop() {
@@ -28,38 +37,69 @@ func (m *mockShipNav) NavStop() {
 func (m *mockShipNav) NetLoss() {
 }
 
-func (m *mockShipNav) SetWaypoints([]*Waypoint) {
+func (m *mockShipNav) SetWaypoints([]*Waypoint, *Origin) {
 }
 
-func (m *mockShipNav) AddWaypoint(*Waypoint) {
+func (m *mockShipNav) AddWaypoint(*Waypoint, *Origin) {
 }
 
-func (m *mockShipNav) ClearWaypoints() {
+func (m *mockShipNav) ClearWaypoints(*Origin) {
 }
 
-func (m *mockShipNav) SetHomeWaypoint(*Waypoint) {
+func (m *mockShipNav) SetHomeWaypoint(*Waypoint, *Origin) {
 }
 
-func (m *mockShipNav) StartCalibration() {
+func (m *mockShipNav) StartCalibration(*Origin) {
 }
 
-func (m *mockShipNav) StopCalibration() {
+func (m *mockShipNav) StopCalibration(*Origin) {
 }
 
-type mockMqttHandler struct {
+type mockTransport struct {
+	name      string
+	responses [][]byte
 }
 
-func (m *mockMqttHandler) SendResponse([]byte) {
+func (m *mockTransport) Name() string {
+	return m.name
 }
 
-func (m *mockMqttHandler) Announce() {
+func (m *mockTransport) SendResponse(resp []byte) {
+	m.responses = append(m.responses, resp)
+}
+
+func (m *mockTransport) Announce() {
+}
+
+// blockingRecorder exports tracks once release is closed
+type blockingRecorder struct {
+	release chan struct{}
+}
+
+func (r *blockingRecorder) Record(*Telemetry) {
+}
+
+func (r *blockingRecorder) StartMission() {
+}
+
+func (r *blockingRecorder) StopMission() {
+}
+
+func (r *blockingRecorder) Export(format string, trackId int) ([]byte, error) {
+	<-r.release
+	return []byte("<gpx/>"), nil
+}
+
+func (r *blockingRecorder) ExportToFile(format string, trackId int, fileName string) (string, error) {
+	<-r.release
+	return "/tmp/" + fileName, nil
 }
 
 func setup() *Core {
 	logger := zerolog.New(os.Stdout).With().Timestamp().Logger().Level(zerolog.DebugLevel)
 
-	core := NewCore(&mockShipControl{}, &mockShipNav{},
-		&mockMqttHandler{}, 3000, &logger)
+	core := NewCore(&mockShipControl{}, &mockShipNav{}, 3000, &logger)
+	core.AddTransport(&mockTransport{name: "mock"})
 
 	return core
 }
@@ -96,3 +136,364 @@ func TestWaypointsParsing(t *testing.T) {
 			rq.waypoints[1].Longitude)
 	}
 }
+
+func TestWaypoints(t *testing.T) {
+	core := setup()
+
+	for _, rq := range []*Request{
+		{Type: RequestTypeCmd, Cmd: CmdSetWaypoints, Data: "56.348284,43.959410;56.359226,43.907618"},
+		{Type: RequestTypeCmd, Cmd: CmdAddWaypoint, Data: "56.36,43.9"},
+		{Type: RequestTypeCmd, Cmd: CmdSetHomeWaypoint, Data: "56.3,43.8"},
+	} {
+		core.parseWaypoints(rq)
+		core.handleCommand(rq)
+	}
+	waypoints := core.Waypoints()
+	if (len(waypoints) != 3) || (waypoints[2] != Waypoint{Latitude: 56.36, Longitude: 43.9}) {
+		t.Errorf("Expected set and added waypoints, got %v", waypoints)
+	}
+
+	core.handleCommand(&Request{Type: RequestTypeCmd, Cmd: CmdClearWaypoints})
+	if len(core.Waypoints()) != 0 {
+		t.Errorf("Expected waypoints to be cleared, got %v", core.Waypoints())
+	}
+}
+
+func TestResponseRouting(t *testing.T) {
+	core := setup()
+	mqtt := &mockTransport{name: "mqtt"}
+	ws := &mockTransport{name: "websocket"}
+	core.transports = []Transport{mqtt, ws}
+
+	core.routeResponse(nil, []byte(`{"type":"query"}`))
+	if len(mqtt.responses) != 1 || len(ws.responses) != 1 {
+		t.Fatalf("Expected uncorrelated response to be sent to all transports")
+	}
+
+	core.routeResponse(&Origin{Transport: ws, RequestId: "42"}, []byte(`{"type":"query"}`))
+	if len(mqtt.responses) != 1 {
+		t.Errorf("Expected correlated response not to be sent to other transports")
+	}
+	if len(ws.responses) != 2 {
+		t.Fatalf("Expected correlated response to be sent to originating transport")
+	}
+
+	var resp map[string]string
+	err := json.Unmarshal(ws.responses[1], &resp)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal correlated response: %s", err)
+	}
+	if resp["id"] != "42" {
+		t.Errorf("Expected response id to be 42, got %s", resp["id"])
+	}
+}
+
+func TestRequestLabels(t *testing.T) {
+	for _, tc := range []struct {
+		rq      *Request
+		rqType  string
+		command string
+	}{
+		{&Request{Type: RequestTypeCmd, Cmd: CmdSetSpeed}, RequestTypeCmd, CmdSetSpeed},
+		{&Request{Type: RequestTypeQuery}, RequestTypeQuery, ""},
+		{&Request{Type: RequestTypeCmd, Cmd: "rm -rf"}, RequestTypeCmd, "unknown"},
+		{&Request{Type: "x1", Cmd: "x2"}, "unknown", "unknown"},
+	} {
+		rqType, command := requestLabels(tc.rq)
+		if (rqType != tc.rqType) || (command != tc.command) {
+			t.Errorf("Expected labels %s %s for %s %s, got %s %s", tc.rqType, tc.command,
+				tc.rq.Type, tc.rq.Cmd, rqType, command)
+		}
+	}
+}
+
+func TestStaleRequests(t *testing.T) {
+	core := setup()
+	core.SetMaxAge(CommandClassControl, 2*time.Second)
+	now := time.Now()
+
+	rq := &Request{
+		Type:      RequestTypeCmd,
+		Cmd:       CmdSetSteering,
+		Timestamp: now.Add(-time.Second).UnixMilli(),
+	}
+	if reason := core.staleReason(rq, now); reason != "" {
+		t.Errorf("Expected fresh control command to be accepted, got %s", reason)
+	}
+
+	rq.Timestamp = now.Add(-20 * time.Second).UnixMilli()
+	if core.staleReason(rq, now) == "" {
+		t.Errorf("Expected old control command to be rejected")
+	}
+
+	rq.Cmd = CmdSetWaypoints
+	if reason := core.staleReason(rq, now); reason != "" {
+		t.Errorf("Expected navigation command without max age to be accepted, got %s", reason)
+	}
+
+	rq.Ttl = 5000
+	if core.staleReason(rq, now) == "" {
+		t.Errorf("Expected command older than its ttl to be rejected")
+	}
+
+	core.SetClockSkewTolerance(time.Second)
+	rq = &Request{
+		Type:      RequestTypeCmd,
+		Cmd:       CmdSetSteering,
+		Timestamp: now.Add(500 * time.Millisecond).UnixMilli(),
+	}
+	if reason := core.staleReason(rq, now); reason != "" {
+		t.Errorf("Expected command within clock skew tolerance to be accepted, got %s", reason)
+	}
+	rq.Timestamp = now.Add(time.Hour).UnixMilli()
+	if core.staleReason(rq, now) == "" {
+		t.Errorf("Expected command from the future to be rejected")
+	}
+	rq.Cmd = CmdSetWaypoints
+	if reason := core.staleReason(rq, now); reason != "" {
+		t.Errorf("Expected command from the future without age limit to be accepted, got %s", reason)
+	}
+
+	rq = &Request{
+		Type:     RequestTypeQuery,
+		deadline: now.Add(-time.Millisecond),
+	}
+	if core.staleReason(rq, now) == "" {
+		t.Errorf("Expected expired request to be rejected")
+	}
+}
+
+func TestStaleRequestErrors(t *testing.T) {
+	core := setup()
+	core.SetMaxAge(CommandClassControl, time.Second)
+	stale := metrics.Errors.WithLabelValues("core", "stale_request")
+	failed := metrics.Errors.WithLabelValues("core", "command_failed")
+	staleBefore, failedBefore := testutil.ToFloat64(stale), testutil.ToFloat64(failed)
+
+	core.handleRequest(&Request{
+		Type:      RequestTypeCmd,
+		Cmd:       CmdSetSteering,
+		Timestamp: time.Now().Add(-time.Minute).UnixMilli(),
+	})
+	if n := testutil.ToFloat64(stale) - staleBefore; n != 1 {
+		t.Errorf("Expected 1 stale request error, got %v", n)
+	}
+	if n := testutil.ToFloat64(failed) - failedBefore; n != 0 {
+		t.Errorf("Expected stale request not to be counted as failed command, got %v", n)
+	}
+}
+
+func TestExportTrack(t *testing.T) {
+	core := setup()
+	recorder := &blockingRecorder{release: make(chan struct{})}
+	core.SetTrackRecorder(recorder)
+	transport := &mockTransport{name: "mock"}
+
+	core.HandleRequest(transport, []byte(`{"id":"1","type":"cmd","cmd":"export_track","data":"gpx;0;mission.gpx"}`))
+	rq, ok := core.requests.Pop()
+	if !ok {
+		t.Fatalf("Expected export_track request to be queued")
+	}
+	handled := make(chan struct{})
+	go func() {
+		core.handleRequest(rq)
+		close(handled)
+	}()
+	select {
+	case <-handled:
+	case <-time.After(time.Second):
+		t.Fatalf("Expected export_track not to block the core while exporting")
+	}
+	if core.responses.Len() != 0 {
+		t.Fatalf("Expected no response before the export is done")
+	}
+
+	close(recorder.release)
+	select {
+	case <-core.responses.Ready():
+	case <-time.After(time.Second):
+		t.Fatalf("Expected the export result to be queued")
+	}
+	resp, _ := core.responses.Pop()
+	if resp.origin.RequestId != "1" {
+		t.Errorf("Expected response to request 1, got %s", resp.origin.RequestId)
+	}
+	var r Response
+	err := json.Unmarshal(resp.data, &r)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal response: %s", err)
+	}
+	if (r.Type != ResponseTypeTrack) || (r.Data != "/tmp/mission.gpx") {
+		t.Errorf("Expected exported file path, got %+v", r)
+	}
+}
+
+func TestClockSkew(t *testing.T) {
+	core := setup()
+	core.SetClockSkewTolerance(time.Second)
+
+	rq := &Request{
+		Timestamp: time.Now().Add(10 * time.Second).UnixMilli(),
+		received:  time.Now(),
+	}
+	core.checkClockSkew(rq)
+
+	status := core.Status().(*Status)
+	if status.ClockSkew == nil {
+		t.Fatalf("Expected clock skew to be reported")
+	}
+	if status.ClockSkew.Skew < 9000 {
+		t.Errorf("Expected clock skew of about 10s, got %dms", status.ClockSkew.Skew)
+	}
+}
+
+func TestRequestPriority(t *testing.T) {
+	core := setup()
+	transport := &mockTransport{name: "mock"}
+
+	core.HandleRequest(transport, []byte(`{"type":"query"}`))
+	core.HandleRequest(transport, []byte(`{"type":"cmd","cmd":"set_waypoints","data":"56.3,43.9"}`))
+	core.HandleRequest(transport, []byte(`{"id":"1","type":"cmd","cmd":"set_steering","data":"10"}`))
+	core.HandleRequest(transport, []byte(`{"id":"2","type":"cmd","cmd":"set_steering","data":"20"}`))
+	core.HandleRequest(transport, []byte(`{"type":"cmd","cmd":"emergency_stop"}`))
+
+	expected := []string{CmdEmergencyStop, CmdSetSteering, CmdSetWaypoints, ""}
+	for _, cmd := range expected {
+		rq, ok := core.requests.Pop()
+		if !ok {
+			t.Fatalf("Expected request %s, queue is empty", cmd)
+		}
+		if rq.Cmd != cmd {
+			t.Errorf("Expected request %s, got %s", cmd, rq.Cmd)
+		}
+		if (rq.Cmd == CmdSetSteering) && (rq.Data != "20") {
+			t.Errorf("Expected the latest steering value, got %s", rq.Data)
+		}
+	}
+
+	if core.responses.Len() != 1 {
+		t.Fatalf("Expected error response for superseded command, got %d responses",
+			core.responses.Len())
+	}
+	resp, _ := core.responses.Pop()
+	if resp.origin.RequestId != "1" {
+		t.Errorf("Expected error response for request 1, got %s", resp.origin.RequestId)
+	}
+}
+
+func TestRequestOverflow(t *testing.T) {
+	core := setup()
+	transport := &mockTransport{name: "mock"}
+	core.SetQueueSize(CommandClassQuery, 1)
+
+	core.HandleRequest(transport, []byte(`{"id":"1","type":"query"}`))
+	core.HandleRequest(transport, []byte(`{"id":"2","type":"query"}`))
+	if core.requests.Len() != 1 {
+		t.Fatalf("Expected 1 queued request, got %d", core.requests.Len())
+	}
+	resp, ok := core.responses.Pop()
+	if !ok || (resp.origin.RequestId != "2") {
+		t.Fatalf("Expected rejected request 2 to get an error response")
+	}
+
+	core.SetOverflowPolicy(queue.DropOldest)
+	core.HandleRequest(transport, []byte(`{"id":"3","type":"query"}`))
+	resp, ok = core.responses.Pop()
+	if !ok || (resp.origin.RequestId != "1") {
+		t.Fatalf("Expected dropped request 1 to get an error response")
+	}
+	rq, _ := core.requests.Pop()
+	if rq.Id != "3" {
+		t.Errorf("Expected request 3 to be queued, got %s", rq.Id)
+	}
+}
+
+func TestSafetyOverflow(t *testing.T) {
+	core := setup()
+	transport := &mockTransport{name: "mock"}
+	core.SetQueueSize(CommandClassSafety, 1)
+	core.SetOverflowPolicy(queue.DropNewest)
+
+	core.HandleRequest(transport, []byte(`{"id":"1","type":"query"}`))
+	core.HandleRequest(transport, []byte(`{"id":"2","type":"cmd","cmd":"nav_stop"}`))
+	core.HandleRequest(transport, []byte(`{"id":"3","type":"cmd","cmd":"emergency_stop"}`))
+
+	// the query makes room for the emergency stop
+	resp, ok := core.responses.Pop()
+	if !ok || (resp.origin.RequestId != "1") {
+		t.Fatalf("Expected dropped query to get an error response")
+	}
+	for _, id := range []string{"2", "3"} {
+		rq, ok := core.requests.Pop()
+		if !ok || (rq.Id != id) {
+			t.Fatalf("Expected safety request %s to be queued", id)
+		}
+	}
+
+	// safety commands are queued even with nothing to drop
+	core.HandleRequest(transport, []byte(`{"id":"4","type":"cmd","cmd":"nav_stop"}`))
+	core.HandleRequest(transport, []byte(`{"id":"5","type":"cmd","cmd":"emergency_stop"}`))
+	if core.requests.Len() != 2 {
+		t.Errorf("Expected 2 queued safety requests, got %d", core.requests.Len())
+	}
+
+	// so is the collision stop of the AIS adapter
+	core.NavStop("collision risk")
+	if core.requests.Len() != 3 {
+		t.Errorf("Expected the collision stop to be queued, got %d requests", core.requests.Len())
+	}
+}
+
+func TestFix(t *testing.T) {
+	core := setup()
+	mqtt := &mockTransport{name: "mqtt"}
+	core.transports = []Transport{mqtt}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan bool)
+	go func() {
+		core.Run(ctx)
+		close(done)
+	}()
+
+	heading := 87.5
+	core.HandleFix(&Fix{Latitude: 48.1173, Longitude: 11.5167, Heading: &heading})
+	deadline := time.Now().Add(time.Second)
+	for (core.LastFix() == nil) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if core.LastFix() == nil {
+		t.Fatalf("Expected the fix to be kept")
+	}
+	if len(mqtt.responses) != 1 {
+		t.Fatalf("Expected the fix to be published, got %d responses", len(mqtt.responses))
+	}
+	var resp Response
+	err := json.Unmarshal(mqtt.responses[0], &resp)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal fix: %s", err)
+	}
+	if (resp.Type != ResponseTypeFix) || (resp.Fix == nil) || (resp.Fix.Latitude != 48.1173) ||
+		(resp.Fix.Heading == nil) || (*resp.Fix.Heading != heading) {
+		t.Errorf("Unexpected fix response %s", mqtt.responses[0])
+	}
+}
+
+func TestNavStop(t *testing.T) {
+	core := setup()
+	transport := &mockTransport{name: "mock"}
+
+	core.HandleRequest(transport, []byte(`{"type":"cmd","cmd":"set_steering","data":"10"}`))
+	core.NavStop("collision risk")
+
+	rq, ok := core.requests.Pop()
+	if !ok || (rq.Cmd != CmdNavStop) || (rq.origin != nil) {
+		t.Fatalf("Expected nav_stop of the bridge ahead of control commands, got %+v", rq)
+	}
+	if reason := core.staleReason(rq, time.Now()); reason != "" {
+		t.Errorf("Expected nav_stop of the bridge to be fresh, got %s", reason)
+	}
+}
@@ -1,5 +1,7 @@
 package core
 
+import "time"
+
 const (
 	RequestTypeCmd   = "cmd"
 	RequestTypeQuery = "query"
@@ -17,9 +19,40 @@ const (
 	CmdClearWaypoints   = "clear_waypoints"
 	CmdSetHomeWaypoint  = "set_home_waypoint"
 	CmdNavStart         = "nav_start"
+	CmdNavStop          = "nav_stop"
 	CmdNetLoss          = "net_loss"
 	CmdStartCalibration = "start_calibration"
 	CmdStopCalibration  = "stop_calibration"
+	CmdExportTrack      = "export_track"
+	CmdEmergencyStop    = "emergency_stop"
+)
+
+// Commands lists all commands the core knows
+var Commands = []string{
+	CmdSpeedUp, CmdSpeedDown, CmdTurnLeft, CmdTurnRight, CmdSetSpeed, CmdSetSteering,
+	CmdSetWaypoints, CmdAddWaypoint, CmdClearWaypoints, CmdSetHomeWaypoint,
+	CmdNavStart, CmdNavStop, CmdNetLoss, CmdStartCalibration, CmdStopCalibration,
+	CmdExportTrack, CmdEmergencyStop,
+}
+
+// Command classes, see CommandClass
+const (
+	CommandClassSafety     = "safety"
+	CommandClassControl    = "control"
+	CommandClassNavigation = "navigation"
+	CommandClassQuery      = "query"
+)
+
+const (
+	ResponseTypeTrack    = "track"
+	ResponseTypeError    = "error"
+	ResponseTypeAnnounce = "announce"
+	ResponseTypeFix      = "fix"
+	// ResponseTypeAisTargets lists vessels around, ResponseTypeCollisionWarning
+	// reports a vessel on collision course, with cmd nav_stop when the bridge
+	// stopped autonav because of it
+	ResponseTypeAisTargets       = "ais_targets"
+	ResponseTypeCollisionWarning = "collision_warning"
 )
 
 type Waypoint struct {
@@ -28,9 +61,107 @@ type Waypoint struct {
 }
 
 type Request struct {
-	Type      string `json:"type"`
-	Cmd       string `json:"cmd"`
-	Data      string `json:"data"`
+	Id   string `json:"id,omitempty"`
+	Type string `json:"type"`
+	Cmd  string `json:"cmd"`
+	Data string `json:"data"`
+	// Timestamp is the time the request was sent, unix milliseconds
+	Timestamp int64 `json:"timestamp,omitempty"`
+	// Ttl is the maximum request age in milliseconds, counted from Timestamp
+	Ttl       int64 `json:"ttl,omitempty"`
 	rawData   []byte
 	waypoints []*Waypoint
+	origin    *Origin
+	operator  string
+	received  time.Time
+	deadline  time.Time
+}
+
+// RequestMeta holds request attributes transports receive
+// outside of the request message
+type RequestMeta struct {
+	// Operator identifies the person or system issuing the request
+	Operator string
+	// Correlated requests get responses only through their transport
+	// even without request id
+	Correlated bool
+	// Deadline after which the request must not be executed, zero means none
+	Deadline time.Time
+}
+
+// Response is a message generated by the bridge itself rather than
+// forwarded from one of the daemons
+type Response struct {
+	Type  string `json:"type"`
+	Cmd   string `json:"cmd,omitempty"`
+	Data  string `json:"data,omitempty"`
+	Error string `json:"error,omitempty"`
+	Fix   *Fix   `json:"fix,omitempty"`
+	// Targets of ResponseTypeAisTargets, Target of ResponseTypeCollisionWarning
+	Targets []*AisTarget `json:"targets,omitempty"`
+	Target  *AisTarget   `json:"target,omitempty"`
+}
+
+// Telemetry is the vessel state reported by ship-nav in query responses
+type Telemetry struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Heading   float64 `json:"heading"`
+	Speed     float64 `json:"speed"`
+	// Battery is missing unless ship-nav reports it
+	Battery   *Battery  `json:"battery,omitempty"`
+	Timestamp time.Time `json:"-"`
+}
+
+// Battery state as reported by ship-nav: voltage in volts, current
+// in amperes (positive when discharging), charge in percent; fields
+// ship-nav doesn't report are nil
+type Battery struct {
+	Voltage *float64 `json:"voltage,omitempty"`
+	Current *float64 `json:"current,omitempty"`
+	Charge  *float64 `json:"charge,omitempty"`
+}
+
+// Fix is the position reported by an NMEA 0183 receiver,
+// a source independent of ship-nav
+type Fix struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	// Quality is GGA fix quality, e.g. 1 for GPS, 2 for DGPS, 4 for RTK,
+	// zero when only RMC is received
+	Quality    int     `json:"quality,omitempty"`
+	Satellites int     `json:"satellites,omitempty"`
+	Hdop       float64 `json:"hdop,omitempty"`
+	// Altitude above mean sea level, meters
+	Altitude float64 `json:"altitude,omitempty"`
+	// Course over ground and Heading are true, degrees; Speed over ground
+	// is in knots; nil when not reported recently
+	Course  *float64 `json:"course,omitempty"`
+	Speed   *float64 `json:"speed,omitempty"`
+	Heading *float64 `json:"heading,omitempty"`
+	// Timestamp is the time the position was received
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// AisTarget is a vessel reported over AIS; Distance, Cpa (closest point of
+// approach, meters) and Tcpa (time to it, seconds, negative when the vessels
+// move apart) are relative to the position from ship-nav telemetry
+// and missing while it isn't known
+type AisTarget struct {
+	Mmsi      uint32  `json:"mmsi"`
+	Name      string  `json:"name,omitempty"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	// Course over ground and Heading are true, degrees; Speed over ground
+	// is in knots; nil when not available
+	Course   *float64 `json:"course,omitempty"`
+	Speed    *float64 `json:"speed,omitempty"`
+	Heading  *float64 `json:"heading,omitempty"`
+	Distance *float64 `json:"distance,omitempty"`
+	Cpa      *float64 `json:"cpa,omitempty"`
+	Tcpa     *float64 `json:"tcpa,omitempty"`
+	// Warning is set while the target is on collision course
+	Warning bool `json:"warning,omitempty"`
+	// Timestamp is the time of the latest position report
+	Timestamp time.Time `json:"timestamp"`
 }
@@ -0,0 +1,156 @@
+package core
+
+import (
+	"encoding/json"
+
+	"github.com/moosethebrown/ship-net-bridge/metrics"
+	"github.com/moosethebrown/ship-net-bridge/queue"
+)
+
+// DefaultQueueSize is the default number of queued requests per command class
+const DefaultQueueSize = 250
+
+// CommandClasses lists command classes from the highest priority to the lowest,
+// queued requests of a class are executed before any of the following ones
+var CommandClasses = []string{
+	CommandClassSafety,
+	CommandClassControl,
+	CommandClassNavigation,
+	CommandClassQuery,
+}
+
+// ClassPriority returns index of class in CommandClasses,
+// unknown classes get the lowest priority
+func ClassPriority(class string) int {
+	for i, c := range CommandClasses {
+		if c == class {
+			return i
+		}
+	}
+	return len(CommandClasses) - 1
+}
+
+// Supersedes tells whether rq makes queued request obsolete: a newer absolute
+// control value replaces a queued one of the same command, so that only
+// the latest of consecutive set_steering values is executed
+func Supersedes(queued *Request, rq *Request) bool {
+	if (queued.Type != RequestTypeCmd) || (rq.Type != RequestTypeCmd) {
+		return false
+	}
+	if queued.Cmd != rq.Cmd {
+		return false
+	}
+	return (rq.Cmd == CmdSetSteering) || (rq.Cmd == CmdSetSpeed)
+}
+
+// NewRequestQueue creates a queue with one priority per command class,
+// each of them bounded by size, 0 means DefaultQueueSize
+func NewRequestQueue[T any](size int, supersede func(queued T, item T) bool) *queue.Priority[T] {
+	if size <= 0 {
+		size = DefaultQueueSize
+	}
+	limits := make([]int, len(CommandClasses))
+	for i := range limits {
+		limits[i] = size
+	}
+	return queue.NewPriority(limits, supersede)
+}
+
+// OverflowExceeded is the policy label of overflow metrics for safety
+// commands queued beyond the limit
+const OverflowExceeded = "exceed"
+
+// PushRequest adds item of the given command class to a request queue,
+// safety commands are never rejected or dropped by the overflow policy:
+// when there is no room for them a queued item of a lower class is dropped,
+// or if there is none the limit is exceeded
+func PushRequest[T any](q *queue.Priority[T], class string, item T) queue.Result[T] {
+	if class == CommandClassSafety {
+		return q.PushAlways(ClassPriority(class), item)
+	}
+	return q.Push(ClassPriority(class), item)
+}
+
+// ClassDepths maps queue lengths of a request queue to command classes
+func ClassDepths(lens []int) map[string]int {
+	depths := make(map[string]int)
+	for i, n := range lens {
+		depths[CommandClasses[i]] = n
+	}
+	return depths
+}
+
+// SetQueueSize sets the maximum number of queued requests of the given
+// command class. Can be called any time.
+func (c *Core) SetQueueSize(class string, size int) {
+	if size <= 0 {
+		size = DefaultQueueSize
+	}
+	c.requests.SetLimit(ClassPriority(class), size)
+}
+
+// HandleError sends an error response for a command that won't be executed,
+// can be called from any goroutine
+func (c *Core) HandleError(origin *Origin, cmd string, msg string) {
+	data, err := json.Marshal(&Response{
+		Type:  ResponseTypeError,
+		Cmd:   cmd,
+		Error: msg,
+	})
+	if err != nil {
+		c.logger.Error().Err(err).Msg("failed to marshal error response")
+		return
+	}
+	c.HandleResponse(origin, data)
+}
+
+// SetOverflowPolicy sets what happens when request or response queue is full,
+// with Reject senders of requests get an error response. Can be called any time.
+func (c *Core) SetOverflowPolicy(policy queue.Policy) {
+	c.requests.SetPolicy(policy)
+	c.responses.SetPolicy(policy)
+}
+
+// newLatestQueue is used for telemetry and fixes, only the latest ones matter
+func newLatestQueue[T any](limit int) *queue.Priority[T] {
+	q := queue.NewQueue[T](limit)
+	q.SetPolicy(queue.DropOldest)
+	return q
+}
+
+// enqueue adds request to the queue of its command class, it never blocks
+func (c *Core) enqueue(rq *Request) {
+	class := CommandClass(rq)
+	result := PushRequest(c.requests, class, rq)
+	if result.Superseded {
+		c.logger.Debug().Msgf("%s superseded by a newer one", result.Item.Cmd)
+		metrics.Superseded.WithLabelValues("core").Inc()
+		if result.Item.origin != nil {
+			c.HandleError(result.Item.origin, result.Item.Cmd, "superseded by a newer command")
+		}
+		return
+	}
+
+	if result.Exceeded {
+		c.logger.Warn().Msgf("%s request queue is full, queuing %s beyond the limit", class, rq.Cmd)
+		metrics.Overflows.WithLabelValues("core", "requests_"+class, OverflowExceeded).Inc()
+		return
+	}
+	if result.Queued && !result.Dropped {
+		return
+	}
+	policy := c.requests.Policy()
+	metrics.Overflows.WithLabelValues("core", "requests_"+class, policy.String()).Inc()
+	if result.Dropped {
+		c.logger.Warn().Msgf("%s request queue is full, dropping the oldest request %s",
+			CommandClass(result.Item), result.Item.Cmd)
+		if result.Item.origin != nil {
+			c.HandleError(result.Item.origin, result.Item.Cmd, "dropped, request queue is full")
+		}
+		return
+	}
+	c.logger.Error().Msgf("%s request queue is full, dropping %s %s", class, rq.Type, rq.Cmd)
+	if (policy == queue.Reject) && (rq.origin != nil) {
+		c.HandleError(rq.origin, rq.Cmd, "request queue is full")
+	}
+}
@@ -0,0 +1,113 @@
+package core
+
+import (
+	"fmt"
+	"time"
+)
+
+type ClockSkew struct {
+	// Skew is how far ahead of the bridge the client clock was, milliseconds
+	Skew     int64     `json:"skew"`
+	Detected time.Time `json:"detected"`
+}
+
+// SetMaxAge sets the maximum age of requests of the given command class,
+// requests with a timestamp older than that are rejected; 0 disables the check.
+// Can be called any time.
+func (c *Core) SetMaxAge(class string, maxAge time.Duration) {
+	c.limitsMutex.Lock()
+	defer c.limitsMutex.Unlock()
+
+	c.maxAge[class] = maxAge
+}
+
+// SetClockSkewTolerance sets how far in the future request timestamps may be
+// before client clock skew is reported; requests with an age limit are
+// rejected beyond that, their age can't be told. Can be called any time.
+func (c *Core) SetClockSkewTolerance(tolerance time.Duration) {
+	c.limitsMutex.Lock()
+	defer c.limitsMutex.Unlock()
+
+	c.skewTolerance = tolerance
+}
+
+// CommandClass returns class of a request, command classes share
+// staleness limits and queue priority
+func CommandClass(rq *Request) string {
+	if rq.Type != RequestTypeCmd {
+		return CommandClassQuery
+	}
+
+	switch rq.Cmd {
+	case CmdEmergencyStop, CmdNavStop, CmdNetLoss:
+		return CommandClassSafety
+	case CmdSpeedUp, CmdSpeedDown, CmdTurnLeft, CmdTurnRight, CmdSetSpeed, CmdSetSteering:
+		return CommandClassControl
+	case CmdExportTrack:
+		return CommandClassQuery
+	default:
+		return CommandClassNavigation
+	}
+}
+
+// staleReason returns why the request is too old to be executed,
+// empty string means it's not
+func (c *Core) staleReason(rq *Request, now time.Time) string {
+	if !rq.deadline.IsZero() && now.After(rq.deadline) {
+		return "request expired"
+	}
+	if rq.Timestamp == 0 {
+		return ""
+	}
+
+	c.limitsMutex.RLock()
+	maxAge := c.maxAge[CommandClass(rq)]
+	tolerance := c.skewTolerance
+	c.limitsMutex.RUnlock()
+
+	age := now.Sub(time.UnixMilli(rq.Timestamp))
+	if (age < -tolerance) && ((rq.Ttl > 0) || (maxAge > 0)) {
+		// a negative age would pass any limit
+		return fmt.Sprintf("request timestamp is %s in the future, clock skew tolerance is %s",
+			(-age).Round(time.Millisecond), tolerance)
+	}
+	if rq.Ttl > 0 {
+		ttl := time.Duration(rq.Ttl) * time.Millisecond
+		if age > ttl {
+			return fmt.Sprintf("request is %s old, ttl is %s", age.Round(time.Millisecond), ttl)
+		}
+	}
+
+	if (maxAge > 0) && (age > maxAge) {
+		return fmt.Sprintf("request is %s old, maximum age of %s commands is %s",
+			age.Round(time.Millisecond), CommandClass(rq), maxAge)
+	}
+
+	return ""
+}
+
+// checkClockSkew reports timestamps from the future, network delays can only
+// make timestamps older so those are caused by client clocks running ahead
+func (c *Core) checkClockSkew(rq *Request) {
+	if rq.Timestamp == 0 {
+		return
+	}
+
+	skew := time.UnixMilli(rq.Timestamp).Sub(rq.received)
+	c.limitsMutex.RLock()
+	tolerance := c.skewTolerance
+	c.limitsMutex.RUnlock()
+	if skew <= tolerance {
+		return
+	}
+
+	c.logger.Warn().Msgf("request timestamp is %s ahead of local clock, check clock synchronization",
+		skew.Round(time.Millisecond))
+
+	c.statusMutex.Lock()
+	defer c.statusMutex.Unlock()
+	c.clockSkew = &ClockSkew{
+		Skew:     skew.Milliseconds(),
+		Detected: rq.received,
+	}
+}
@@ -1,32 +1,101 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
 	"os/signal"
+	"syscall"
 
 	"github.com/moosethebrown/ship-net-bridge/config"
 )
 
 func main() {
+	if (len(os.Args) > 2) && (os.Args[1] == "config") && (os.Args[2] == "convert") {
+		os.Exit(convertConfig(os.Args[3:]))
+	}
+
 	var configFile string
+	var configFormat string
+	var checkConfig bool
 	flag.StringVar(&configFile, "c", "/etc/ship-net-bridge.conf", "path to configuration file")
+	flag.StringVar(&configFormat, "config-format", "", "configuration file format: json, yaml or toml, by file extension if not set")
+	flag.BoolVar(&checkConfig, "check-config", false, "validate configuration, print it with defaults applied and exit")
+	// flags for common settings take precedence over SNB_ environment
+	// variables and the configuration file
+	settingFlags := map[string]string{
+		"ship-id":            "shipId",
+		"log-level":          "logLevel",
+		"mqtt-broker":        "mqtt.broker",
+		"mqtt-username":      "mqtt.username",
+		"mqtt-password-file": "mqtt.passwordFile",
+		"ship-control":       "shipControl.socketName",
+		"ship-nav":           "shipNav.socketName",
+		"websocket-address":  "webSocket.address",
+		"admin-address":      "admin.address",
+	}
+	for name, path := range settingFlags {
+		flag.String(name, "", "overrides "+path)
+	}
 	flag.Parse()
 
-	cfg, err := config.NewConfig(configFile)
+	overrides := make(config.Overrides)
+	flag.Visit(func(f *flag.Flag) {
+		if path, ok := settingFlags[f.Name]; ok {
+			overrides[path] = f.Value.String()
+		}
+	})
+
+	var format config.Format
+	if configFormat != "" {
+		var err error
+		format, err = config.ParseFormat(configFormat)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	cfg, err := config.NewConfig(configFile, format, overrides)
 	if err != nil {
-		fmt.Printf("Error reading config: %s\n", err)
+		fmt.Printf("Error reading config:\n%s\n", err)
+		os.Exit(1)
+	}
+
+	if checkConfig {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "    ")
+		err = encoder.Encode(cfg.Redacted())
+		if err != nil {
+			fmt.Printf("Error printing config: %s\n", err)
+			os.Exit(1)
+		}
 		return
 	}
 
-	app := NewApp(cfg)
+	app := NewApp(cfg, configFile, format, overrides)
 
 	sigch := make(chan os.Signal, 1)
-	signal.Notify(sigch, os.Interrupt)
+	signal.Notify(sigch, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
 
 	app.Start()
 
-	<-sigch
-	app.Stop()
+	for {
+		select {
+		case sig := <-sigch:
+			if sig == syscall.SIGHUP {
+				app.Reload()
+				continue
+			}
+			fmt.Printf("Received %s, shutting down\n", sig)
+			app.Stop()
+			return
+		case err := <-app.Fatal():
+			fmt.Printf("Fatal error: %s\n", err)
+			app.Stop()
+			os.Exit(1)
+		}
+	}
 }
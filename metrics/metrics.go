@@ -0,0 +1,162 @@
+package metrics
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "snb"
+
+// Registry holds all bridge metrics, it is separate from the default
+// prometheus registry so that only bridge metrics are exposed
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+var (
+	Requests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "requests_total",
+		Help:      "Requests received by core, by request type and command.",
+	}, []string{"type", "cmd"})
+
+	Errors = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "errors_total",
+		Help:      "Errors by component and reason.",
+	}, []string{"component", "reason"})
+
+	DaemonRoundTrip = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "daemon_round_trip_seconds",
+		Help:      "Time between sending a message to a daemon and receiving its response.",
+		Buckets:   []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1},
+	}, []string{"daemon"})
+
+	Announces = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "announces_total",
+		Help:      "Announce messages published, by result.",
+	}, []string{"result"})
+
+	Reconnects = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reconnects_total",
+		Help:      "Connections re-established after being lost, by component.",
+	}, []string{"component"})
+
+	Restarts = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "restarts_total",
+		Help:      "Components restarted by the supervisor after a failure, by component.",
+	}, []string{"component"})
+
+	MqttBroker = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "mqtt_broker_connected",
+		Help:      "Set to 1 for the MQTT broker the bridge is connected to.",
+	}, []string{"broker"})
+
+	WebSocketClients = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "websocket_clients",
+		Help:      "Currently connected WebSocket clients.",
+	})
+
+	Overflows = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "queue_overflows_total",
+		Help:      "Items pushed to full queues, by component, queue and overflow policy, exceed for safety commands kept beyond the limit.",
+	}, []string{"component", "queue", "policy"})
+
+	Superseded = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "superseded_commands_total",
+		Help:      "Queued commands replaced by a newer command of the same kind, by component.",
+	}, []string{"component"})
+
+	MqttMessages = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "mqtt_messages_total",
+		Help:      "MQTT messages, by direction.",
+	}, []string{"direction"})
+
+	NmeaSentences = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "nmea_sentences_total",
+		Help:      "Valid NMEA 0183 sentences received, by sentence type, other for unsupported types.",
+	}, []string{"type"})
+
+	CollisionWarnings = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "collision_warnings_total",
+		Help:      "AIS targets that came under collision warning thresholds.",
+	})
+)
+
+// QueueReporter is implemented by components with internal queues
+type QueueReporter interface {
+	QueueDepths() map[string]int
+}
+
+type queueSource struct {
+	component string
+	reporter  QueueReporter
+}
+
+// queueCollector reports queue depths at scrape time
+type queueCollector struct {
+	desc    *prometheus.Desc
+	sources []*queueSource
+	mutex   sync.Mutex
+}
+
+var queues = &queueCollector{
+	desc: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "queue_depth"),
+		"Number of messages waiting in a queue.",
+		[]string{"component", "queue"}, nil),
+	sources: make([]*queueSource, 0),
+}
+
+func init() {
+	Registry.MustRegister(queues)
+	Registry.MustRegister(collectors.NewGoCollector())
+	Registry.MustRegister(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+}
+
+// RegisterQueues adds component queues to snb_queue_depth metric
+func RegisterQueues(component string, reporter QueueReporter) {
+	queues.mutex.Lock()
+	defer queues.mutex.Unlock()
+
+	queues.sources = append(queues.sources, &queueSource{
+		component: component,
+		reporter:  reporter,
+	})
+}
+
+// Handler serves metrics in Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+func (c *queueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *queueCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, source := range c.sources {
+		for queue, depth := range source.reporter.QueueDepths() {
+			ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue,
+				float64(depth), source.component, queue)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package queue
+
+import "fmt"
+
+// Policy tells what happens when an item is pushed to a full queue
+type Policy int
+
+const (
+	// Reject keeps the queue as is, the producer reports an error
+	// to whoever sent the item
+	Reject Policy = iota
+	// DropNewest silently drops the pushed item
+	DropNewest
+	// DropOldest makes room for the pushed item by removing
+	// the oldest item of the same priority
+	DropOldest
+)
+
+var policyNames = map[Policy]string{
+	Reject:     "reject",
+	DropNewest: "drop_newest",
+	DropOldest: "drop_oldest",
+}
+
+func (p Policy) String() string {
+	return policyNames[p]
+}
+
+// ParsePolicy converts policy name to Policy, empty name means Reject
+func ParsePolicy(name string) (Policy, error) {
+	if name == "" {
+		return Reject, nil
+	}
+	for p, n := range policyNames {
+		if n == name {
+			return p, nil
+		}
+	}
+	return Reject, fmt.Errorf("unknown overflow policy: %s", name)
+}
@@ -0,0 +1,208 @@
+package queue
+
+import "sync"
+
+// Priority is a set of bounded FIFO queues, items are popped from the
+// highest priority (lowest index) non-empty queue first.
+// It's safe for concurrent use.
+type Priority[T any] struct {
+	queues    [][]T
+	limits    []int
+	supersede func(queued T, item T) bool
+	policy    Policy
+	ready     chan struct{}
+	mutex     sync.Mutex
+}
+
+// NewPriority creates a queue with len(limits) priorities, limits[i] is the
+// maximum number of items of priority i. supersede, if not nil, tells
+// whether item makes the last queued item of the same priority obsolete,
+// in which case the queued item is replaced.
+func NewPriority[T any](limits []int, supersede func(queued T, item T) bool) *Priority[T] {
+	return &Priority[T]{
+		queues:    make([][]T, len(limits)),
+		limits:    limits,
+		supersede: supersede,
+		ready:     make(chan struct{}, 1),
+	}
+}
+
+// Result describes what Push did
+type Result[T any] struct {
+	// Queued is false if the pushed item was not added because the queue is full
+	Queued bool
+	// Superseded is true if Item was replaced by the pushed item
+	Superseded bool
+	// Dropped is true if Item was removed to make room for the pushed item
+	Dropped bool
+	// Exceeded is true if PushAlways queued the item beyond the limit
+	Exceeded bool
+	// Item is the superseded or dropped item
+	Item T
+}
+
+// NewQueue creates a bounded FIFO queue, it's a Priority with a single priority 0
+func NewQueue[T any](limit int) *Priority[T] {
+	return NewPriority[T]([]int{limit}, nil)
+}
+
+// Push adds item to the queue of the given priority, when the queue is full
+// the overflow policy applies
+func (q *Priority[T]) Push(priority int, item T) Result[T] {
+	return q.push(priority, item, false)
+}
+
+// PushAlways adds item to the queue of the given priority regardless of its
+// limit and the overflow policy, e.g. for items that must never be lost;
+// when the queue is full the oldest item of the lowest priority below it
+// is dropped instead. With no such item the limit is exceeded, callers
+// should report Result.Exceeded since only the consumer bounds the queue then.
+func (q *Priority[T]) PushAlways(priority int, item T) Result[T] {
+	return q.push(priority, item, true)
+}
+
+func (q *Priority[T]) push(priority int, item T, always bool) Result[T] {
+	var result Result[T]
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	queue := q.queues[priority]
+	if (len(queue) > 0) && (q.supersede != nil) && q.supersede(queue[len(queue)-1], item) {
+		result.Queued = true
+		result.Superseded = true
+		result.Item = queue[len(queue)-1]
+		queue[len(queue)-1] = item
+		return result
+	}
+
+	if always && (len(queue) >= q.limits[priority]) {
+		for p := len(q.queues) - 1; p > priority; p-- {
+			if len(q.queues[p]) > 0 {
+				result.Dropped = true
+				result.Item = q.queues[p][0]
+				var zero T
+				q.queues[p][0] = zero
+				q.queues[p] = q.queues[p][1:]
+				break
+			}
+		}
+		result.Exceeded = !result.Dropped
+	} else if len(queue) >= q.limits[priority] {
+		if (q.policy != DropOldest) || (len(queue) == 0) {
+			return result
+		}
+		result.Dropped = true
+		result.Item = queue[0]
+		var zero T
+		queue[0] = zero
+		queue = queue[1:]
+	}
+
+	q.queues[priority] = append(queue, item)
+	q.signal()
+	result.Queued = true
+
+	return result
+}
+
+// Pop removes and returns the highest priority item, false means the queue is empty
+func (q *Priority[T]) Pop() (T, bool) {
+	var item T
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for p, queue := range q.queues {
+		if len(queue) == 0 {
+			continue
+		}
+
+		item = queue[0]
+		var zero T
+		queue[0] = zero
+		q.queues[p] = queue[1:]
+		if q.len() > 0 {
+			q.signal()
+		}
+		return item, true
+	}
+
+	return item, false
+}
+
+// Ready receives a value when the queue may have items to pop, consumers
+// pop one item per received value so that they can serve other channels
+// in between
+func (q *Priority[T]) Ready() <-chan struct{} {
+	return q.ready
+}
+
+func (q *Priority[T]) Len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return q.len()
+}
+
+// Lens returns the number of queued items per priority
+func (q *Priority[T]) Lens() []int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	lens := make([]int, len(q.queues))
+	for p, queue := range q.queues {
+		lens[p] = len(queue)
+	}
+	return lens
+}
+
+func (q *Priority[T]) len() int {
+	n := 0
+	for _, queue := range q.queues {
+		n += len(queue)
+	}
+	return n
+}
+
+func (q *Priority[T]) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// SetPolicy sets what happens when an item is pushed to a full queue,
+// Reject by default
+func (q *Priority[T]) SetPolicy(policy Policy) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.policy = policy
+}
+
+func (q *Priority[T]) Policy() Policy {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return q.policy
+}
+
+// SetLimit changes the maximum number of items of the given priority,
+// items already queued above the new limit are kept
+func (q *Priority[T]) SetLimit(priority int, limit int) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.limits[priority] = limit
+}
+
+// Drain removes and returns all items of the given priority
+func (q *Priority[T]) Drain(priority int) []T {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	items := q.queues[priority]
+	q.queues[priority] = nil
+	return items
+}
@@ -0,0 +1,162 @@
+package queue
+
+import "testing"
+
+type item struct {
+	kind  string
+	value int
+}
+
+func TestPriorityOrder(t *testing.T) {
+	q := NewPriority[*item]([]int{10, 10, 10}, nil)
+
+	q.Push(2, &item{value: 1})
+	q.Push(1, &item{value: 2})
+	q.Push(0, &item{value: 3})
+	q.Push(2, &item{value: 4})
+
+	expected := []int{3, 2, 1, 4}
+	for _, value := range expected {
+		select {
+		case <-q.Ready():
+		default:
+			t.Fatalf("Expected queue to be ready")
+		}
+		it, ok := q.Pop()
+		if !ok {
+			t.Fatalf("Expected item %d, queue is empty", value)
+		}
+		if it.value != value {
+			t.Errorf("Expected item %d, got %d", value, it.value)
+		}
+	}
+
+	if _, ok := q.Pop(); ok {
+		t.Errorf("Expected queue to be empty")
+	}
+}
+
+func TestPriorityLimits(t *testing.T) {
+	q := NewPriority[*item]([]int{1, 2}, nil)
+
+	if !q.Push(0, &item{}).Queued {
+		t.Errorf("Expected push to succeed")
+	}
+	if q.Push(0, &item{}).Queued {
+		t.Errorf("Expected push to a full queue to fail")
+	}
+	if !q.Push(1, &item{}).Queued {
+		t.Errorf("Expected push to another priority to succeed")
+	}
+}
+
+func TestPrioritySupersede(t *testing.T) {
+	q := NewPriority[*item]([]int{10}, func(queued *item, it *item) bool {
+		return queued.kind == it.kind
+	})
+
+	q.Push(0, &item{kind: "steering", value: 1})
+	result := q.Push(0, &item{kind: "steering", value: 2})
+	if !result.Superseded || (result.Item.value != 1) {
+		t.Errorf("Expected first steering command to be superseded")
+	}
+	result = q.Push(0, &item{kind: "speed", value: 3})
+	if result.Superseded {
+		t.Errorf("Expected speed command not to supersede steering")
+	}
+	q.Push(0, &item{kind: "steering", value: 4})
+
+	if q.Len() != 3 {
+		t.Fatalf("Expected 3 items, got %d", q.Len())
+	}
+	for _, value := range []int{2, 3, 4} {
+		it, _ := q.Pop()
+		if it.value != value {
+			t.Errorf("Expected item %d, got %d", value, it.value)
+		}
+	}
+}
+
+func TestPriorityDropOldest(t *testing.T) {
+	q := NewQueue[*item](2)
+	q.SetPolicy(DropOldest)
+
+	q.Push(0, &item{value: 1})
+	q.Push(0, &item{value: 2})
+	result := q.Push(0, &item{value: 3})
+	if !result.Queued {
+		t.Errorf("Expected item to be queued")
+	}
+	if !result.Dropped || (result.Item.value != 1) {
+		t.Errorf("Expected the oldest item to be dropped")
+	}
+
+	for _, value := range []int{2, 3} {
+		it, _ := q.Pop()
+		if it.value != value {
+			t.Errorf("Expected item %d, got %d", value, it.value)
+		}
+	}
+}
+
+func TestPriorityPushAlways(t *testing.T) {
+	q := NewPriority[*item]([]int{1, 10, 10}, nil)
+	q.SetPolicy(DropNewest)
+	q.Push(1, &item{value: 1})
+	q.Push(2, &item{value: 2})
+	q.Push(2, &item{value: 3})
+	q.Push(0, &item{value: 4})
+
+	result := q.PushAlways(0, &item{value: 5})
+	if !result.Queued {
+		t.Errorf("Expected item to be queued")
+	}
+	if !result.Dropped || (result.Item.value != 2) || result.Exceeded {
+		t.Errorf("Expected the oldest item of the lowest priority to be dropped")
+	}
+
+	// with nothing of lower priority left to drop the limit is exceeded
+	q.Drain(1)
+	q.Drain(2)
+	result = q.PushAlways(0, &item{value: 6})
+	if !result.Queued || result.Dropped || !result.Exceeded {
+		t.Errorf("Expected item to be queued beyond the limit, got %+v", result)
+	}
+	for _, value := range []int{4, 5, 6} {
+		it, _ := q.Pop()
+		if it.value != value {
+			t.Errorf("Expected item %d, got %d", value, it.value)
+		}
+	}
+}
+
+func TestPriorityPushAlwaysFull(t *testing.T) {
+	// only the highest priority is used
+	q := NewPriority[*item]([]int{2, 10}, nil)
+	q.SetPolicy(Reject)
+	for value := 1; value <= 4; value++ {
+		result := q.PushAlways(0, &item{value: value})
+		if !result.Queued || result.Dropped || (result.Exceeded != (value > 2)) {
+			t.Errorf("Unexpected result for item %d: %+v", value, result)
+		}
+	}
+	if q.Len() != 4 {
+		t.Errorf("Expected all items to be kept, got %d", q.Len())
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	for _, p := range []Policy{Reject, DropNewest, DropOldest} {
+		parsed, err := ParsePolicy(p.String())
+		if err != nil {
+			t.Errorf("Failed to parse policy %s: %s", p, err)
+		}
+		if parsed != p {
+			t.Errorf("Expected policy %s, got %s", p, parsed)
+		}
+	}
+
+	if _, err := ParsePolicy("drop_all"); err == nil {
+		t.Errorf("Expected unknown policy to be rejected")
+	}
+}
@@ -0,0 +1,302 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/moosethebrown/ship-net-bridge/metrics"
+	"github.com/rs/zerolog"
+)
+
+const (
+	DefaultMinBackoff = time.Second
+	DefaultMaxBackoff = 30 * time.Second
+)
+
+// Component states reported by Status
+const (
+	StateStarting   = "starting"
+	StateRunning    = "running"
+	StateRestarting = "restarting"
+	StateFailed     = "failed"
+	StateStopped    = "stopped"
+)
+
+// errUnexpectedExit is reported for components whose Run returns
+// without error while they are not being stopped
+var errUnexpectedExit = errors.New("exited unexpectedly")
+
+type fatalError struct {
+	err error
+}
+
+func (e *fatalError) Error() string {
+	return e.err.Error()
+}
+
+func (e *fatalError) Unwrap() error {
+	return e.err
+}
+
+// Fatal marks err as a failure restarting won't fix, e.g. invalid configuration
+func Fatal(err error) error {
+	return &fatalError{err: err}
+}
+
+func IsFatal(err error) bool {
+	var fatal *fatalError
+	return errors.As(err, &fatal)
+}
+
+// Supervisor runs components in their own goroutines and restarts them with
+// exponential backoff when they fail; a component failing with a Fatal error
+// is not restarted and reported on the Fatal channel instead.
+// Components are stopped in reverse order of adding.
+type Supervisor struct {
+	children   []*child
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	fatalChan  chan error
+	logger     *zerolog.Logger
+}
+
+// ComponentStatus is reported for each component by Status
+type ComponentStatus struct {
+	State     string    `json:"state"`
+	Since     time.Time `json:"since"`
+	Restarts  int       `json:"restarts"`
+	LastError string    `json:"lastError,omitempty"`
+}
+
+type child struct {
+	name string
+	run  func(ctx context.Context) error
+	// stopTimeout, if set, is how long Stop waits for the component
+	// regardless of its own timeout
+	stopTimeout time.Duration
+	ctx         context.Context
+	cancel      context.CancelFunc
+	doneChan    chan bool
+	mutex       sync.Mutex
+	status      ComponentStatus
+}
+
+func NewSupervisor(minBackoff time.Duration, maxBackoff time.Duration,
+	logger *zerolog.Logger) *Supervisor {
+	if minBackoff <= 0 {
+		minBackoff = DefaultMinBackoff
+	}
+	if maxBackoff < minBackoff {
+		maxBackoff = max(minBackoff, DefaultMaxBackoff)
+	}
+
+	return &Supervisor{
+		children:   make([]*child, 0),
+		minBackoff: minBackoff,
+		maxBackoff: maxBackoff,
+		fatalChan:  make(chan error, 1),
+		logger:     logger,
+	}
+}
+
+// Add registers a component, run must block until ctx is cancelled or
+// the component fails. Must be called before Start.
+func (s *Supervisor) Add(name string, run func(ctx context.Context) error) {
+	s.AddWithTimeout(name, run, 0)
+}
+
+// AddWithTimeout registers a component with a shutdown budget of its own,
+// e.g. to put the ship into a safe state: Stop waits for it up to stopTimeout
+// after cancelling it, even when the timeout of Stop expires meanwhile.
+// When it's stopped while waiting to be restarted, run is called once more
+// with the cancelled ctx so that it can still shut down.
+// Must be called before Start.
+func (s *Supervisor) AddWithTimeout(name string, run func(ctx context.Context) error,
+	stopTimeout time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.children = append(s.children, &child{
+		name:        name,
+		run:         run,
+		stopTimeout: stopTimeout,
+		ctx:         ctx,
+		cancel:      cancel,
+		doneChan:    make(chan bool),
+		status: ComponentStatus{
+			State: StateStarting,
+			Since: time.Now(),
+		},
+	})
+}
+
+// Start runs all components in order of adding
+func (s *Supervisor) Start() {
+	for _, c := range s.children {
+		go s.supervise(c)
+	}
+}
+
+// Stop cancels components in reverse order of adding, waiting for each of them
+// to exit. When timeout expires the remaining components are all cancelled
+// at once and abandoned, except for those with a stop timeout of their own.
+func (s *Supervisor) Stop(timeout time.Duration) {
+	deadline := time.After(timeout)
+
+	for i := len(s.children) - 1; i >= 0; i-- {
+		c := s.children[i]
+		c.cancel()
+
+		if c.stopTimeout > 0 {
+			c.wait(s.logger, c.stopTimeout)
+			continue
+		}
+		select {
+		case <-c.doneChan:
+		case <-deadline:
+			s.logger.Error().Msgf("shutdown timeout of %s expired waiting for %s", timeout, c.name)
+			s.cancelAll(s.children[:i])
+			return
+		}
+	}
+}
+
+// cancelAll cancels children in reverse order without waiting for them,
+// then waits for those with a stop timeout
+func (s *Supervisor) cancelAll(children []*child) {
+	for i := len(children) - 1; i >= 0; i-- {
+		children[i].cancel()
+	}
+
+	// they are shutting down at the same time, budgets count from now
+	var wg sync.WaitGroup
+	for i := len(children) - 1; i >= 0; i-- {
+		c := children[i]
+		if c.stopTimeout <= 0 {
+			s.logger.Warn().Msgf("abandoning %s", c.name)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.wait(s.logger, c.stopTimeout)
+		}()
+	}
+	wg.Wait()
+}
+
+// Fatal receives the first fatal component failure
+func (s *Supervisor) Fatal() <-chan error {
+	return s.fatalChan
+}
+
+// Status can be called from any goroutine
+func (s *Supervisor) Status() any {
+	status := make(map[string]ComponentStatus, len(s.children))
+	for _, c := range s.children {
+		c.mutex.Lock()
+		status[c.name] = c.status
+		c.mutex.Unlock()
+	}
+	return status
+}
+
+// Healthy is false while any component is not running
+func (s *Supervisor) Healthy() bool {
+	for _, c := range s.children {
+		c.mutex.Lock()
+		state := c.status.State
+		c.mutex.Unlock()
+		if (state != StateRunning) && (state != StateStopped) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Supervisor) supervise(c *child) {
+	defer close(c.doneChan)
+
+	backoff := s.minBackoff
+	for {
+		c.setState(StateRunning, nil)
+		start := time.Now()
+		err := c.runSafely()
+
+		if c.ctx.Err() != nil {
+			// failed before it could shut down
+			if (err != nil) && (c.stopTimeout > 0) {
+				c.runSafely()
+			}
+			c.setState(StateStopped, err)
+			return
+		}
+
+		if err == nil {
+			err = errUnexpectedExit
+		}
+		if IsFatal(err) {
+			s.logger.Error().Err(err).Msgf("%s failed", c.name)
+			c.setState(StateFailed, err)
+			select {
+			case s.fatalChan <- fmt.Errorf("%s: %w", c.name, err):
+			default:
+			}
+			return
+		}
+
+		// a component that ran for a while before failing starts over
+		if time.Since(start) > s.maxBackoff {
+			backoff = s.minBackoff
+		}
+		s.logger.Error().Err(err).Msgf("%s failed, restarting in %s", c.name, backoff)
+		metrics.Restarts.WithLabelValues(c.name).Inc()
+		c.setState(StateRestarting, err)
+		c.mutex.Lock()
+		c.status.Restarts++
+		c.mutex.Unlock()
+
+		select {
+		case <-time.After(backoff):
+		case <-c.ctx.Done():
+			if c.stopTimeout > 0 {
+				c.runSafely()
+			}
+			c.setState(StateStopped, err)
+			return
+		}
+		backoff = min(2*backoff, s.maxBackoff)
+	}
+}
+
+// runSafely turns panics into transient failures
+func (c *child) runSafely() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return c.run(c.ctx)
+}
+
+// wait returns when the component exits or timeout expires
+func (c *child) wait(logger *zerolog.Logger, timeout time.Duration) {
+	select {
+	case <-c.doneChan:
+	case <-time.After(timeout):
+		logger.Error().Msgf("stop timeout of %s expired waiting for %s", timeout, c.name)
+	}
+}
+
+func (c *child) setState(state string, err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.status.State = state
+	c.status.Since = time.Now()
+	if err != nil {
+		c.status.LastError = err.Error()
+	}
+}
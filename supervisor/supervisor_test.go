@@ -0,0 +1,204 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type mockComponent struct {
+	name     string
+	failures []error
+	runs     int
+	order    *[]string
+	mutex    *sync.Mutex
+}
+
+func newMockComponent(name string, order *[]string, mutex *sync.Mutex, failures ...error) *mockComponent {
+	return &mockComponent{
+		name:     name,
+		failures: failures,
+		order:    order,
+		mutex:    mutex,
+	}
+}
+
+func (m *mockComponent) Run(ctx context.Context) error {
+	m.mutex.Lock()
+	m.runs++
+	if len(m.failures) > 0 {
+		err := m.failures[0]
+		m.failures = m.failures[1:]
+		m.mutex.Unlock()
+		return err
+	}
+	m.mutex.Unlock()
+
+	<-ctx.Done()
+	m.mutex.Lock()
+	*m.order = append(*m.order, m.name)
+	m.mutex.Unlock()
+	return nil
+}
+
+func (m *mockComponent) Runs() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.runs
+}
+
+func setup() *Supervisor {
+	logger := zerolog.New(os.Stdout).With().Timestamp().Logger().Level(zerolog.DebugLevel)
+	return NewSupervisor(time.Millisecond, 10*time.Millisecond, &logger)
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRestart(t *testing.T) {
+	s := setup()
+	var order []string
+	var mutex sync.Mutex
+	c := newMockComponent("daemon", &order, &mutex,
+		errors.New("connection refused"), errors.New("connection refused"))
+	s.Add(c.name, c.Run)
+
+	s.Start()
+	waitFor(t, func() bool { return c.Runs() == 3 })
+	waitFor(t, s.Healthy)
+
+	status := s.Status().(map[string]ComponentStatus)
+	if status["daemon"].Restarts != 2 {
+		t.Errorf("Expected 2 restarts, got %d", status["daemon"].Restarts)
+	}
+
+	s.Stop(time.Second)
+	status = s.Status().(map[string]ComponentStatus)
+	if status["daemon"].State != StateStopped {
+		t.Errorf("Expected stopped component, got %s", status["daemon"].State)
+	}
+}
+
+func TestFatal(t *testing.T) {
+	s := setup()
+	var order []string
+	var mutex sync.Mutex
+	c := newMockComponent("mqtt", &order, &mutex, Fatal(errors.New("no brokers")))
+	s.Add(c.name, c.Run)
+
+	s.Start()
+	select {
+	case err := <-s.Fatal():
+		if !IsFatal(err) {
+			t.Errorf("Expected fatal error, got %s", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Expected fatal failure to be reported")
+	}
+
+	if c.Runs() != 1 {
+		t.Errorf("Expected fatally failed component not to be restarted, got %d runs", c.Runs())
+	}
+	if s.Healthy() {
+		t.Errorf("Expected supervisor to be unhealthy")
+	}
+}
+
+func TestStopOrder(t *testing.T) {
+	s := setup()
+	var order []string
+	var mutex sync.Mutex
+	for _, name := range []string{"core", "ship-control", "mqtt"} {
+		c := newMockComponent(name, &order, &mutex)
+		s.Add(c.name, c.Run)
+	}
+
+	s.Start()
+	waitFor(t, s.Healthy)
+	s.Stop(time.Second)
+
+	expected := []string{"mqtt", "ship-control", "core"}
+	mutex.Lock()
+	defer mutex.Unlock()
+	for i, name := range expected {
+		if (i >= len(order)) || (order[i] != name) {
+			t.Fatalf("Expected stop order %v, got %v", expected, order)
+		}
+	}
+}
+
+func TestStopTimeout(t *testing.T) {
+	s := setup()
+	var order []string
+	var mutex sync.Mutex
+	// ship-control needs time to send the safe state, its budget
+	// doesn't depend on how long the others take
+	safeState := make(chan bool, 1)
+	s.AddWithTimeout("ship-control", func(ctx context.Context) error {
+		<-ctx.Done()
+		time.Sleep(50 * time.Millisecond)
+		safeState <- true
+		return nil
+	}, time.Second)
+	c := newMockComponent("ship-nav", &order, &mutex)
+	s.Add(c.name, c.Run)
+	s.Add("core", func(ctx context.Context) error {
+		// stuck draining, never exits
+		select {}
+	})
+
+	s.Start()
+	waitFor(t, s.Healthy)
+	s.Stop(10 * time.Millisecond)
+
+	select {
+	case <-safeState:
+	default:
+		t.Errorf("Expected ship-control to finish shutting down")
+	}
+	waitFor(t, func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return len(order) == 1
+	})
+}
+
+func TestStopWhileRestarting(t *testing.T) {
+	logger := zerolog.New(os.Stdout).With().Timestamp().Logger().Level(zerolog.DebugLevel)
+	s := NewSupervisor(time.Hour, time.Hour, &logger)
+	var mutex sync.Mutex
+	var cancelled []bool
+	s.AddWithTimeout("ship-control", func(ctx context.Context) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		cancelled = append(cancelled, ctx.Err() != nil)
+		return errors.New("connection refused")
+	}, time.Second)
+
+	s.Start()
+	waitFor(t, func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return len(cancelled) == 1
+	})
+	s.Stop(time.Second)
+
+	// run once more to shut down instead of waiting for the restart
+	mutex.Lock()
+	defer mutex.Unlock()
+	if (len(cancelled) != 2) || !cancelled[1] {
+		t.Errorf("Expected one more run with cancelled context, got %v", cancelled)
+	}
+}
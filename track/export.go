@@ -0,0 +1,129 @@
+package track
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"time"
+)
+
+const gpxExtNamespace = "https://github.com/moosethebrown/ship-net-bridge/gpx/1"
+
+type gpx struct {
+	XMLName  xml.Name `xml:"gpx"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	XmlnsSnb string   `xml:"xmlns:snb,attr"`
+	Version  string   `xml:"version,attr"`
+	Creator  string   `xml:"creator,attr"`
+	Track    gpxTrack `xml:"trk"`
+}
+
+type gpxTrack struct {
+	Name    string     `xml:"name"`
+	Segment gpxSegment `xml:"trkseg"`
+}
+
+type gpxSegment struct {
+	Points []gpxPoint `xml:"trkpt"`
+}
+
+type gpxPoint struct {
+	Latitude   float64       `xml:"lat,attr"`
+	Longitude  float64       `xml:"lon,attr"`
+	Time       string        `xml:"time"`
+	Extensions gpxExtensions `xml:"extensions"`
+}
+
+type gpxExtensions struct {
+	Heading float64 `xml:"snb:heading"`
+	Speed   float64 `xml:"snb:speed"`
+}
+
+type geoJsonFeature struct {
+	Type       string            `json:"type"`
+	Geometry   geoJsonGeometry   `json:"geometry"`
+	Properties geoJsonProperties `json:"properties"`
+}
+
+type geoJsonGeometry struct {
+	Type        string       `json:"type"`
+	Coordinates [][2]float64 `json:"coordinates"`
+}
+
+type geoJsonProperties struct {
+	Name       string    `json:"name"`
+	Mission    bool      `json:"mission"`
+	CoordTimes []string  `json:"coordTimes"`
+	Headings   []float64 `json:"headings"`
+	Speeds     []float64 `json:"speeds"`
+}
+
+func trackName(t *Track) string {
+	if t.Mission {
+		return fmt.Sprintf("mission %d", t.Id)
+	}
+	return fmt.Sprintf("track %d", t.Id)
+}
+
+func exportGpx(t *Track) ([]byte, error) {
+	doc := gpx{
+		Xmlns:    "http://www.topografix.com/GPX/1/1",
+		XmlnsSnb: gpxExtNamespace,
+		Version:  "1.1",
+		Creator:  "ship-net-bridge",
+		Track: gpxTrack{
+			Name: trackName(t),
+			Segment: gpxSegment{
+				Points: make([]gpxPoint, 0, len(t.Points)),
+			},
+		},
+	}
+
+	for _, p := range t.Points {
+		doc.Track.Segment.Points = append(doc.Track.Segment.Points, gpxPoint{
+			Latitude:  p.Latitude,
+			Longitude: p.Longitude,
+			Time:      p.Time.UTC().Format(time.RFC3339),
+			Extensions: gpxExtensions{
+				Heading: p.Heading,
+				Speed:   p.Speed,
+			},
+		})
+	}
+
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), data...), nil
+}
+
+func exportGeoJson(t *Track) ([]byte, error) {
+	feature := geoJsonFeature{
+		Type: "Feature",
+		Geometry: geoJsonGeometry{
+			Type:        "LineString",
+			Coordinates: make([][2]float64, 0, len(t.Points)),
+		},
+		Properties: geoJsonProperties{
+			Name:       trackName(t),
+			Mission:    t.Mission,
+			CoordTimes: make([]string, 0, len(t.Points)),
+			Headings:   make([]float64, 0, len(t.Points)),
+			Speeds:     make([]float64, 0, len(t.Points)),
+		},
+	}
+
+	for _, p := range t.Points {
+		// GeoJSON positions are longitude first
+		feature.Geometry.Coordinates = append(feature.Geometry.Coordinates,
+			[2]float64{p.Longitude, p.Latitude})
+		feature.Properties.CoordTimes = append(feature.Properties.CoordTimes,
+			p.Time.UTC().Format(time.RFC3339))
+		feature.Properties.Headings = append(feature.Properties.Headings, p.Heading)
+		feature.Properties.Speeds = append(feature.Properties.Speeds, p.Speed)
+	}
+
+	return json.Marshal(feature)
+}
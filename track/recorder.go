@@ -0,0 +1,188 @@
+package track
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/moosethebrown/ship-net-bridge/core"
+	"github.com/rs/zerolog"
+)
+
+const (
+	FormatGpx     = "gpx"
+	FormatGeoJson = "geojson"
+)
+
+type Point struct {
+	Latitude  float64
+	Longitude float64
+	Heading   float64
+	Speed     float64
+	Time      time.Time
+}
+
+// Track is a continuous piece of the vessel's path, either a mission
+// (between nav_start and stop) or manual steering in between missions
+type Track struct {
+	Id      int
+	Mission bool
+	Start   time.Time
+	End     time.Time
+	Points  []*Point
+}
+
+// Recorder keeps recent tracks in memory, maxTracks and maxPoints limit
+// the number of tracks and points per track, 0 means no limit
+type Recorder struct {
+	maxTracks int
+	maxPoints int
+	exportDir string
+	tracks    []*Track
+	current   *Track
+	nextId    int
+	mutex     sync.Mutex
+	logger    *zerolog.Logger
+}
+
+func NewRecorder(maxTracks int, maxPoints int, exportDir string, logger *zerolog.Logger) *Recorder {
+	return &Recorder{
+		maxTracks: maxTracks,
+		maxPoints: maxPoints,
+		exportDir: exportDir,
+		tracks:    make([]*Track, 0),
+		nextId:    1,
+		logger:    logger,
+	}
+}
+
+func (r *Recorder) Record(t *core.Telemetry) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.current == nil {
+		r.openTrack(false)
+	}
+
+	if (r.maxPoints > 0) && (len(r.current.Points) >= r.maxPoints) {
+		r.logger.Warn().Msgf("track %d reached %d points, dropping oldest point",
+			r.current.Id, r.maxPoints)
+		r.current.Points = r.current.Points[1:]
+	}
+
+	r.current.Points = append(r.current.Points, &Point{
+		Latitude:  t.Latitude,
+		Longitude: t.Longitude,
+		Heading:   t.Heading,
+		Speed:     t.Speed,
+		Time:      t.Timestamp,
+	})
+	r.current.End = t.Timestamp
+}
+
+func (r *Recorder) StartMission() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.openTrack(true)
+	r.logger.Info().Msgf("started mission track %d", r.current.Id)
+}
+
+func (r *Recorder) StopMission() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.current != nil && r.current.Mission {
+		r.logger.Info().Msgf("finished mission track %d, %d points",
+			r.current.Id, len(r.current.Points))
+		// next position report opens a new non-mission track
+		r.current = nil
+	}
+}
+
+// Tracks returns a snapshot of the recorded tracks, oldest first
+func (r *Recorder) Tracks() []*Track {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	tracks := make([]*Track, len(r.tracks))
+	copy(tracks, r.tracks)
+	return tracks
+}
+
+// Export serializes track with the given id in the given format,
+// id 0 selects the latest mission
+func (r *Recorder) Export(format string, trackId int) ([]byte, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	t, err := r.findTrack(trackId)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case FormatGpx:
+		return exportGpx(t)
+	case FormatGeoJson:
+		return exportGeoJson(t)
+	default:
+		return nil, fmt.Errorf("unsupported track format: %s", format)
+	}
+}
+
+// ExportToFile writes exported track to exportDir and returns the file path,
+// fileName must not contain directories
+func (r *Recorder) ExportToFile(format string, trackId int, fileName string) (string, error) {
+	if r.exportDir == "" {
+		return "", errors.New("track export directory is not configured")
+	}
+	if fileName != filepath.Base(fileName) || fileName == "." || fileName == ".." {
+		return "", fmt.Errorf("invalid file name: %s", fileName)
+	}
+
+	data, err := r.Export(format, trackId)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(r.exportDir, fileName)
+	err = os.WriteFile(path, data, 0644)
+	if err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+func (r *Recorder) openTrack(mission bool) {
+	if (r.maxTracks > 0) && (len(r.tracks) >= r.maxTracks) {
+		r.tracks = r.tracks[1:]
+	}
+
+	r.current = &Track{
+		Id:      r.nextId,
+		Mission: mission,
+		Start:   time.Now(),
+		Points:  make([]*Point, 0),
+	}
+	r.nextId++
+	r.tracks = append(r.tracks, r.current)
+}
+
+func (r *Recorder) findTrack(trackId int) (*Track, error) {
+	for i := len(r.tracks) - 1; i >= 0; i-- {
+		t := r.tracks[i]
+		if (trackId == 0 && t.Mission) || (trackId != 0 && t.Id == trackId) {
+			return t, nil
+		}
+	}
+
+	if trackId == 0 {
+		return nil, errors.New("no mission tracks recorded")
+	}
+	return nil, fmt.Errorf("track %d not found", trackId)
+}
@@ -0,0 +1,137 @@
+package track
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moosethebrown/ship-net-bridge/core"
+	"github.com/rs/zerolog"
+)
+
+func setup(t *testing.T) *Recorder {
+	logger := zerolog.New(os.Stdout).With().Timestamp().Logger().Level(zerolog.DebugLevel)
+
+	return NewRecorder(10, 100, t.TempDir(), &logger)
+}
+
+func telemetry(lat float64, lon float64) *core.Telemetry {
+	return &core.Telemetry{
+		Latitude:  lat,
+		Longitude: lon,
+		Heading:   90,
+		Speed:     1.5,
+		Timestamp: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestMissionSegmentation(t *testing.T) {
+	r := setup(t)
+
+	r.Record(telemetry(56.1, 43.1))
+	r.StartMission()
+	r.Record(telemetry(56.2, 43.2))
+	r.Record(telemetry(56.3, 43.3))
+	r.StopMission()
+	r.Record(telemetry(56.4, 43.4))
+
+	tracks := r.Tracks()
+	if len(tracks) != 3 {
+		t.Fatalf("Expected 3 tracks, got %d", len(tracks))
+	}
+	if tracks[0].Mission || !tracks[1].Mission || tracks[2].Mission {
+		t.Errorf("Expected only track 2 to be a mission")
+	}
+	if len(tracks[1].Points) != 2 {
+		t.Errorf("Expected mission track to have 2 points, got %d", len(tracks[1].Points))
+	}
+}
+
+func TestExportGpx(t *testing.T) {
+	r := setup(t)
+
+	r.StartMission()
+	r.Record(telemetry(56.348284, 43.959410))
+	r.Record(telemetry(56.359226, 43.907618))
+	r.StopMission()
+
+	data, err := r.Export(FormatGpx, 0)
+	if err != nil {
+		t.Fatalf("Failed to export GPX: %s", err)
+	}
+
+	var doc struct {
+		Points []struct {
+			Latitude  float64 `xml:"lat,attr"`
+			Longitude float64 `xml:"lon,attr"`
+			Time      string  `xml:"time"`
+		} `xml:"trk>trkseg>trkpt"`
+	}
+	err = xml.Unmarshal(data, &doc)
+	if err != nil {
+		t.Fatalf("Failed to parse exported GPX: %s", err)
+	}
+	if len(doc.Points) != 2 {
+		t.Fatalf("Expected 2 track points, got %d", len(doc.Points))
+	}
+	if doc.Points[1].Latitude != 56.359226 || doc.Points[1].Longitude != 43.907618 {
+		t.Errorf("Unexpected point 2 position: %f,%f",
+			doc.Points[1].Latitude, doc.Points[1].Longitude)
+	}
+	if doc.Points[0].Time != "2024-06-01T12:00:00Z" {
+		t.Errorf("Unexpected point 1 time: %s", doc.Points[0].Time)
+	}
+}
+
+func TestExportGeoJson(t *testing.T) {
+	r := setup(t)
+
+	r.StartMission()
+	r.Record(telemetry(56.348284, 43.959410))
+
+	data, err := r.Export(FormatGeoJson, 1)
+	if err != nil {
+		t.Fatalf("Failed to export GeoJSON: %s", err)
+	}
+
+	var feature geoJsonFeature
+	err = json.Unmarshal(data, &feature)
+	if err != nil {
+		t.Fatalf("Failed to parse exported GeoJSON: %s", err)
+	}
+	if feature.Geometry.Type != "LineString" {
+		t.Errorf("Expected LineString geometry, got %s", feature.Geometry.Type)
+	}
+	if len(feature.Geometry.Coordinates) != 1 ||
+		feature.Geometry.Coordinates[0] != [2]float64{43.959410, 56.348284} {
+		t.Errorf("Unexpected coordinates: %v", feature.Geometry.Coordinates)
+	}
+}
+
+func TestExportToFile(t *testing.T) {
+	r := setup(t)
+
+	r.StartMission()
+	r.Record(telemetry(56.348284, 43.959410))
+
+	path, err := r.ExportToFile(FormatGpx, 0, "mission.gpx")
+	if err != nil {
+		t.Fatalf("Failed to export track to file: %s", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read exported file: %s", err)
+	}
+	if !strings.Contains(string(data), "<trkpt") {
+		t.Errorf("Exported file doesn't contain track points")
+	}
+
+	_, err = r.ExportToFile(FormatGpx, 0, filepath.Join("..", "mission.gpx"))
+	if err == nil {
+		t.Errorf("Expected export outside of export directory to fail")
+	}
+}